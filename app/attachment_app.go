@@ -0,0 +1,192 @@
+package app
+
+import (
+	"bufio"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"forum/domain/entity"
+	"forum/domain/repository"
+	"io"
+	"net/http"
+	"path/filepath"
+	"strings"
+)
+
+type AttachmentApp struct {
+	a        repository.AttachmentRepository
+	f        repository.ForumRepository
+	storage  repository.BlobStorage
+	defaults entity.AttachmentLimits
+	auditApp AuditAppInterface
+}
+
+// NewAttachmentApp creates app using defaults for forums without own attachment limits
+func NewAttachmentApp(
+	a repository.AttachmentRepository,
+	f repository.ForumRepository,
+	storage repository.BlobStorage,
+	defaults entity.AttachmentLimits,
+	auditApp AuditAppInterface) *AttachmentApp {
+	return &AttachmentApp{a: a, f: f, storage: storage, defaults: defaults, auditApp: auditApp}
+}
+
+type AttachmentAppInterface interface {
+	UploadAttachment(post *entity.Post, author string, filename string, content io.Reader) (*entity.Attachment, error)
+	OpenAttachment(id int) (*entity.Attachment, io.ReadCloser, error)
+	FillPostsAttachments(posts []entity.Post) error
+	GetForumLimits(slug string) (*entity.AttachmentLimits, error)
+	SetForumLimits(slug string, limits *entity.AttachmentLimits) error
+}
+
+func attachmentURL(id int) string {
+	return fmt.Sprintf("/api/attachment/%d", id)
+}
+
+// UploadAttachment stores content for the post checking limits of the post forum.
+// Content type is sniffed from the content itself, client supplied type is not trusted
+func (a *AttachmentApp) UploadAttachment(post *entity.Post, author string, filename string, content io.Reader) (*entity.Attachment, error) {
+	if !strings.EqualFold(post.Author, author) {
+		return nil, entity.NotPostAuthorError
+	}
+
+	limits, err := a.GetForumLimits(post.Forum)
+	if err != nil {
+		return nil, err
+	}
+
+	reader := bufio.NewReaderSize(content, 512)
+	head, err := reader.Peek(512)
+	if err != nil && err != io.EOF && err != bufio.ErrBufferFull {
+		return nil, err
+	}
+
+	contentType := http.DetectContentType(head)
+	if idx := strings.IndexByte(contentType, ';'); idx >= 0 {
+		contentType = contentType[:idx]
+	}
+
+	allowed := false
+	for _, allowedType := range limits.Types {
+		if strings.EqualFold(allowedType, contentType) {
+			allowed = true
+			break
+		}
+	}
+	if !allowed {
+		return nil, entity.AttachmentTypeError
+	}
+
+	keyBytes := make([]byte, 16)
+	_, err = rand.Read(keyBytes)
+	if err != nil {
+		return nil, err
+	}
+	key := hex.EncodeToString(keyBytes)
+
+	size, err := a.storage.Save(key, io.LimitReader(reader, limits.MaxSize+1))
+	if err != nil {
+		return nil, err
+	}
+	if size > limits.MaxSize {
+		a.storage.Delete(key)
+		return nil, entity.AttachmentTooLargeError
+	}
+
+	attachment := &entity.Attachment{
+		Post:        post.ID,
+		Author:      post.Author,
+		Filename:    filepath.Base(filename),
+		ContentType: contentType,
+		Size:        size,
+		StorageKey:  key,
+	}
+
+	err = a.a.CreateAttachment(attachment)
+	if err != nil {
+		a.storage.Delete(key)
+		return nil, err
+	}
+
+	attachment.URL = attachmentURL(attachment.ID)
+	return attachment, nil
+}
+
+func (a *AttachmentApp) OpenAttachment(id int) (*entity.Attachment, io.ReadCloser, error) {
+	attachment, err := a.a.GetAttachment(id)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	content, err := a.storage.Open(attachment.StorageKey)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	attachment.URL = attachmentURL(attachment.ID)
+	return attachment, content, nil
+}
+
+// FillPostsAttachments loads attachment lists of all posts at once
+func (a *AttachmentApp) FillPostsAttachments(posts []entity.Post) error {
+	ids := make([]int, 0, len(posts))
+	for _, post := range posts {
+		ids = append(ids, post.ID)
+	}
+
+	attachments, err := a.a.GetPostsAttachments(ids)
+	if err != nil {
+		return err
+	}
+
+	for i := range posts {
+		posts[i].Attachments = attachments[posts[i].ID]
+		for j := range posts[i].Attachments {
+			posts[i].Attachments[j].URL = attachmentURL(posts[i].Attachments[j].ID)
+		}
+	}
+	return nil
+}
+
+// GetForumLimits returns limits of the forum, unset ones are taken from defaults
+func (a *AttachmentApp) GetForumLimits(slug string) (*entity.AttachmentLimits, error) {
+	limits, err := a.f.GetAttachmentLimits(slug)
+	if err != nil {
+		return nil, err
+	}
+
+	if limits.MaxSize == 0 {
+		limits.MaxSize = a.defaults.MaxSize
+	}
+	if len(limits.Types) == 0 {
+		limits.Types = a.defaults.Types
+	}
+	return limits, nil
+}
+
+// SetForumLimits changes forum limits, only forum owner is allowed to do it
+func (a *AttachmentApp) SetForumLimits(slug string, limits *entity.AttachmentLimits) error {
+	forum, err := a.f.GetForumDetails(slug)
+	if err != nil {
+		return entity.ForumNotExistError
+	}
+
+	if !strings.EqualFold(forum.User, limits.User) {
+		return entity.NotForumOwnerError
+	}
+	if limits.MaxSize < 0 {
+		return entity.DataError
+	}
+
+	previousLimits, err := a.f.GetAttachmentLimits(forum.Slug)
+	if err != nil {
+		return err
+	}
+
+	err = a.f.SetAttachmentLimits(forum.Slug, limits)
+	if err != nil {
+		return err
+	}
+	return a.auditApp.Record(forum.User, entity.AuditForumAttachmentLimits, entity.AuditTargetForum,
+		forum.Slug, previousLimits, limits)
+}
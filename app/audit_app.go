@@ -0,0 +1,59 @@
+package app
+
+import (
+	"encoding/json"
+	"forum/domain/entity"
+	"forum/domain/repository"
+)
+
+type AuditApp struct {
+	a repository.AuditRepository
+}
+
+func NewAuditApp(a repository.AuditRepository) *AuditApp {
+	return &AuditApp{a: a}
+}
+
+type AuditAppInterface interface {
+	Record(actor string, action string, targetType string, targetID string, before interface{}, after interface{}) error
+	GetAuditEntries(filter *entity.AuditFilter) ([]entity.AuditEntry, error)
+}
+
+// Record appends entry to the audit log, nil snapshots are not stored
+func (a *AuditApp) Record(actor string, action string, targetType string, targetID string, before interface{}, after interface{}) error {
+	entry, err := newAuditEntry(actor, action, targetType, targetID, before, after)
+	if err != nil {
+		return err
+	}
+	return a.a.AddAuditEntry(entry)
+}
+
+// newAuditEntry makes an entry for repositories that write it in the transaction of the change
+func newAuditEntry(actor string, action string, targetType string, targetID string,
+	before interface{}, after interface{}) (*entity.AuditEntry, error) {
+	entry := &entity.AuditEntry{
+		Actor:      actor,
+		Action:     action,
+		TargetType: targetType,
+		TargetID:   targetID,
+	}
+
+	var err error
+	if before != nil {
+		entry.Before, err = json.Marshal(before)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if after != nil {
+		entry.After, err = json.Marshal(after)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return entry, nil
+}
+
+func (a *AuditApp) GetAuditEntries(filter *entity.AuditFilter) ([]entity.AuditEntry, error) {
+	return a.a.GetAuditEntries(filter)
+}
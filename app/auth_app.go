@@ -0,0 +1,154 @@
+package app
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"forum/domain/entity"
+	"forum/domain/repository"
+	"golang.org/x/crypto/bcrypt"
+	"time"
+)
+
+const minPasswordLength = 8
+const defaultSessionTTL = 30 * 24 * time.Hour
+
+// dummyPasswordHash is compared with passwords of unknown users, so that login takes
+// the same time whether the user exists or not
+var dummyPasswordHash, _ = bcrypt.GenerateFromPassword([]byte("dummy password"), bcrypt.DefaultCost)
+
+type AuthApp struct {
+	us         repository.UserRepository
+	sessions   repository.SessionRepository
+	emailApp   EmailAppInterface
+	auditApp   AuditAppInterface
+	sessionTTL time.Duration
+	// resetErrors receives errors of password reset mails, which are sent after the request is answered
+	resetErrors func(err error)
+}
+
+func NewAuthApp(us repository.UserRepository,
+	sessions repository.SessionRepository,
+	emailApp EmailAppInterface,
+	auditApp AuditAppInterface,
+	sessionTTL time.Duration,
+	resetErrors func(err error)) *AuthApp {
+	if sessionTTL <= 0 {
+		sessionTTL = defaultSessionTTL
+	}
+	return &AuthApp{us: us, sessions: sessions, emailApp: emailApp, auditApp: auditApp, sessionTTL: sessionTTL,
+		resetErrors: resetErrors}
+}
+
+type AuthAppInterface interface {
+	Login(credentials *entity.Credentials) (string, *entity.Session, error)
+	Logout(token string) error
+	GetSession(token string) (*entity.Session, error)
+	RequestPasswordReset(email string) error
+	ResetPassword(reset *entity.PasswordReset) error
+}
+
+func hashPassword(password string) (string, error) {
+	if len([]rune(password)) < minPasswordLength {
+		return "", entity.WeakPasswordError
+	}
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return "", err
+	}
+	return string(hash), nil
+}
+
+// Login checks the password and starts a session, the returned token is the cookie value
+func (a *AuthApp) Login(credentials *entity.Credentials) (string, *entity.Session, error) {
+	userID, nickname, passwordHash, err := a.us.GetPasswordHash(credentials.Nickname)
+	if err != nil && err != entity.UserDoesntExistsError {
+		return "", nil, err
+	}
+	if passwordHash == "" {
+		bcrypt.CompareHashAndPassword(dummyPasswordHash, []byte(credentials.Password))
+		return "", nil, entity.WrongCredentialsError
+	}
+	err = bcrypt.CompareHashAndPassword([]byte(passwordHash), []byte(credentials.Password))
+	if err != nil {
+		return "", nil, entity.WrongCredentialsError
+	}
+
+	tokenBytes := make([]byte, 32)
+	_, err = rand.Read(tokenBytes)
+	if err != nil {
+		return "", nil, err
+	}
+	token := hex.EncodeToString(tokenBytes)
+
+	session := &entity.Session{
+		UserID:   userID,
+		Nickname: nickname,
+		Expires:  time.Now().Add(a.sessionTTL),
+	}
+	err = a.sessions.CreateSession(hashEmailToken(token), session)
+	if err != nil {
+		return "", nil, err
+	}
+	return token, session, nil
+}
+
+func (a *AuthApp) Logout(token string) error {
+	return a.sessions.DeleteSession(hashEmailToken(token))
+}
+
+func (a *AuthApp) GetSession(token string) (*entity.Session, error) {
+	if token == "" {
+		return nil, entity.SessionNotFoundError
+	}
+	return a.sessions.GetSession(hashEmailToken(token))
+}
+
+// RequestPasswordReset mails reset token if there is a user with the email. Only the email format
+// is checked before returning, the lookup and the mail happen in background, so that neither
+// the answer nor its timing tells whether the email is registered
+func (a *AuthApp) RequestPasswordReset(email string) error {
+	err := validateEmail(email)
+	if err != nil {
+		return err
+	}
+
+	go func() {
+		err := a.sendPasswordReset(email)
+		if err != nil && a.resetErrors != nil {
+			a.resetErrors(err)
+		}
+	}()
+	return nil
+}
+
+func (a *AuthApp) sendPasswordReset(email string) error {
+	nickname, err := a.us.GetUserNicknameWithEmail(email)
+	if err != nil {
+		return nil
+	}
+	user, err := a.us.GetUserByNickname(nickname)
+	if err != nil {
+		return err
+	}
+	if user.IsDeleted {
+		return nil
+	}
+	return a.emailApp.SendPasswordReset(user)
+}
+
+// ResetPassword sets the new password and ends all sessions of the user
+func (a *AuthApp) ResetPassword(reset *entity.PasswordReset) error {
+	if reset.Token == "" {
+		return entity.EmailTokenError
+	}
+	passwordHash, err := hashPassword(reset.Password)
+	if err != nil {
+		return err
+	}
+
+	nickname, err := a.us.ResetPassword(hashEmailToken(reset.Token), passwordHash)
+	if err != nil {
+		return err
+	}
+	return a.auditApp.Record(nickname, entity.AuditUserPasswordReset, entity.AuditTargetUser, nickname, nil, nil)
+}
@@ -0,0 +1,87 @@
+package app
+
+import (
+	"forum/domain/entity"
+	"golang.org/x/crypto/bcrypt"
+	"testing"
+	"time"
+)
+
+func newTestAuthApp(t *testing.T) (*AuthApp, *fakeEmailApp) {
+	hash, err := bcrypt.GenerateFromPassword([]byte("correct horse"), bcrypt.MinCost)
+	if err != nil {
+		t.Fatal(err)
+	}
+	users := &fakeUserRepo{
+		users: map[string]*entity.User{
+			"Alice": {Nickname: "Alice", Email: "alice@example.com"},
+		},
+		hashes: map[string]string{"Alice": string(hash)},
+	}
+	emailApp := &fakeEmailApp{resets: make(chan string, 1)}
+	sessions := &fakeSessionRepo{sessions: map[string]*entity.Session{}}
+	return NewAuthApp(users, sessions, emailApp, &fakeAuditApp{}, time.Hour, func(err error) {
+		t.Error(err)
+	}), emailApp
+}
+
+func TestLoginUsesStoredNickname(t *testing.T) {
+	authApp, _ := newTestAuthApp(t)
+
+	token, session, err := authApp.Login(&entity.Credentials{Nickname: "ALICE", Password: "correct horse"})
+	if err != nil {
+		t.Fatalf("login failed: %v", err)
+	}
+	if session.Nickname != "Alice" {
+		t.Errorf("session nickname is %q, want stored %q", session.Nickname, "Alice")
+	}
+
+	stored, err := authApp.GetSession(token)
+	if err != nil || stored.Nickname != "Alice" {
+		t.Errorf("GetSession returned %v, %v", stored, err)
+	}
+}
+
+func TestLoginRejectsWrongCredentials(t *testing.T) {
+	authApp, _ := newTestAuthApp(t)
+
+	cases := []entity.Credentials{
+		{Nickname: "Alice", Password: "wrong password"},
+		{Nickname: "Bob", Password: "correct horse"},
+	}
+	for _, credentials := range cases {
+		_, _, err := authApp.Login(&credentials)
+		if err != entity.WrongCredentialsError {
+			t.Errorf("login of %q returned %v, want %v", credentials.Nickname, err, entity.WrongCredentialsError)
+		}
+	}
+}
+
+func TestRequestPasswordReset(t *testing.T) {
+	authApp, emailApp := newTestAuthApp(t)
+
+	if err := authApp.RequestPasswordReset("not an email"); err != entity.WrongEmailError {
+		t.Errorf("malformed email returned %v, want %v", err, entity.WrongEmailError)
+	}
+
+	if err := authApp.RequestPasswordReset("nobody@example.com"); err != nil {
+		t.Errorf("unknown email returned %v", err)
+	}
+	if err := authApp.RequestPasswordReset("alice@example.com"); err != nil {
+		t.Errorf("known email returned %v", err)
+	}
+
+	select {
+	case nickname := <-emailApp.resets:
+		if nickname != "Alice" {
+			t.Errorf("reset mailed to %q", nickname)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("reset mail was not sent")
+	}
+	select {
+	case nickname := <-emailApp.resets:
+		t.Errorf("unexpected reset mail to %q", nickname)
+	case <-time.After(50 * time.Millisecond):
+	}
+}
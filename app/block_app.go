@@ -0,0 +1,106 @@
+package app
+
+import (
+	"forum/domain/entity"
+	"forum/domain/repository"
+	"strings"
+)
+
+type BlockApp struct {
+	b  repository.BlockRepository
+	us repository.UserRepository
+}
+
+func NewBlockApp(b repository.BlockRepository, us repository.UserRepository) *BlockApp {
+	return &BlockApp{b: b, us: us}
+}
+
+type BlockAppInterface interface {
+	Block(nickname, blocked string) error
+	Unblock(nickname, blocked string) error
+	GetBlocked(nickname string, limit int32, since string, desc bool) ([]entity.User, error)
+	CollapseBlockedPosts(viewer string, posts []entity.Post) error
+}
+
+// blockPair returns nicknames of both users as they are stored
+func (b *BlockApp) blockPair(nickname, blocked string) (string, string, error) {
+	nickname, err := b.us.CheckIfUserExists(nickname)
+	if err != nil {
+		return "", "", entity.UserDoesntExistsError
+	}
+	blocked, err = b.us.CheckIfUserExists(blocked)
+	if err != nil {
+		return "", "", entity.UserDoesntExistsError
+	}
+	if strings.EqualFold(nickname, blocked) {
+		return "", "", entity.BlockSelfError
+	}
+	return nickname, blocked, nil
+}
+
+// Block is idempotent, the users stop following each other
+func (b *BlockApp) Block(nickname, blocked string) error {
+	nickname, blocked, err := b.blockPair(nickname, blocked)
+	if err != nil {
+		return err
+	}
+	return b.b.Block(nickname, blocked)
+}
+
+func (b *BlockApp) Unblock(nickname, blocked string) error {
+	nickname, blocked, err := b.blockPair(nickname, blocked)
+	if err != nil {
+		return err
+	}
+	return b.b.Unblock(nickname, blocked)
+}
+
+func (b *BlockApp) GetBlocked(nickname string, limit int32, since string, desc bool) ([]entity.User, error) {
+	nickname, err := b.us.CheckIfUserExists(nickname)
+	if err != nil {
+		return nil, entity.UserDoesntExistsError
+	}
+	return b.b.GetBlocked(nickname, limit, since, desc)
+}
+
+func (b *BlockApp) blockedSet(nickname string) (map[string]bool, error) {
+	nicknames, err := b.b.GetBlockedNicknames(nickname)
+	if err != nil {
+		return nil, err
+	}
+	blocked := make(map[string]bool, len(nicknames))
+	for _, blockedNickname := range nicknames {
+		blocked[strings.ToLower(blockedNickname)] = true
+	}
+	return blocked, nil
+}
+
+// CollapseBlockedPosts strips content of posts whose authors are blocked by viewer. Posts stay in the list
+// with their ids and parents, so that replies to them keep their place in trees. Quotes of blocked posts inside
+// other posts are left as they are, they carry only the id and the author of the quoted post
+func (b *BlockApp) CollapseBlockedPosts(viewer string, posts []entity.Post) error {
+	if viewer == "" || len(posts) == 0 {
+		return nil
+	}
+
+	blocked, err := b.blockedSet(viewer)
+	if err != nil {
+		return err
+	}
+	if len(blocked) == 0 {
+		return nil
+	}
+
+	for i := range posts {
+		if !blocked[strings.ToLower(posts[i].Author)] {
+			continue
+		}
+		posts[i].IsBlocked = true
+		posts[i].Message = ""
+		posts[i].MessageHTML = ""
+		posts[i].Reactions = nil
+		posts[i].Quotes = nil
+		posts[i].Attachments = nil
+	}
+	return nil
+}
@@ -0,0 +1,63 @@
+package app
+
+import (
+	"forum/domain/entity"
+	"forum/domain/repository"
+	"reflect"
+	"testing"
+)
+
+type fakeBlockRepo struct {
+	repository.BlockRepository
+	blocked map[string][]string
+}
+
+func (f *fakeBlockRepo) GetBlockedNicknames(nickname string) ([]string, error) {
+	return f.blocked[nickname], nil
+}
+
+func blockTestPosts() []entity.Post {
+	return []entity.Post{
+		{ID: 1, Author: "bob", Message: "first", Thread: 1},
+		{
+			ID:          2,
+			Author:      "Mallory",
+			Message:     "**spam**",
+			MessageHTML: "<p><strong>spam</strong></p>",
+			Parent:      1,
+			Thread:      1,
+			Reactions:   map[string]int{"+1": 2},
+			Quotes:      []entity.PostQuote{{ID: 1, Author: "bob", Thread: 1}},
+			QuotedBy:    []int{3},
+			Attachments: []entity.Attachment{{ID: 7}},
+		},
+		{ID: 3, Author: "carol", Message: "reply", Parent: 2, Thread: 1, Quotes: []entity.PostQuote{{ID: 2, Author: "Mallory", Thread: 1}}},
+	}
+}
+
+func TestCollapseBlockedPostsKeepsTreeShape(t *testing.T) {
+	blocks := NewBlockApp(&fakeBlockRepo{blocked: map[string][]string{"alice": {"mallory"}}}, &fakeUserRepo{})
+	posts := blockTestPosts()
+	if err := blocks.CollapseBlockedPosts("alice", posts); err != nil {
+		t.Fatal(err)
+	}
+
+	want := blockTestPosts()
+	want[1] = entity.Post{ID: 2, Author: "Mallory", Parent: 1, Thread: 1, IsBlocked: true, QuotedBy: []int{3}}
+	if !reflect.DeepEqual(posts, want) {
+		t.Errorf("got posts\n%+v\nwant\n%+v", posts, want)
+	}
+}
+
+func TestCollapseBlockedPostsWithoutBlocks(t *testing.T) {
+	blocks := NewBlockApp(&fakeBlockRepo{blocked: map[string][]string{"alice": {"mallory"}}}, &fakeUserRepo{})
+	for _, viewer := range []string{"", "bob"} {
+		posts := blockTestPosts()
+		if err := blocks.CollapseBlockedPosts(viewer, posts); err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(posts, blockTestPosts()) {
+			t.Errorf("viewer %q: posts changed to %+v", viewer, posts)
+		}
+	}
+}
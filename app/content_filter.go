@@ -0,0 +1,172 @@
+package app
+
+import (
+	"forum/domain/entity"
+	"forum/domain/repository"
+	"regexp"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// ContentFilter checks posts before they are stored.
+// A filter may reject the whole batch with an error, change post messages
+// or mark posts as held until a moderator approves them
+type ContentFilter interface {
+	Filter(forum string, posts []entity.Post) error
+}
+
+// ContentFilterPipeline runs filters one by one stopping at the first error
+type ContentFilterPipeline []ContentFilter
+
+func (pipeline ContentFilterPipeline) Filter(forum string, posts []entity.Post) error {
+	for _, filter := range pipeline {
+		err := filter.Filter(forum, posts)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// WordFilter applies banned words list of the forum
+type WordFilter struct {
+	f repository.ForumRepository
+}
+
+func NewWordFilter(f repository.ForumRepository) *WordFilter {
+	return &WordFilter{f: f}
+}
+
+func (w *WordFilter) Filter(forum string, posts []entity.Post) error {
+	filter, err := w.f.GetWordFilter(forum)
+	if err != nil {
+		return err
+	}
+	if len(filter.Words) == 0 {
+		return nil
+	}
+
+	banned := make(map[string]bool, len(filter.Words))
+	for _, word := range filter.Words {
+		banned[strings.ToLower(word)] = true
+	}
+
+	for i := range posts {
+		masked, found := maskWords(posts[i].Message, banned)
+		if !found {
+			continue
+		}
+		if filter.Mode != entity.MaskFilterMode {
+			return entity.BannedWordsError
+		}
+		posts[i].Message = masked
+	}
+	return nil
+}
+
+// maskWords replaces every letter of banned words with asterisks.
+// Words are compared case-insensitively as whole sequences of letters and digits
+func maskWords(message string, banned map[string]bool) (string, bool) {
+	runes := []rune(message)
+	found := false
+	for start := 0; start < len(runes); {
+		if !isWordRune(runes[start]) {
+			start++
+			continue
+		}
+
+		end := start
+		for end < len(runes) && isWordRune(runes[end]) {
+			end++
+		}
+		if banned[strings.ToLower(string(runes[start:end]))] {
+			found = true
+			for j := start; j < end; j++ {
+				runes[j] = '*'
+			}
+		}
+		start = end
+	}
+	return string(runes), found
+}
+
+func isWordRune(r rune) bool {
+	return unicode.IsLetter(r) || unicode.IsDigit(r)
+}
+
+var postLinkRegexp = regexp.MustCompile(`(?i)\b(?:https?://|www\.)`)
+
+// LinkFilter holds posts of new users containing more links than allowed
+type LinkFilter struct {
+	u             repository.UserRepository
+	newUserPeriod time.Duration
+	maxLinks      int
+}
+
+func NewLinkFilter(u repository.UserRepository, newUserPeriod time.Duration, maxLinks int) *LinkFilter {
+	return &LinkFilter{u: u, newUserPeriod: newUserPeriod, maxLinks: maxLinks}
+}
+
+func (l *LinkFilter) Filter(forum string, posts []entity.Post) error {
+	linked := make([]int, 0)
+	authors := make([]string, 0)
+	for i, post := range posts {
+		if len(postLinkRegexp.FindAllStringIndex(post.Message, l.maxLinks+1)) > l.maxLinks {
+			linked = append(linked, i)
+			authors = append(authors, post.Author)
+		}
+	}
+	if len(linked) == 0 {
+		return nil
+	}
+
+	created, err := l.u.GetUsersCreated(authors)
+	if err != nil {
+		return err
+	}
+
+	newSince := time.Now().Add(-l.newUserPeriod)
+	for _, i := range linked {
+		userCreated, ok := created[strings.ToLower(posts[i].Author)]
+		if ok && userCreated.After(newSince) {
+			posts[i].IsHeld = true
+		}
+	}
+	return nil
+}
+
+// DuplicateFilter rejects messages already posted by the same author within the window
+type DuplicateFilter struct {
+	p      repository.PostRepository
+	window time.Duration
+}
+
+func NewDuplicateFilter(p repository.PostRepository, window time.Duration) *DuplicateFilter {
+	return &DuplicateFilter{p: p, window: window}
+}
+
+func (d *DuplicateFilter) Filter(forum string, posts []entity.Post) error {
+	authors := make([]string, 0, len(posts))
+	messages := make([]string, 0, len(posts))
+	seen := make(map[string]bool, len(posts))
+	for _, post := range posts {
+		key := strings.ToLower(post.Author) + "\x00" + post.Message
+		if seen[key] {
+			return entity.DuplicatePostError
+		}
+		seen[key] = true
+
+		authors = append(authors, post.Author)
+		messages = append(messages, post.Message)
+	}
+
+	duplicates, err := d.p.FindRecentDuplicates(authors, messages, time.Now().Add(-d.window))
+	if err != nil {
+		return err
+	}
+	if len(duplicates) != 0 {
+		return entity.DuplicatePostError
+	}
+	return nil
+}
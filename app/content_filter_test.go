@@ -0,0 +1,161 @@
+package app
+
+import (
+	"forum/domain/entity"
+	"testing"
+	"time"
+)
+
+func TestMaskWords(t *testing.T) {
+	banned := map[string]bool{"spam": true, "eggs": true}
+	cases := []struct {
+		message string
+		masked  string
+		found   bool
+	}{
+		{"no banned words", "no banned words", false},
+		{"SPAM and Eggs!", "**** and ****!", true},
+		{"spammer is not spam", "spammer is not ****", true},
+		{"спам spam", "спам ****", true},
+	}
+
+	for _, c := range cases {
+		masked, found := maskWords(c.message, banned)
+		if masked != c.masked || found != c.found {
+			t.Errorf("maskWords(%q) = %q, %v, want %q, %v", c.message, masked, found, c.masked, c.found)
+		}
+	}
+}
+
+func TestWordFilter(t *testing.T) {
+	forums := &fakeForumRepo{filters: map[string]*entity.WordFilter{
+		"rejecting": {Words: []string{"spam"}, Mode: entity.RejectFilterMode},
+		"masking":   {Words: []string{"spam"}, Mode: entity.MaskFilterMode},
+	}}
+	filter := NewWordFilter(forums)
+
+	posts := []entity.Post{{Message: "fine"}, {Message: "buy Spam"}}
+	if err := filter.Filter("rejecting", posts); err != entity.BannedWordsError {
+		t.Errorf("reject mode returned %v, want %v", err, entity.BannedWordsError)
+	}
+
+	if err := filter.Filter("masking", posts); err != nil {
+		t.Fatalf("mask mode returned %v", err)
+	}
+	if posts[0].Message != "fine" || posts[1].Message != "buy ****" {
+		t.Errorf("unexpected masked messages %q, %q", posts[0].Message, posts[1].Message)
+	}
+
+	if err := filter.Filter("unfiltered", []entity.Post{{Message: "spam"}}); err != nil {
+		t.Errorf("forum without words returned %v", err)
+	}
+}
+
+func TestLinkFilterHoldsNewUsers(t *testing.T) {
+	users := &fakeUserRepo{
+		users: map[string]*entity.User{"old": {Nickname: "old"}, "new": {Nickname: "new"}},
+		created: map[string]time.Time{
+			"old": time.Now().Add(-48 * time.Hour),
+			"new": time.Now().Add(-time.Hour),
+		},
+	}
+	filter := NewLinkFilter(users, 24*time.Hour, 1)
+
+	posts := []entity.Post{
+		{Author: "old", Message: "https://a.example https://b.example"},
+		{Author: "new", Message: "https://a.example"},
+		{Author: "new", Message: "https://a.example www.b.example"},
+	}
+	if err := filter.Filter("forum", posts); err != nil {
+		t.Fatal(err)
+	}
+
+	for i, held := range []bool{false, false, true} {
+		if posts[i].IsHeld != held {
+			t.Errorf("post %d is held %v, want %v", i, posts[i].IsHeld, held)
+		}
+	}
+}
+
+func TestDuplicateFilter(t *testing.T) {
+	postsRepo := &fakePostRepo{posts: map[int]*entity.Post{
+		1: {ID: 1, Author: "alice", Message: "hello"},
+	}}
+	filter := NewDuplicateFilter(postsRepo, time.Minute)
+
+	cases := []struct {
+		name  string
+		posts []entity.Post
+		err   error
+	}{
+		{"recent duplicate", []entity.Post{{Author: "Alice", Message: "hello"}}, entity.DuplicatePostError},
+		{"duplicate in batch", []entity.Post{{Author: "bob", Message: "hi"}, {Author: "BOB", Message: "hi"}},
+			entity.DuplicatePostError},
+		{"other author", []entity.Post{{Author: "bob", Message: "hello"}}, nil},
+	}
+	for _, c := range cases {
+		if err := filter.Filter("forum", c.posts); err != c.err {
+			t.Errorf("%s: got %v, want %v", c.name, err, c.err)
+		}
+	}
+}
+
+func newTestPostApp(posts *fakePostRepo, filter ContentFilter) *PostApp {
+	return NewPostApp(posts, &fakeReactionApp{}, &fakeAttachmentApp{}, &fakeAuditApp{}, filter)
+}
+
+func TestChangePostMessageRunsFilters(t *testing.T) {
+	forums := &fakeForumRepo{filters: map[string]*entity.WordFilter{
+		"rejecting": {Words: []string{"spam"}, Mode: entity.RejectFilterMode},
+		"masking":   {Words: []string{"spam"}, Mode: entity.MaskFilterMode},
+	}}
+	posts := &fakePostRepo{posts: map[int]*entity.Post{
+		1: {ID: 1, Author: "alice", Forum: "rejecting", Message: "hello", Format: entity.PlainFormat},
+		2: {ID: 2, Author: "alice", Forum: "masking", Message: "hello", Format: entity.PlainFormat},
+	}}
+	postApp := newTestPostApp(posts, ContentFilterPipeline{NewWordFilter(forums)})
+
+	_, err := postApp.ChangePostMessage(&entity.Post{ID: 1, Message: "spam"}, "alice")
+	if err != entity.BannedWordsError {
+		t.Errorf("edit in rejecting forum returned %v, want %v", err, entity.BannedWordsError)
+	}
+	if posts.posts[1].Message != "hello" {
+		t.Errorf("rejected edit changed the message to %q", posts.posts[1].Message)
+	}
+
+	edited, err := postApp.ChangePostMessage(&entity.Post{ID: 2, Message: "more spam"}, "alice")
+	if err != nil {
+		t.Fatalf("edit in masking forum returned %v", err)
+	}
+	if edited.Message != "more ****" {
+		t.Errorf("edited message is %q, want masked", edited.Message)
+	}
+	if len(posts.audit) != 1 || posts.audit[0].Actor != "alice" {
+		t.Errorf("edit audit is %+v", posts.audit)
+	}
+}
+
+// holdAll marks every post as held, like link filter does for new users
+type holdAll struct{}
+
+func (holdAll) Filter(forum string, posts []entity.Post) error {
+	for i := range posts {
+		posts[i].IsHeld = true
+	}
+	return nil
+}
+
+func TestChangePostMessageHoldsPost(t *testing.T) {
+	posts := &fakePostRepo{posts: map[int]*entity.Post{
+		1: {ID: 1, Author: "alice", Forum: "forum", Message: "hello", Format: entity.PlainFormat},
+	}}
+	postApp := newTestPostApp(posts, holdAll{})
+
+	edited, err := postApp.ChangePostMessage(&entity.Post{ID: 1, Message: "https://example.com"}, "alice")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !edited.IsHeld {
+		t.Error("edit held by the filter did not hold the post")
+	}
+}
@@ -0,0 +1,154 @@
+package app
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"forum/domain/entity"
+	"forum/domain/repository"
+	"net/mail"
+	"net/url"
+	"time"
+)
+
+const defaultEmailTokenTTL = 24 * time.Hour
+
+// passwordResetTokenTTL is shorter than email tokens ttl since reset token gives access to the account
+const passwordResetTokenTTL = time.Hour
+
+type EmailApp struct {
+	us       repository.UserRepository
+	mailer   repository.Mailer
+	auditApp AuditAppInterface
+	tokenTTL time.Duration
+	// confirmURL and resetURL are the pages that post token from their query to the api
+	confirmURL string
+	resetURL   string
+}
+
+func NewEmailApp(us repository.UserRepository,
+	mailer repository.Mailer,
+	auditApp AuditAppInterface,
+	tokenTTL time.Duration,
+	confirmURL string,
+	resetURL string) *EmailApp {
+	if tokenTTL <= 0 {
+		tokenTTL = defaultEmailTokenTTL
+	}
+	return &EmailApp{
+		us:         us,
+		mailer:     mailer,
+		auditApp:   auditApp,
+		tokenTTL:   tokenTTL,
+		confirmURL: confirmURL,
+		resetURL:   resetURL,
+	}
+}
+
+type EmailAppInterface interface {
+	SendVerification(nickname string) error
+	RequestEmailChange(user *entity.User, email string) error
+	ConfirmEmail(token string) (*entity.User, error)
+	SendPasswordReset(user *entity.User) error
+}
+
+// validateEmail accepts bare addresses only, display names and comments are rejected
+func validateEmail(email string) error {
+	address, err := mail.ParseAddress(email)
+	if err != nil || address.Address != email {
+		return entity.WrongEmailError
+	}
+	return nil
+}
+
+// hashEmailToken hashes tokens before they are stored, it is used for session tokens as well
+func hashEmailToken(token string) string {
+	hash := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(hash[:])
+}
+
+func (e *EmailApp) SendVerification(nickname string) error {
+	user, err := e.us.GetUserByNickname(nickname)
+	if err != nil {
+		return err
+	}
+	if user.IsDeleted {
+		return entity.UserDoesntExistsError
+	}
+	if user.EmailVerified {
+		return entity.EmailVerifiedError
+	}
+
+	return e.sendToken(user, user.Email, entity.VerifyEmailPurpose, e.tokenTTL, e.confirmURL, "Confirm your email",
+		"Hello, %v!\n\nPlease confirm your email address by opening the link:\n%v\n")
+}
+
+// RequestEmailChange sends confirmation to the new address, the address is changed only when it is confirmed
+func (e *EmailApp) RequestEmailChange(user *entity.User, email string) error {
+	return e.sendToken(user, email, entity.ChangeEmailPurpose, e.tokenTTL, e.confirmURL, "Confirm your new email",
+		"Hello, %v!\n\nThis address was set as the new email of your account. "+
+			"Please confirm the change by opening the link:\n%v\n\n"+
+			"If you did not request the change, ignore this mail.\n")
+}
+
+// SendPasswordReset sends single use reset token to the current address of the user
+func (e *EmailApp) SendPasswordReset(user *entity.User) error {
+	return e.sendToken(user, user.Email, entity.ResetPasswordPurpose, passwordResetTokenTTL, e.resetURL,
+		"Reset your password",
+		"Hello, %v!\n\nSomeone asked to reset password of your account. "+
+			"Set a new password by opening the link:\n%v\n\n"+
+			"The link expires in an hour. If you did not ask for it, ignore this mail.\n")
+}
+
+func (e *EmailApp) sendToken(user *entity.User,
+	email string,
+	purpose string,
+	ttl time.Duration,
+	linkBase string,
+	subject string,
+	bodyFormat string) error {
+	tokenBytes := make([]byte, 32)
+	_, err := rand.Read(tokenBytes)
+	if err != nil {
+		return err
+	}
+	token := hex.EncodeToString(tokenBytes)
+
+	err = e.us.SaveEmailToken(hashEmailToken(token), &entity.EmailToken{
+		UserID:  user.ID,
+		Email:   email,
+		Purpose: purpose,
+		Expires: time.Now().Add(ttl),
+	})
+	if err != nil {
+		return err
+	}
+
+	link := linkBase + "?" + url.Values{"token": {token}}.Encode()
+	return e.mailer.Send(&entity.Mail{
+		To:      email,
+		Subject: subject,
+		Body:    fmt.Sprintf(bodyFormat, user.Nickname, link),
+	})
+}
+
+func (e *EmailApp) ConfirmEmail(token string) (*entity.User, error) {
+	if token == "" {
+		return nil, entity.EmailTokenError
+	}
+
+	used, nickname, previousEmail, err := e.us.ConfirmEmail(hashEmailToken(token))
+	if err != nil {
+		return nil, err
+	}
+
+	if used.Purpose == entity.ChangeEmailPurpose {
+		err = e.auditApp.Record(nickname, entity.AuditUserEdit, entity.AuditTargetUser, nickname,
+			map[string]string{"email": previousEmail}, map[string]string{"email": used.Email})
+		if err != nil {
+			return nil, err
+		}
+	}
+	return e.us.GetUserByNickname(nickname)
+}
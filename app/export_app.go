@@ -0,0 +1,183 @@
+package app
+
+import (
+	"archive/zip"
+	"encoding/json"
+	"forum/domain/entity"
+	"forum/domain/repository"
+	"io"
+	"time"
+)
+
+type ExportApp struct {
+	us       repository.UserRepository
+	t        repository.ThreadRepository
+	p        repository.PostRepository
+	a        repository.AuditRepository
+	s        repository.SubscriptionRepository
+	auditApp AuditAppInterface
+}
+
+func NewExportApp(us repository.UserRepository,
+	t repository.ThreadRepository,
+	p repository.PostRepository,
+	a repository.AuditRepository,
+	s repository.SubscriptionRepository,
+	auditApp AuditAppInterface) *ExportApp {
+	return &ExportApp{us: us, t: t, p: p, a: a, s: s, auditApp: auditApp}
+}
+
+type ExportAppInterface interface {
+	PrepareExport(nickname string) (*UserExport, error)
+	WriteExport(export *UserExport, w io.Writer) error
+}
+
+type exportProfile struct {
+	*entity.User
+	Privacy       *entity.UserPrivacy          `json:"privacy"`
+	Notifications *entity.NotificationSettings `json:"notifications"`
+	Subscriptions []entity.Subscription        `json:"subscriptions"`
+}
+
+// UserExport is the looked up part of an export, it is made before anything is written to the client
+type UserExport struct {
+	profile exportProfile
+}
+
+// Nickname is the stored nickname of the exported user
+func (e *UserExport) Nickname() string {
+	return e.profile.Nickname
+}
+
+// jsonArrayWriter encodes a json array element by element
+type jsonArrayWriter struct {
+	w     io.Writer
+	enc   *json.Encoder
+	count int
+}
+
+func newJSONArrayWriter(w io.Writer) *jsonArrayWriter {
+	return &jsonArrayWriter{w: w, enc: json.NewEncoder(w)}
+}
+
+func (a *jsonArrayWriter) Write(v interface{}) error {
+	separator := ","
+	if a.count == 0 {
+		separator = "["
+	}
+	a.count++
+
+	_, err := io.WriteString(a.w, separator)
+	if err != nil {
+		return err
+	}
+	return a.enc.Encode(v)
+}
+
+func (a *jsonArrayWriter) Close() error {
+	closing := "]"
+	if a.count == 0 {
+		closing = "[]"
+	}
+	_, err := io.WriteString(a.w, closing)
+	return err
+}
+
+// PrepareExport looks up the profile and records the export, so that lookup errors
+// can still be answered with a status before the archive is streamed
+func (e *ExportApp) PrepareExport(nickname string) (*UserExport, error) {
+	user, err := e.us.GetUserByNickname(nickname)
+	if err != nil {
+		return nil, err
+	}
+	if user.IsDeleted {
+		return nil, entity.UserDoesntExistsError
+	}
+	user.Stats, err = e.us.GetUserStats(user.Nickname)
+	if err != nil {
+		return nil, err
+	}
+	privacy, err := e.us.GetUserPrivacy(user.Nickname)
+	if err != nil {
+		return nil, err
+	}
+	settings, err := e.s.GetNotificationSettings(user.Nickname)
+	if err != nil {
+		return nil, err
+	}
+	subscriptions, err := e.s.GetSubscriptions(user.Nickname)
+	if err != nil {
+		return nil, err
+	}
+
+	err = e.auditApp.Record(user.Nickname, entity.AuditUserExport, entity.AuditTargetUser, user.Nickname, nil, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	return &UserExport{profile: exportProfile{
+		User:          user,
+		Privacy:       privacy,
+		Notifications: settings,
+		Subscriptions: subscriptions,
+	}}, nil
+}
+
+// WriteExport writes zip archive with personal data of the prepared export to w. Rows are written to the archive
+// as they are read, so nothing but the profile and subscriptions is held in memory. Revisions are edits
+// of the user's posts and threads recorded in the audit log
+func (e *ExportApp) WriteExport(export *UserExport, w io.Writer) error {
+	user := export.profile.User
+	archive := zip.NewWriter(w)
+	created := time.Now()
+
+	file, err := archive.CreateHeader(&zip.FileHeader{Name: "profile.json", Method: zip.Deflate, Modified: created})
+	if err != nil {
+		return err
+	}
+	err = json.NewEncoder(file).Encode(export.profile)
+	if err != nil {
+		return err
+	}
+
+	lists := []struct {
+		name    string
+		forEach func(write func(v interface{}) error) error
+	}{
+		{"threads.json", func(write func(v interface{}) error) error {
+			return e.t.ForEachUserThread(user.Nickname, func(thread *entity.Thread) error { return write(thread) })
+		}},
+		{"posts.json", func(write func(v interface{}) error) error {
+			return e.p.ForEachUserPost(user.Nickname, func(post *entity.Post) error { return write(post) })
+		}},
+		{"revisions.json", func(write func(v interface{}) error) error {
+			return e.a.ForEachContentRevision(user.Nickname, func(entry *entity.AuditEntry) error { return write(entry) })
+		}},
+		{"votes.json", func(write func(v interface{}) error) error {
+			return e.us.ForEachUserVote(user.Nickname, func(vote *entity.VoteRecord) error { return write(vote) })
+		}},
+		{"notifications.json", func(write func(v interface{}) error) error {
+			return e.s.ForEachNotification(user.Nickname, func(notification *entity.Notification) error {
+				return write(notification)
+			})
+		}},
+	}
+	for _, list := range lists {
+		file, err = archive.CreateHeader(&zip.FileHeader{Name: list.name, Method: zip.Deflate, Modified: created})
+		if err != nil {
+			return err
+		}
+
+		array := newJSONArrayWriter(file)
+		err = list.forEach(array.Write)
+		if err != nil {
+			return err
+		}
+		err = array.Close()
+		if err != nil {
+			return err
+		}
+	}
+
+	return archive.Close()
+}
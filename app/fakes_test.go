@@ -0,0 +1,217 @@
+package app
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"forum/domain/entity"
+	"forum/domain/repository"
+	"strings"
+	"sync"
+	"time"
+)
+
+// fakeUserRepo keeps users in memory, methods that tests do not need panic through the nil embedded interface
+type fakeUserRepo struct {
+	repository.UserRepository
+	users   map[string]*entity.User
+	hashes  map[string]string
+	created map[string]time.Time
+}
+
+func (f *fakeUserRepo) find(nickname string) *entity.User {
+	for stored, user := range f.users {
+		if strings.EqualFold(stored, nickname) {
+			return user
+		}
+	}
+	return nil
+}
+
+func (f *fakeUserRepo) CheckIfUserExists(nickname string) (string, error) {
+	user := f.find(nickname)
+	if user == nil {
+		return "", entity.UserDoesntExistsError
+	}
+	return user.Nickname, nil
+}
+
+func (f *fakeUserRepo) GetUserByNickname(nickname string) (*entity.User, error) {
+	user := f.find(nickname)
+	if user == nil {
+		return nil, entity.UserDoesntExistsError
+	}
+	return user, nil
+}
+
+func (f *fakeUserRepo) GetUserNicknameWithEmail(email string) (string, error) {
+	for _, user := range f.users {
+		if strings.EqualFold(user.Email, email) {
+			return user.Nickname, nil
+		}
+	}
+	return "", entity.UserDoesntExistsError
+}
+
+func (f *fakeUserRepo) GetPasswordHash(nickname string) (int, string, string, error) {
+	user := f.find(nickname)
+	if user == nil || user.IsDeleted {
+		return 0, "", "", entity.UserDoesntExistsError
+	}
+	return 1, user.Nickname, f.hashes[user.Nickname], nil
+}
+
+func (f *fakeUserRepo) GetUsersCreated(nicknames []string) (map[string]time.Time, error) {
+	created := make(map[string]time.Time, len(nicknames))
+	for _, nickname := range nicknames {
+		if user := f.find(nickname); user != nil {
+			created[strings.ToLower(nickname)] = f.created[user.Nickname]
+		}
+	}
+	return created, nil
+}
+
+func (f *fakeUserRepo) DeleteUser(nickname string) (*entity.User, error) {
+	user := f.find(nickname)
+	if user == nil || user.IsDeleted {
+		return nil, entity.UserDoesntExistsError
+	}
+	delete(f.users, user.Nickname)
+	deleted := &entity.User{
+		ID:        user.ID,
+		Nickname:  fmt.Sprintf("deleted-%d", user.ID),
+		Fullname:  entity.DeletedUserFullname,
+		IsDeleted: true,
+	}
+	f.users[deleted.Nickname] = deleted
+	return deleted, nil
+}
+
+type fakeSessionRepo struct {
+	sessions map[string]*entity.Session
+}
+
+func (f *fakeSessionRepo) CreateSession(tokenHash string, session *entity.Session) error {
+	f.sessions[tokenHash] = session
+	return nil
+}
+
+func (f *fakeSessionRepo) GetSession(tokenHash string) (*entity.Session, error) {
+	session, ok := f.sessions[tokenHash]
+	if !ok {
+		return nil, entity.SessionNotFoundError
+	}
+	return session, nil
+}
+
+func (f *fakeSessionRepo) DeleteSession(tokenHash string) error {
+	delete(f.sessions, tokenHash)
+	return nil
+}
+
+// fakeEmailApp reports nicknames of password reset mails to the resets channel
+type fakeEmailApp struct {
+	EmailAppInterface
+	resets chan string
+}
+
+func (f *fakeEmailApp) SendPasswordReset(user *entity.User) error {
+	f.resets <- user.Nickname
+	return nil
+}
+
+type fakeAuditApp struct {
+	AuditAppInterface
+	mu      sync.Mutex
+	entries []entity.AuditEntry
+}
+
+func (f *fakeAuditApp) Record(actor string, action string, targetType string, targetID string,
+	before interface{}, after interface{}) error {
+	entry := entity.AuditEntry{Actor: actor, Action: action, TargetType: targetType, TargetID: targetID}
+	if before != nil {
+		entry.Before, _ = json.Marshal(before)
+	}
+	if after != nil {
+		entry.After, _ = json.Marshal(after)
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.entries = append(f.entries, entry)
+	return nil
+}
+
+type fakeForumRepo struct {
+	repository.ForumRepository
+	filters map[string]*entity.WordFilter
+}
+
+func (f *fakeForumRepo) GetWordFilter(slug string) (*entity.WordFilter, error) {
+	if filter, ok := f.filters[slug]; ok {
+		return filter, nil
+	}
+	return &entity.WordFilter{Mode: entity.RejectFilterMode}, nil
+}
+
+var errPostNotFound = errors.New("post not found")
+
+// fakePostRepo keeps posts by id, messages of recent posts are the posts themselves
+type fakePostRepo struct {
+	repository.PostRepository
+	posts map[int]*entity.Post
+	audit []*entity.AuditEntry
+}
+
+func (f *fakePostRepo) GetPostDetails(postID int) (*entity.Post, error) {
+	post, ok := f.posts[postID]
+	if !ok {
+		return nil, errPostNotFound
+	}
+	copied := *post
+	return &copied, nil
+}
+
+func (f *fakePostRepo) ChangePostMessage(post *entity.Post, audit *entity.AuditEntry) (*entity.Post, error) {
+	stored := f.posts[post.ID]
+	stored.Message = post.Message
+	stored.Format = post.Format
+	stored.MessageHTML = post.MessageHTML
+	stored.IsEdited = true
+	stored.IsHeld = stored.IsHeld || post.IsHeld
+	f.audit = append(f.audit, audit)
+	copied := *stored
+	return &copied, nil
+}
+
+func (f *fakePostRepo) GetPostsQuotes(postIDs []int) (map[int][]entity.PostQuote, map[int][]int, error) {
+	return map[int][]entity.PostQuote{}, map[int][]int{}, nil
+}
+
+func (f *fakePostRepo) FindRecentDuplicates(authors []string, messages []string, since time.Time) ([]int, error) {
+	duplicates := make([]int, 0)
+	for i := range authors {
+		for _, post := range f.posts {
+			if strings.EqualFold(post.Author, authors[i]) && post.Message == messages[i] {
+				duplicates = append(duplicates, i)
+			}
+		}
+	}
+	return duplicates, nil
+}
+
+type fakeReactionApp struct {
+	ReactionAppInterface
+}
+
+func (f *fakeReactionApp) FillPostsReactions(posts []entity.Post) error {
+	return nil
+}
+
+type fakeAttachmentApp struct {
+	AttachmentAppInterface
+}
+
+func (f *fakeAttachmentApp) FillPostsAttachments(posts []entity.Post) error {
+	return nil
+}
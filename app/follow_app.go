@@ -0,0 +1,140 @@
+package app
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"forum/domain/entity"
+	"forum/domain/repository"
+	"strings"
+	"time"
+)
+
+const defaultFeedLimit = 50
+
+// feedCursor points at the last item of a feed page
+type feedCursor struct {
+	Created time.Time `json:"c"`
+	Type    string    `json:"t"`
+	ID      int       `json:"i"`
+}
+
+type FollowApp struct {
+	f  repository.FollowRepository
+	us repository.UserRepository
+}
+
+func NewFollowApp(f repository.FollowRepository, us repository.UserRepository) *FollowApp {
+	return &FollowApp{f: f, us: us}
+}
+
+type FollowAppInterface interface {
+	Follow(nickname, followee string) error
+	Unfollow(nickname, followee string) error
+	GetFollowing(nickname string, limit int32, since string, desc bool) ([]entity.User, error)
+	GetFollowers(nickname string, limit int32, since string, desc bool) ([]entity.User, error)
+	GetFeed(nickname string, limit int32, since *time.Time, cursor string, desc bool) (*entity.FeedPage, error)
+}
+
+// followPair returns nicknames of both users as they are stored
+func (f *FollowApp) followPair(nickname, followee string) (string, string, error) {
+	nickname, err := f.us.CheckIfUserExists(nickname)
+	if err != nil {
+		return "", "", entity.UserDoesntExistsError
+	}
+	followee, err = f.us.CheckIfUserExists(followee)
+	if err != nil {
+		return "", "", entity.UserDoesntExistsError
+	}
+	if strings.EqualFold(nickname, followee) {
+		return "", "", entity.FollowSelfError
+	}
+	return nickname, followee, nil
+}
+
+// Follow is idempotent, following a user twice keeps the first follow
+func (f *FollowApp) Follow(nickname, followee string) error {
+	nickname, followee, err := f.followPair(nickname, followee)
+	if err != nil {
+		return err
+	}
+	return f.f.Follow(nickname, followee)
+}
+
+func (f *FollowApp) Unfollow(nickname, followee string) error {
+	nickname, followee, err := f.followPair(nickname, followee)
+	if err != nil {
+		return err
+	}
+	return f.f.Unfollow(nickname, followee)
+}
+
+func (f *FollowApp) GetFollowing(nickname string, limit int32, since string, desc bool) ([]entity.User, error) {
+	nickname, err := f.us.CheckIfUserExists(nickname)
+	if err != nil {
+		return nil, entity.UserDoesntExistsError
+	}
+	return f.f.GetFollowing(nickname, limit, since, desc)
+}
+
+func (f *FollowApp) GetFollowers(nickname string, limit int32, since string, desc bool) ([]entity.User, error) {
+	nickname, err := f.us.CheckIfUserExists(nickname)
+	if err != nil {
+		return nil, entity.UserDoesntExistsError
+	}
+	return f.f.GetFollowers(nickname, limit, since, desc)
+}
+
+// GetFeed returns a page of new threads and posts of users followed and not blocked by the user. The first page
+// starts at since when it is set, next pages continue from the cursor of the previous one
+func (f *FollowApp) GetFeed(nickname string,
+	limit int32,
+	since *time.Time,
+	cursor string,
+	desc bool) (*entity.FeedPage, error) {
+	nickname, err := f.us.CheckIfUserExists(nickname)
+	if err != nil {
+		return nil, entity.UserDoesntExistsError
+	}
+
+	query := &entity.FeedQuery{Nickname: nickname, Limit: pageLimit(limit, defaultFeedLimit), Desc: desc, After: since}
+	if cursor != "" {
+		data, err := base64.RawURLEncoding.DecodeString(cursor)
+		if err != nil {
+			return nil, entity.WrongCursorError
+		}
+		after := feedCursor{}
+		err = json.Unmarshal(data, &after)
+		if err != nil || after.Type == "" {
+			return nil, entity.WrongCursorError
+		}
+		query.After = &after.Created
+		query.AfterType = after.Type
+		query.AfterID = after.ID
+	}
+
+	// one more item is requested to know whether the next page exists
+	query.Limit++
+	items, err := f.f.GetFeed(query)
+	query.Limit--
+	if err != nil {
+		return nil, err
+	}
+
+	page := &entity.FeedPage{Items: items}
+	if int32(len(items)) > query.Limit {
+		page.Items = items[:query.Limit]
+		last := page.Items[query.Limit-1]
+		after := feedCursor{Created: time.Time(last.Created), Type: last.Type}
+		if last.Thread != nil {
+			after.ID = last.Thread.ID
+		} else {
+			after.ID = last.Post.ID
+		}
+		data, err := json.Marshal(after)
+		if err != nil {
+			return nil, err
+		}
+		page.Next = base64.RawURLEncoding.EncodeToString(data)
+	}
+	return page, nil
+}
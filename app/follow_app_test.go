@@ -0,0 +1,128 @@
+package app
+
+import (
+	"fmt"
+	"forum/domain/entity"
+	"forum/domain/repository"
+	"github.com/go-openapi/strfmt"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+)
+
+// fakeFollowRepo pages items by the (created, type, id) key the way the feed queries do
+type fakeFollowRepo struct {
+	repository.FollowRepository
+	items []entity.FeedItem
+}
+
+func feedItemID(item entity.FeedItem) int {
+	if item.Thread != nil {
+		return item.Thread.ID
+	}
+	return item.Post.ID
+}
+
+// compareFeedKeys compares (created, type, id) keys of feed items
+func compareFeedKeys(aCreated time.Time, aType string, aID int, bCreated time.Time, bType string, bID int) int {
+	switch {
+	case aCreated.Before(bCreated):
+		return -1
+	case aCreated.After(bCreated):
+		return 1
+	case aType != bType:
+		return strings.Compare(aType, bType)
+	case aID != bID:
+		if aID < bID {
+			return -1
+		}
+		return 1
+	}
+	return 0
+}
+
+func (f *fakeFollowRepo) GetFeed(query *entity.FeedQuery) ([]entity.FeedItem, error) {
+	items := append([]entity.FeedItem(nil), f.items...)
+	sort.Slice(items, func(i, j int) bool {
+		c := compareFeedKeys(time.Time(items[i].Created), items[i].Type, feedItemID(items[i]),
+			time.Time(items[j].Created), items[j].Type, feedItemID(items[j]))
+		if query.Desc {
+			return c > 0
+		}
+		return c < 0
+	})
+
+	page := make([]entity.FeedItem, 0, query.Limit)
+	for _, item := range items {
+		if query.After != nil {
+			c := compareFeedKeys(time.Time(item.Created), item.Type, feedItemID(item),
+				*query.After, query.AfterType, query.AfterID)
+			if (!query.Desc && c <= 0) || (query.Desc && c >= 0) {
+				continue
+			}
+		}
+		if int32(len(page)) == query.Limit {
+			break
+		}
+		page = append(page, item)
+	}
+	return page, nil
+}
+
+func TestFeedPagesKeepItemsWithSameCreated(t *testing.T) {
+	created := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+	at := func(offset time.Duration) strfmt.DateTime {
+		return strfmt.DateTime(created.Add(offset))
+	}
+	follows := &fakeFollowRepo{items: []entity.FeedItem{
+		{Type: entity.PostFeedItem, Created: at(-time.Second), Post: &entity.Post{ID: 1}},
+		{Type: entity.PostFeedItem, Created: at(0), Post: &entity.Post{ID: 3}},
+		{Type: entity.ThreadFeedItem, Created: at(0), Thread: &entity.Thread{ID: 2}},
+		{Type: entity.PostFeedItem, Created: at(0), Post: &entity.Post{ID: 2}},
+		{Type: entity.ThreadFeedItem, Created: at(time.Second), Thread: &entity.Thread{ID: 1}},
+	}}
+	users := &fakeUserRepo{users: map[string]*entity.User{"alice": {Nickname: "alice"}}}
+	followApp := NewFollowApp(follows, users)
+
+	for _, desc := range []bool{false, true} {
+		var seen []string
+		cursor := ""
+		for pages := 0; ; pages++ {
+			if pages > len(follows.items) {
+				t.Fatalf("desc %v: paging does not end", desc)
+			}
+			page, err := followApp.GetFeed("alice", 2, nil, cursor, desc)
+			if err != nil {
+				t.Fatalf("desc %v: %v", desc, err)
+			}
+			for _, item := range page.Items {
+				seen = append(seen, fmt.Sprintf("%v%d", item.Type, feedItemID(item)))
+			}
+			if page.Next == "" {
+				break
+			}
+			cursor = page.Next
+		}
+
+		want := "post1 post2 post3 thread2 thread1"
+		if desc {
+			want = "thread1 thread2 post3 post2 post1"
+		}
+		if got := strings.Join(seen, " "); got != want {
+			t.Errorf("desc %v: got items %q, want %q", desc, got, want)
+		}
+	}
+}
+
+func TestFeedRejectsMalformedCursor(t *testing.T) {
+	users := &fakeUserRepo{users: map[string]*entity.User{"alice": {Nickname: "alice"}}}
+	followApp := NewFollowApp(&fakeFollowRepo{}, users)
+
+	for _, cursor := range []string{"not base64!", "bm90IGpzb24", "e30"} {
+		_, err := followApp.GetFeed("alice", 10, nil, cursor, false)
+		if err != entity.WrongCursorError {
+			t.Errorf("cursor %q returned %v, want %v", cursor, err, entity.WrongCursorError)
+		}
+	}
+}
@@ -4,14 +4,16 @@ import (
 	"fmt"
 	"forum/domain/entity"
 	"forum/domain/repository"
+	"strings"
 )
 
 type ForumApp struct {
-	f repository.ForumRepository
+	f        repository.ForumRepository
+	auditApp AuditAppInterface
 }
 
-func NewForumApp(f repository.ForumRepository) *ForumApp {
-	return &ForumApp{f: f}
+func NewForumApp(f repository.ForumRepository, auditApp AuditAppInterface) *ForumApp {
+	return &ForumApp{f: f, auditApp: auditApp}
 }
 
 type ForumAppInterface interface {
@@ -19,10 +21,31 @@ type ForumAppInterface interface {
 	GetForumDetails(slug string) (*entity.Forum, error)
 	GetForumUsers(slug string, limit int32, since string, desc bool) ([]entity.User, error)
 	CheckForumCase(slug string) (string, error)
+	CheckBanned(slug string, nicknames []string) error
+	IsModerator(slug string, nickname string) (bool, error)
+	GetWordFilter(slug string) (*entity.WordFilter, error)
+	SetWordFilter(slug string, filter *entity.WordFilter) error
 }
 
 func (f *ForumApp) CreateForum(forumInput *entity.Forum) error {
-	return f.f.CreateForum(forumInput)
+	if forumInput.Slug != "" {
+		return f.f.CreateForum(forumInput)
+	}
+
+	base := Slugify(forumInput.Title, "forum")
+	for attempt := 0; attempt < slugCreateAttempts; attempt++ {
+		taken, err := f.f.GetSlugsWithPrefix(base)
+		if err != nil {
+			return err
+		}
+
+		forumInput.Slug = uniqueSlug(base, taken)
+		err = f.f.CreateForum(forumInput)
+		if err != entity.SlugExistsError {
+			return err
+		}
+	}
+	return entity.SlugExistsError
 }
 
 func (f *ForumApp) GetForumDetails(slug string) (*entity.Forum, error) {
@@ -45,3 +68,72 @@ func (f *ForumApp) GetForumUsers(slug string, limit int32, since string, desc bo
 func (f *ForumApp) CheckForumCase(slug string) (string, error) {
 	return f.f.CheckForum(slug)
 }
+
+// CheckBanned returns UserBannedError if any of nicknames is banned in the forum
+func (f *ForumApp) CheckBanned(slug string, nicknames []string) error {
+	banned, err := f.f.GetBannedUsers(slug, nicknames)
+	if err != nil {
+		return err
+	}
+	if len(banned) != 0 {
+		return entity.UserBannedError
+	}
+	return nil
+}
+
+// IsModerator reports whether user moderates the forum, forum owner is its moderator
+func (f *ForumApp) IsModerator(slug string, nickname string) (bool, error) {
+	forum, err := f.f.GetForumDetails(slug)
+	if err != nil {
+		return false, entity.ForumNotExistError
+	}
+	return nickname != "" && strings.EqualFold(forum.User, nickname), nil
+}
+
+func (f *ForumApp) GetWordFilter(slug string) (*entity.WordFilter, error) {
+	filter, err := f.f.GetWordFilter(slug)
+	if err != nil {
+		return nil, entity.ForumNotExistError
+	}
+	return filter, nil
+}
+
+// SetWordFilter replaces banned words of the forum, only forum owner is allowed to do it
+func (f *ForumApp) SetWordFilter(slug string, filter *entity.WordFilter) error {
+	forum, err := f.f.GetForumDetails(slug)
+	if err != nil {
+		return entity.ForumNotExistError
+	}
+
+	if !strings.EqualFold(forum.User, filter.User) {
+		return entity.NotForumOwnerError
+	}
+
+	if filter.Mode == "" {
+		filter.Mode = entity.RejectFilterMode
+	}
+	if filter.Mode != entity.RejectFilterMode && filter.Mode != entity.MaskFilterMode {
+		return entity.WrongFilterModeError
+	}
+
+	words := make([]string, 0, len(filter.Words))
+	for _, word := range filter.Words {
+		word = strings.ToLower(strings.TrimSpace(word))
+		if word != "" {
+			words = append(words, word)
+		}
+	}
+	filter.Words = words
+
+	previousFilter, err := f.f.GetWordFilter(forum.Slug)
+	if err != nil {
+		return err
+	}
+
+	err = f.f.SetWordFilter(forum.Slug, filter)
+	if err != nil {
+		return err
+	}
+	return f.auditApp.Record(forum.User, entity.AuditForumFilter, entity.AuditTargetForum,
+		forum.Slug, previousFilter, filter)
+}
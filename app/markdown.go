@@ -0,0 +1,227 @@
+package app
+
+import (
+	"fmt"
+	"forum/domain/entity"
+	"html"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// RenderMarkdown converts markdown message to html.
+// Raw html of the message is never passed through: the text is escaped before any markup
+// is produced, so only tags generated by the renderer itself can appear in the result.
+// Supported syntax: paragraphs, headings, block quotes, lists, fenced code, rules,
+// emphasis, strikethrough, inline code and links with http, https and mailto schemes
+func RenderMarkdown(source string) string {
+	lines := strings.Split(strings.ReplaceAll(source, "\r\n", "\n"), "\n")
+	var out strings.Builder
+	renderBlocks(lines, &out)
+	return strings.TrimRight(out.String(), "\n")
+}
+
+var (
+	headingRegexp     = regexp.MustCompile(`^(#{1,6})\s+(.*?)\s*#*\s*$`)
+	unorderedRegexp   = regexp.MustCompile(`^\s*[-*+]\s+(.*)$`)
+	orderedRegexp     = regexp.MustCompile(`^\s*\d+[.)]\s+(.*)$`)
+	ruleRegexp        = regexp.MustCompile(`^\s*(?:(?:-\s*){3,}|(?:\*\s*){3,}|(?:_\s*){3,})$`)
+	codeSpanRegexp    = regexp.MustCompile("`([^`]+)`")
+	linkRegexp        = regexp.MustCompile(`\[([^\]]+)\]\(([^)\s]+)\)`)
+	strongRegexp      = regexp.MustCompile(`\*\*([^\s*](?:.*?[^\s*])??)\*\*|__([^\s_](?:.*?[^\s_])??)__`)
+	emphasisRegexp    = regexp.MustCompile(`(^|[^\w*])(?:\*(\S(?:.*?\S)??)\*|_(\S(?:.*?\S)??)_)($|[^\w*])`)
+	strikeRegexp      = regexp.MustCompile(`~~(\S(?:.*?\S)??)~~`)
+	placeholderRegexp = regexp.MustCompile("\x00(\\d+)\x00")
+)
+
+// renderMessage validates message format, empty format is replaced with plain.
+// Returns rendered html for markdown messages and empty string for plain ones
+func renderMessage(format *string, message string) (string, error) {
+	switch *format {
+	case "":
+		*format = entity.PlainFormat
+		return "", nil
+	case entity.PlainFormat:
+		return "", nil
+	case entity.MarkdownFormat:
+		return RenderMarkdown(message), nil
+	default:
+		return "", entity.WrongFormatError
+	}
+}
+
+var allowedLinkSchemes = map[string]bool{"http": true, "https": true, "mailto": true}
+
+func renderBlocks(lines []string, out *strings.Builder) {
+	for i := 0; i < len(lines); {
+		line := lines[i]
+		trimmed := strings.TrimSpace(line)
+
+		switch {
+		case trimmed == "":
+			i++
+
+		case strings.HasPrefix(trimmed, "```"):
+			i++
+			start := i
+			for i < len(lines) && !strings.HasPrefix(strings.TrimSpace(lines[i]), "```") {
+				i++
+			}
+			out.WriteString("<pre><code>")
+			out.WriteString(html.EscapeString(strings.Join(lines[start:i], "\n")))
+			out.WriteString("</code></pre>\n")
+			i++
+
+		case headingRegexp.MatchString(trimmed):
+			match := headingRegexp.FindStringSubmatch(trimmed)
+			level := len(match[1])
+			fmt.Fprintf(out, "<h%d>%s</h%d>\n", level, renderInline(match[2]), level)
+			i++
+
+		case ruleRegexp.MatchString(line):
+			out.WriteString("<hr>\n")
+			i++
+
+		case strings.HasPrefix(trimmed, ">"):
+			quoted := make([]string, 0)
+			for i < len(lines) && strings.HasPrefix(strings.TrimSpace(lines[i]), ">") {
+				inner := strings.TrimPrefix(strings.TrimSpace(lines[i]), ">")
+				quoted = append(quoted, strings.TrimPrefix(inner, " "))
+				i++
+			}
+			out.WriteString("<blockquote>\n")
+			renderBlocks(quoted, out)
+			out.WriteString("</blockquote>\n")
+
+		case unorderedRegexp.MatchString(line):
+			i = renderList(lines, i, unorderedRegexp, "ul", out)
+
+		case orderedRegexp.MatchString(line):
+			i = renderList(lines, i, orderedRegexp, "ol", out)
+
+		default:
+			paragraph := make([]string, 0)
+			for i < len(lines) && isParagraphLine(lines[i]) {
+				paragraph = append(paragraph, renderInline(strings.TrimSpace(lines[i])))
+				i++
+			}
+			out.WriteString("<p>")
+			out.WriteString(strings.Join(paragraph, "<br>\n"))
+			out.WriteString("</p>\n")
+		}
+	}
+}
+
+// isParagraphLine reports whether line continues a paragraph instead of starting another block
+func isParagraphLine(line string) bool {
+	trimmed := strings.TrimSpace(line)
+	return trimmed != "" &&
+		!strings.HasPrefix(trimmed, "```") &&
+		!strings.HasPrefix(trimmed, ">") &&
+		!headingRegexp.MatchString(trimmed) &&
+		!ruleRegexp.MatchString(line) &&
+		!unorderedRegexp.MatchString(line) &&
+		!orderedRegexp.MatchString(line)
+}
+
+func renderList(lines []string, i int, itemRegexp *regexp.Regexp, tag string, out *strings.Builder) int {
+	out.WriteString("<" + tag + ">\n")
+	for i < len(lines) && itemRegexp.MatchString(lines[i]) {
+		item := itemRegexp.FindStringSubmatch(lines[i])[1]
+		out.WriteString("<li>" + renderInline(item) + "</li>\n")
+		i++
+	}
+	out.WriteString("</" + tag + ">\n")
+	return i
+}
+
+// renderInline escapes text and applies inline markup.
+// Every rendered element is replaced with a placeholder as soon as it is produced, so that markup
+// of the outer text can't start outside of an element and end inside of it, and tags always nest
+func renderInline(text string) string {
+	escaped := html.EscapeString(strings.ReplaceAll(text, "\x00", ""))
+	fragments := &inlineFragments{}
+
+	escaped = codeSpanRegexp.ReplaceAllStringFunc(escaped, func(match string) string {
+		return fragments.placeholder("<code>" + codeSpanRegexp.FindStringSubmatch(match)[1] + "</code>")
+	})
+
+	escaped = linkRegexp.ReplaceAllStringFunc(escaped, func(match string) string {
+		parts := linkRegexp.FindStringSubmatch(match)
+		href, ok := safeLink(parts[2])
+		if !ok {
+			return match
+		}
+		return fragments.placeholder(`<a href="` + href + `" rel="nofollow noopener">` + fragments.format(parts[1]) + "</a>")
+	})
+
+	escaped = fragments.format(escaped)
+
+	// fragments hold placeholders of the fragments nested into them
+	for placeholderRegexp.MatchString(escaped) {
+		escaped = placeholderRegexp.ReplaceAllStringFunc(escaped, func(match string) string {
+			idx, _ := strconv.Atoi(placeholderRegexp.FindStringSubmatch(match)[1])
+			return fragments.html[idx]
+		})
+	}
+	return escaped
+}
+
+// inlineFragments keeps rendered inline elements of a line behaving as single characters
+// "\x00<index>\x00" for the rest of the rendering, raw text never contains "\x00"
+type inlineFragments struct {
+	html []string
+}
+
+func (f *inlineFragments) placeholder(fragment string) string {
+	f.html = append(f.html, fragment)
+	return "\x00" + strconv.Itoa(len(f.html)-1) + "\x00"
+}
+
+// format applies strong, emphasis and strikethrough to escaped text, content of every element is formatted on its own
+func (f *inlineFragments) format(escaped string) string {
+	escaped = strongRegexp.ReplaceAllStringFunc(escaped, func(match string) string {
+		parts := strongRegexp.FindStringSubmatch(match)
+		return f.placeholder("<strong>" + f.format(parts[1]+parts[2]) + "</strong>")
+	})
+
+	// emphasis matches take the boundary characters around them, so emphasis right after
+	// another one is only found by the next pass. Every pass removes markers, so the loop ends
+	for {
+		formatted := emphasisRegexp.ReplaceAllStringFunc(escaped, func(match string) string {
+			parts := emphasisRegexp.FindStringSubmatch(match)
+			return parts[1] + f.placeholder("<em>"+f.format(parts[2]+parts[3])+"</em>") + parts[4]
+		})
+		if formatted == escaped {
+			break
+		}
+		escaped = formatted
+	}
+
+	return strikeRegexp.ReplaceAllStringFunc(escaped, func(match string) string {
+		return f.placeholder("<del>" + f.format(strikeRegexp.FindStringSubmatch(match)[1]) + "</del>")
+	})
+}
+
+// safeLink validates escaped link target and returns it ready to be put into href attribute.
+// Links with schemes other than allowed ones (javascript:, data: etc.) are rejected, so are targets
+// with character references, which could hide the scheme from this check but not from a reader
+func safeLink(escapedTarget string) (string, bool) {
+	target := html.UnescapeString(escapedTarget)
+	if html.UnescapeString(target) != target {
+		return "", false
+	}
+	parsed, err := url.Parse(target)
+	if err != nil {
+		return "", false
+	}
+
+	if parsed.Scheme != "" && !allowedLinkSchemes[strings.ToLower(parsed.Scheme)] {
+		return "", false
+	}
+	if parsed.Scheme == "" && strings.ContainsAny(strings.SplitN(target, "/", 2)[0], ":") {
+		return "", false
+	}
+	return html.EscapeString(parsed.String()), true
+}
@@ -0,0 +1,141 @@
+package app
+
+import "testing"
+
+func TestRenderMarkdown(t *testing.T) {
+	cases := []struct {
+		name   string
+		source string
+		want   string
+	}{
+		{
+			name:   "raw script",
+			source: "<script>alert(1)</script>",
+			want:   "<p>&lt;script&gt;alert(1)&lt;/script&gt;</p>",
+		},
+		{
+			name:   "raw html attributes",
+			source: `<img src=x onerror="alert(1)">`,
+			want:   "<p>&lt;img src=x onerror=&#34;alert(1)&#34;&gt;</p>",
+		},
+		{
+			name:   "quote in link target",
+			source: `[x](http://a"onmouseover=alert(1))`,
+			want:   `<p><a href="http://a&#34;onmouseover=alert(1" rel="nofollow noopener">x</a>)</p>`,
+		},
+		{
+			name:   "html in code span",
+			source: "`<b>`",
+			want:   "<p><code>&lt;b&gt;</code></p>",
+		},
+		{
+			name:   "html in fenced code",
+			source: "```\n<script>\n```",
+			want:   "<pre><code>&lt;script&gt;</code></pre>",
+		},
+		{
+			name:   "allowed link",
+			source: "[x](https://example.com/a?b=1&c=2)",
+			want:   `<p><a href="https://example.com/a?b=1&amp;c=2" rel="nofollow noopener">x</a></p>`,
+		},
+		{
+			name:   "mailto link",
+			source: "[x](mailto:a@example.com)",
+			want:   `<p><a href="mailto:a@example.com" rel="nofollow noopener">x</a></p>`,
+		},
+		{
+			name:   "javascript link",
+			source: "[x](javascript:alert(1))",
+			want:   "<p>[x](javascript:alert(1))</p>",
+		},
+		{
+			name:   "mixed case javascript link",
+			source: "[x](JaVaScRiPt:alert(1))",
+			want:   "<p>[x](JaVaScRiPt:alert(1))</p>",
+		},
+		{
+			name:   "data link",
+			source: "[x](data:text/html;base64,PHNjcmlwdD4=)",
+			want:   "<p>[x](data:text/html;base64,PHNjcmlwdD4=)</p>",
+		},
+		{
+			name:   "entity encoded scheme letter",
+			source: "[x](&#106;avascript:alert(1))",
+			want:   "<p>[x](&amp;#106;avascript:alert(1))</p>",
+		},
+		{
+			name:   "entity encoded scheme colon",
+			source: "[x](javascript&#58;alert(1))",
+			want:   "<p>[x](javascript&amp;#58;alert(1))</p>",
+		},
+		{
+			name:   "named entity colon",
+			source: "[x](javascript&colon;alert(1))",
+			want:   "<p>[x](javascript&amp;colon;alert(1))</p>",
+		},
+		{
+			name:   "placeholder injection",
+			source: "a\x000\x00b `c`",
+			want:   "<p>a0b <code>c</code></p>",
+		},
+		{
+			name:   "code span in link target",
+			source: "[x](`javascript:alert(1)`)",
+			want:   "<p>[x](<code>javascript:alert(1)</code>)</p>",
+		},
+		{
+			name:   "code span in link text",
+			source: "[`x`](http://x)",
+			want:   `<p><a href="http://x" rel="nofollow noopener"><code>x</code></a></p>`,
+		},
+		{
+			name:   "adjacent emphasis",
+			source: "*a* *b* _c_",
+			want:   "<p><em>a</em> <em>b</em> <em>c</em></p>",
+		},
+		{
+			name:   "adjacent strong and strikethrough",
+			source: "**a** **b** ~~c~~ ~~d~~",
+			want:   "<p><strong>a</strong> <strong>b</strong> <del>c</del> <del>d</del></p>",
+		},
+		{
+			name:   "emphasis inside words",
+			source: "snake_case_name a*b*c",
+			want:   "<p>snake_case_name a*b*c</p>",
+		},
+		{
+			name:   "nested emphasis",
+			source: "***a*** *b **c** d* **e *f* g**",
+			want: "<p><em><strong>a</strong></em> <em>b <strong>c</strong> d</em> " +
+				"<strong>e <em>f</em> g</strong></p>",
+		},
+		{
+			name:   "overlapping emphasis",
+			source: "*a **b* c**",
+			want:   "<p>*a <strong>b* c</strong></p>",
+		},
+		{
+			name:   "emphasis around link",
+			source: "**[a *b*](https://x)**",
+			want:   `<p><strong><a href="https://x" rel="nofollow noopener">a <em>b</em></a></strong></p>`,
+		},
+		{
+			name:   "emphasis overlapping link",
+			source: "*a [b* c](http://x)",
+			want:   `<p>*a <a href="http://x" rel="nofollow noopener">b* c</a></p>`,
+		},
+		{
+			name:   "emphasis in rejected link",
+			source: "[*a*](javascript:x)",
+			want:   "<p>[<em>a</em>](javascript:x)</p>",
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			if got := RenderMarkdown(c.source); got != c.want {
+				t.Errorf("RenderMarkdown(%q)\n got %q\nwant %q", c.source, got, c.want)
+			}
+		})
+	}
+}
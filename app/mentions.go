@@ -0,0 +1,28 @@
+package app
+
+import (
+	"regexp"
+	"strings"
+)
+
+// mentionRegexp matches @nickname not preceded by a word character, so emails are not treated as mentions
+var mentionRegexp = regexp.MustCompile(`(?:^|[^\w.@])@([\w.]+)`)
+
+// ParseMentions returns distinct nicknames mentioned in message, in order of appearance.
+// Nicknames are compared case-insensitively like in users table
+func ParseMentions(message string) []string {
+	matches := mentionRegexp.FindAllStringSubmatch(message, -1)
+	nicknames := make([]string, 0, len(matches))
+	seen := make(map[string]bool, len(matches))
+	for _, match := range matches {
+		nickname := strings.TrimRight(match[1], ".")
+		key := strings.ToLower(nickname)
+		if nickname == "" || seen[key] {
+			continue
+		}
+
+		seen[key] = true
+		nicknames = append(nicknames, nickname)
+	}
+	return nicknames
+}
@@ -0,0 +1,84 @@
+package app
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"forum/domain/entity"
+	"github.com/go-openapi/strfmt"
+	"time"
+)
+
+// maxPageLimit caps limits of cursor paged listings, which request one item more than the limit
+const maxPageLimit = 1000
+const defaultVotesLimit = 100
+
+// pageLimit returns the default for unset limits and caps the others at maxPageLimit
+func pageLimit(limit, defaultLimit int32) int32 {
+	if limit <= 0 {
+		return defaultLimit
+	}
+	if limit > maxPageLimit {
+		return maxPageLimit
+	}
+	return limit
+}
+
+// voteCursor points at the last vote of a vote listing page
+type voteCursor struct {
+	Created  time.Time `json:"c"`
+	Nickname string    `json:"n"`
+	Thread   int       `json:"t"`
+	Post     int       `json:"p"`
+}
+
+// getVotesPage returns a page of a vote listing. The first page starts at since when it is set,
+// next pages continue from the cursor of the previous one
+func getVotesPage(limit int32,
+	since *time.Time,
+	cursor string,
+	desc bool,
+	list func(query *entity.VoteQuery) ([]entity.VoteRecord, error)) (*entity.VotePage, error) {
+	query := &entity.VoteQuery{Limit: pageLimit(limit, defaultVotesLimit), Desc: desc, Since: since}
+	if cursor != "" {
+		data, err := base64.RawURLEncoding.DecodeString(cursor)
+		if err != nil {
+			return nil, entity.WrongCursorError
+		}
+		after := voteCursor{}
+		err = json.Unmarshal(data, &after)
+		if err != nil || after.Nickname == "" {
+			return nil, entity.WrongCursorError
+		}
+		query.After = &entity.VoteRecord{
+			Nickname: after.Nickname,
+			Thread:   after.Thread,
+			Post:     after.Post,
+			Created:  strfmt.DateTime(after.Created),
+		}
+	}
+
+	// one more vote is requested to know whether the next page exists
+	query.Limit++
+	votes, err := list(query)
+	query.Limit--
+	if err != nil {
+		return nil, err
+	}
+
+	page := &entity.VotePage{Items: votes}
+	if int32(len(votes)) > query.Limit {
+		page.Items = votes[:query.Limit]
+		last := page.Items[query.Limit-1]
+		data, err := json.Marshal(voteCursor{
+			Created:  time.Time(last.Created),
+			Nickname: last.Nickname,
+			Thread:   last.Thread,
+			Post:     last.Post,
+		})
+		if err != nil {
+			return nil, err
+		}
+		page.Next = base64.RawURLEncoding.EncodeToString(data)
+	}
+	return page, nil
+}
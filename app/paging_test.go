@@ -0,0 +1,147 @@
+package app
+
+import (
+	"forum/domain/entity"
+	"github.com/go-openapi/strfmt"
+	"math"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+)
+
+// voteLess orders votes by the (created, nickname, thread, post) key of vote listings
+func voteLess(a, b entity.VoteRecord) bool {
+	at, bt := time.Time(a.Created), time.Time(b.Created)
+	if !at.Equal(bt) {
+		return at.Before(bt)
+	}
+	if !strings.EqualFold(a.Nickname, b.Nickname) {
+		return strings.ToLower(a.Nickname) < strings.ToLower(b.Nickname)
+	}
+	if a.Thread != b.Thread {
+		return a.Thread < b.Thread
+	}
+	return a.Post < b.Post
+}
+
+// listVotes pages votes the way votesPageClause does in SQL
+func listVotes(votes []entity.VoteRecord) func(query *entity.VoteQuery) ([]entity.VoteRecord, error) {
+	return func(query *entity.VoteQuery) ([]entity.VoteRecord, error) {
+		sorted := append([]entity.VoteRecord(nil), votes...)
+		sort.Slice(sorted, func(i, j int) bool {
+			if query.Desc {
+				return voteLess(sorted[j], sorted[i])
+			}
+			return voteLess(sorted[i], sorted[j])
+		})
+
+		page := make([]entity.VoteRecord, 0, query.Limit)
+		for _, vote := range sorted {
+			created := time.Time(vote.Created)
+			switch {
+			case query.After != nil && !query.Desc && !voteLess(*query.After, vote):
+				continue
+			case query.After != nil && query.Desc && !voteLess(vote, *query.After):
+				continue
+			case query.After == nil && query.Since != nil && !query.Desc && created.Before(*query.Since):
+				continue
+			case query.After == nil && query.Since != nil && query.Desc && created.After(*query.Since):
+				continue
+			}
+			if int32(len(page)) == query.Limit {
+				break
+			}
+			page = append(page, vote)
+		}
+		return page, nil
+	}
+}
+
+func TestVotesPagesKeepVotesWithSameCreated(t *testing.T) {
+	created := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+	votes := []entity.VoteRecord{
+		{Nickname: "dave", Thread: 1, Created: strfmt.DateTime(created.Add(-time.Second))},
+		{Nickname: "carol", Thread: 1, Created: strfmt.DateTime(created)},
+		{Nickname: "alice", Thread: 1, Created: strfmt.DateTime(created)},
+		{Nickname: "Bob", Thread: 1, Created: strfmt.DateTime(created)},
+		{Nickname: "erin", Thread: 1, Created: strfmt.DateTime(created.Add(time.Second))},
+	}
+
+	for _, desc := range []bool{false, true} {
+		var seen []string
+		cursor := ""
+		for pages := 0; ; pages++ {
+			if pages > len(votes) {
+				t.Fatalf("desc %v: paging does not end", desc)
+			}
+			page, err := getVotesPage(2, nil, cursor, desc, listVotes(votes))
+			if err != nil {
+				t.Fatalf("desc %v: %v", desc, err)
+			}
+			for _, vote := range page.Items {
+				seen = append(seen, vote.Nickname)
+			}
+			if page.Next == "" {
+				break
+			}
+			cursor = page.Next
+		}
+
+		want := "dave alice Bob carol erin"
+		if desc {
+			want = "erin carol Bob alice dave"
+		}
+		if got := strings.Join(seen, " "); got != want {
+			t.Errorf("desc %v: got votes %q, want %q", desc, got, want)
+		}
+	}
+}
+
+func TestVotesPageSinceIsInclusive(t *testing.T) {
+	created := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+	votes := []entity.VoteRecord{
+		{Nickname: "alice", Thread: 1, Created: strfmt.DateTime(created.Add(-time.Second))},
+		{Nickname: "bob", Thread: 1, Created: strfmt.DateTime(created)},
+		{Nickname: "carol", Thread: 1, Created: strfmt.DateTime(created.Add(time.Second))},
+	}
+
+	page, err := getVotesPage(0, &created, "", false, listVotes(votes))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(page.Items) != 2 || page.Items[0].Nickname != "bob" || page.Next != "" {
+		t.Errorf("got page %+v, want bob and carol without next", page)
+	}
+
+	page, err = getVotesPage(0, &created, "", true, listVotes(votes))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(page.Items) != 2 || page.Items[0].Nickname != "bob" {
+		t.Errorf("got page %+v, want bob and alice", page)
+	}
+}
+
+func TestVotesPageRejectsMalformedCursor(t *testing.T) {
+	for _, cursor := range []string{"not base64!", "bm90IGpzb24", "e30"} {
+		_, err := getVotesPage(10, nil, cursor, false, listVotes(nil))
+		if err != entity.WrongCursorError {
+			t.Errorf("cursor %q returned %v, want %v", cursor, err, entity.WrongCursorError)
+		}
+	}
+}
+
+func TestVotesPageLimitIsCapped(t *testing.T) {
+	var limit int32
+	_, err := getVotesPage(math.MaxInt32, nil, "", false, func(query *entity.VoteQuery) ([]entity.VoteRecord, error) {
+		limit = query.Limit
+		return nil, nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if limit != maxPageLimit+1 {
+		t.Errorf("listing is asked for %d votes, want %d", limit, maxPageLimit+1)
+	}
+}
@@ -3,33 +3,210 @@ package app
 import (
 	"forum/domain/entity"
 	"forum/domain/repository"
+	"strconv"
 )
 
 type PostApp struct {
-	p repository.PostRepository
+	p             repository.PostRepository
+	reactionApp   ReactionAppInterface
+	attachmentApp AttachmentAppInterface
+	auditApp      AuditAppInterface
+	filter        ContentFilter
 }
 
-func NewPostApp(p repository.PostRepository) *PostApp {
-	return &PostApp{p: p}
+func NewPostApp(
+	p repository.PostRepository,
+	reactionApp ReactionAppInterface,
+	attachmentApp AttachmentAppInterface,
+	auditApp AuditAppInterface,
+	filter ContentFilter) *PostApp {
+	return &PostApp{
+		p:             p,
+		reactionApp:   reactionApp,
+		attachmentApp: attachmentApp,
+		auditApp:      auditApp,
+		filter:        filter,
+	}
 }
 
 type PostAppInterface interface {
 	GetPostDetails(postID int) (*entity.Post, error)
-	ChangePostMessage(post *entity.Post) (*entity.Post, error)
+	ChangePostMessage(post *entity.Post, actor string) (*entity.Post, error)
+	VoteForPost(vote *entity.Vote) (*entity.Post, error)
+	FillPostsDetails(posts []entity.Post) error
+	GetUserMentions(nickname string, limit int32, since string, desc bool) ([]entity.Post, error)
+	GetUserPosts(nickname string, limit int32, since string, desc bool) ([]entity.Post, error)
+	GetForumHeldPosts(forum string, limit int32, since string, desc bool) ([]entity.Post, error)
+	ApprovePost(postID int) (*entity.Post, error)
+	RejectPost(postID int) (*entity.Post, error)
 }
 
 func (p *PostApp) GetPostDetails(postID int) (*entity.Post, error) {
-	return p.p.GetPostDetails(postID)
+	post, err := p.p.GetPostDetails(postID)
+	if err != nil {
+		return nil, err
+	}
+
+	posts := []entity.Post{*post}
+	err = p.FillPostsDetails(posts)
+	if err != nil {
+		return nil, err
+	}
+	return &posts[0], nil
 }
 
-func (p *PostApp) ChangePostMessage(post *entity.Post) (*entity.Post, error) {
+// ChangePostMessage edits the post, actor is who is recorded in the audit log for the edit
+func (p *PostApp) ChangePostMessage(post *entity.Post, actor string) (*entity.Post, error) {
 	previousPost, err := p.GetPostDetails(post.ID)
 	if err != nil {
 		return nil, err
 	}
 
-	if post.Message == previousPost.Message {
+	if previousPost.IsDeleted {
+		return nil, entity.PostDeletedError
+	}
+
+	if post.Format == "" {
+		post.Format = previousPost.Format
+	}
+
+	if post.Message == previousPost.Message && post.Format == previousPost.Format {
 		return previousPost, nil
 	}
-	return p.p.ChangePostMessage(post)
+
+	// edited message goes through the same filters as new posts, a held edit hides the post until approval
+	if post.Message != previousPost.Message {
+		edited := []entity.Post{{
+			Author:  previousPost.Author,
+			Forum:   previousPost.Forum,
+			Thread:  previousPost.Thread,
+			Message: post.Message,
+		}}
+		err = p.filter.Filter(previousPost.Forum, edited)
+		if err != nil {
+			return nil, err
+		}
+		post.Message = edited[0].Message
+		post.IsHeld = edited[0].IsHeld
+	}
+
+	post.MessageHTML, err = renderMessage(&post.Format, post.Message)
+	if err != nil {
+		return nil, err
+	}
+
+	updated := *previousPost
+	updated.Message = post.Message
+	updated.Format = post.Format
+	updated.MessageHTML = post.MessageHTML
+	updated.IsEdited = true
+	updated.IsHeld = previousPost.IsHeld || post.IsHeld
+	audit, err := newAuditEntry(actor, entity.AuditPostEdit, entity.AuditTargetPost,
+		strconv.Itoa(post.ID), previousPost, &updated)
+	if err != nil {
+		return nil, err
+	}
+
+	post.Mentions = ParseMentions(post.Message)
+	return p.p.ChangePostMessage(post, audit)
+}
+
+func (p *PostApp) VoteForPost(vote *entity.Vote) (*entity.Post, error) {
+	if vote.Voice < -1 || vote.Voice > 1 {
+		return nil, entity.WrongVoiceError
+	}
+
+	_, err := p.GetPostDetails(vote.ID)
+	if err != nil {
+		return nil, err
+	}
+	return p.p.VoteForPost(vote)
+}
+
+func (p *PostApp) GetUserMentions(nickname string, limit int32, since string, desc bool) ([]entity.Post, error) {
+	posts, err := p.p.GetUserMentions(nickname, limit, since, desc)
+	if err != nil {
+		return nil, err
+	}
+
+	err = p.FillPostsDetails(posts)
+	if err != nil {
+		return nil, err
+	}
+	return posts, nil
+}
+
+func (p *PostApp) GetUserPosts(nickname string, limit int32, since string, desc bool) ([]entity.Post, error) {
+	posts, err := p.p.GetUserPosts(nickname, limit, since, desc)
+	if err != nil {
+		return nil, err
+	}
+
+	err = p.FillPostsDetails(posts)
+	if err != nil {
+		return nil, err
+	}
+	return posts, nil
+}
+
+// FillPostsDetails loads reactions, quotes and attachments of the posts, each kind of data is loaded for all posts at once
+func (p *PostApp) FillPostsDetails(posts []entity.Post) error {
+	if len(posts) == 0 {
+		return nil
+	}
+
+	err := p.reactionApp.FillPostsReactions(posts)
+	if err != nil {
+		return err
+	}
+
+	err = p.attachmentApp.FillPostsAttachments(posts)
+	if err != nil {
+		return err
+	}
+
+	ids := make([]int, 0, len(posts))
+	for _, post := range posts {
+		ids = append(ids, post.ID)
+	}
+
+	quotes, quotedBy, err := p.p.GetPostsQuotes(ids)
+	if err != nil {
+		return err
+	}
+
+	for i := range posts {
+		posts[i].Quotes = quotes[posts[i].ID]
+		posts[i].QuotedBy = quotedBy[posts[i].ID]
+		if posts[i].Format == entity.MarkdownFormat && posts[i].MessageHTML == "" {
+			posts[i].MessageHTML = RenderMarkdown(posts[i].Message)
+		}
+	}
+	return nil
+}
+
+func (p *PostApp) GetForumHeldPosts(forum string, limit int32, since string, desc bool) ([]entity.Post, error) {
+	posts, err := p.p.GetForumHeldPosts(forum, limit, since, desc)
+	if err != nil {
+		return nil, err
+	}
+
+	err = p.FillPostsDetails(posts)
+	if err != nil {
+		return nil, err
+	}
+	return posts, nil
+}
+
+// ApprovePost publishes held post, subscribers are notified of it only now
+func (p *PostApp) ApprovePost(postID int) (*entity.Post, error) {
+	_, err := p.p.ApprovePost(postID)
+	if err != nil {
+		return nil, err
+	}
+	return p.GetPostDetails(postID)
+}
+
+func (p *PostApp) RejectPost(postID int) (*entity.Post, error) {
+	return p.p.RejectPost(postID)
 }
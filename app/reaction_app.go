@@ -0,0 +1,67 @@
+package app
+
+import (
+	"forum/domain/entity"
+	"forum/domain/repository"
+)
+
+type ReactionApp struct {
+	r     repository.ReactionRepository
+	types map[string]bool
+}
+
+// NewReactionApp creates app accepting only given reaction types
+func NewReactionApp(r repository.ReactionRepository, types []string) *ReactionApp {
+	allowed := make(map[string]bool, len(types))
+	for _, reactionType := range types {
+		allowed[reactionType] = true
+	}
+	return &ReactionApp{r: r, types: allowed}
+}
+
+type ReactionAppInterface interface {
+	AddReaction(reaction *entity.Reaction) error
+	RemoveReaction(reaction *entity.Reaction) error
+	FillPostsReactions(posts []entity.Post) error
+	GetThreadReactions(threadID int) (map[string]int, error)
+}
+
+func (r *ReactionApp) AddReaction(reaction *entity.Reaction) error {
+	if !r.types[reaction.Type] {
+		return entity.WrongReactionError
+	}
+	return r.r.AddReaction(reaction)
+}
+
+func (r *ReactionApp) RemoveReaction(reaction *entity.Reaction) error {
+	if !r.types[reaction.Type] {
+		return entity.WrongReactionError
+	}
+	return r.r.RemoveReaction(reaction)
+}
+
+// FillPostsReactions sets reaction counters of the posts, counters of all posts are loaded at once
+func (r *ReactionApp) FillPostsReactions(posts []entity.Post) error {
+	if len(posts) == 0 {
+		return nil
+	}
+
+	ids := make([]int, 0, len(posts))
+	for _, post := range posts {
+		ids = append(ids, post.ID)
+	}
+
+	reactions, err := r.r.GetPostsReactions(ids)
+	if err != nil {
+		return err
+	}
+
+	for i := range posts {
+		posts[i].Reactions = reactions[posts[i].ID]
+	}
+	return nil
+}
+
+func (r *ReactionApp) GetThreadReactions(threadID int) (map[string]int, error) {
+	return r.r.GetThreadReactions(threadID)
+}
@@ -0,0 +1,222 @@
+package app
+
+import (
+	"forum/domain/entity"
+	"forum/domain/repository"
+	"strconv"
+	"strings"
+)
+
+type ReportApp struct {
+	r         repository.ReportRepository
+	storage   repository.BlobStorage
+	forumApp  ForumAppInterface
+	postApp   PostAppInterface
+	threadApp ThreadAppInterface
+	auditApp  AuditAppInterface
+}
+
+func NewReportApp(
+	r repository.ReportRepository,
+	storage repository.BlobStorage,
+	forumApp ForumAppInterface,
+	postApp PostAppInterface,
+	threadApp ThreadAppInterface,
+	auditApp AuditAppInterface) *ReportApp {
+	return &ReportApp{
+		r:         r,
+		storage:   storage,
+		forumApp:  forumApp,
+		postApp:   postApp,
+		threadApp: threadApp,
+		auditApp:  auditApp,
+	}
+}
+
+type ReportAppInterface interface {
+	ReportPost(postID int, report *entity.Report) error
+	ReportThread(slugOrID string, report *entity.Report) error
+	GetForumReports(slug string, moderator string, status string, limit int32, since string, desc bool) ([]entity.Report, error)
+	ResolveReport(id int, resolution *entity.ReportResolution) (*entity.Report, error)
+	GetForumHeldPosts(slug string, moderator string, limit int32, since string, desc bool) ([]entity.Post, error)
+	ApprovePost(postID int, moderation *entity.Moderation) (*entity.Post, error)
+	RejectPost(postID int, moderation *entity.Moderation) (*entity.Post, error)
+}
+
+func (r *ReportApp) ReportPost(postID int, report *entity.Report) error {
+	post, err := r.postApp.GetPostDetails(postID)
+	if err != nil {
+		return err
+	}
+	if post.IsDeleted {
+		return entity.PostDeletedError
+	}
+
+	report.Post = post.ID
+	report.Thread = 0
+	report.Forum = post.Forum
+	return r.createReport(report)
+}
+
+func (r *ReportApp) ReportThread(slugOrID string, report *entity.Report) error {
+	thread, err := r.threadApp.GetThreadForumAndID(slugOrID)
+	if err != nil {
+		return err
+	}
+
+	report.Post = 0
+	report.Thread = thread.ID
+	report.Forum = thread.Forum
+	return r.createReport(report)
+}
+
+func (r *ReportApp) createReport(report *entity.Report) error {
+	report.Reason = strings.TrimSpace(report.Reason)
+	if report.Reason == "" {
+		return entity.DataError
+	}
+	return r.r.CreateReport(report)
+}
+
+// GetForumReports is the moderation queue of the forum, reports are open unless status is given
+func (r *ReportApp) GetForumReports(slug string, moderator string, status string, limit int32, since string, desc bool) ([]entity.Report, error) {
+	err := r.checkModerator(slug, moderator)
+	if err != nil {
+		return nil, err
+	}
+
+	if status == "" {
+		status = entity.OpenReportStatus
+	}
+	reports, err := r.r.GetForumReports(slug, status, limit, since, desc)
+	if err != nil {
+		return nil, err
+	}
+
+	posts := make([]entity.Post, 0)
+	for _, report := range reports {
+		if report.ReportedPost != nil {
+			posts = append(posts, *report.ReportedPost)
+		}
+	}
+	err = r.postApp.FillPostsDetails(posts)
+	if err != nil {
+		return nil, err
+	}
+
+	idx := 0
+	for i := range reports {
+		if reports[i].ReportedPost != nil {
+			reports[i].ReportedPost = &posts[idx]
+			idx++
+		}
+	}
+	return reports, nil
+}
+
+// ResolveReport closes open report with moderator action.
+// Attachments of deleted content are removed from the storage after the transaction is committed
+func (r *ReportApp) ResolveReport(id int, resolution *entity.ReportResolution) (*entity.Report, error) {
+	switch resolution.Action {
+	case entity.DismissReportAction, entity.DeleteReportAction, entity.BanReportAction:
+	default:
+		return nil, entity.WrongReportActionError
+	}
+
+	report, err := r.r.GetReport(id)
+	if err != nil {
+		return nil, err
+	}
+
+	err = r.checkModerator(report.Forum, resolution.Moderator)
+	if err != nil {
+		return nil, err
+	}
+	if report.Status != entity.OpenReportStatus {
+		return nil, entity.ReportResolvedError
+	}
+
+	// reported content goes into the audit log snapshot since delete action removes it
+	if report.Post != 0 {
+		report.ReportedPost, err = r.postApp.GetPostDetails(report.Post)
+	} else {
+		report.ReportedThread, err = r.threadApp.GetThread(strconv.Itoa(report.Thread))
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	keys, err := r.r.ResolveReport(report, resolution)
+	if err != nil {
+		return nil, err
+	}
+	for _, key := range keys {
+		r.storage.Delete(key)
+	}
+
+	resolvedReport, err := r.r.GetReport(id)
+	if err != nil {
+		return nil, err
+	}
+
+	err = r.auditApp.Record(resolution.Moderator, entity.AuditReportResolve, entity.AuditTargetReport,
+		strconv.Itoa(report.ID), report, resolvedReport)
+	if err != nil {
+		return nil, err
+	}
+	return resolvedReport, nil
+}
+
+// GetForumHeldPosts lists posts held by content filters until a moderator reviews them
+func (r *ReportApp) GetForumHeldPosts(slug string, moderator string, limit int32, since string, desc bool) ([]entity.Post, error) {
+	err := r.checkModerator(slug, moderator)
+	if err != nil {
+		return nil, err
+	}
+	return r.postApp.GetForumHeldPosts(slug, limit, since, desc)
+}
+
+func (r *ReportApp) ApprovePost(postID int, moderation *entity.Moderation) (*entity.Post, error) {
+	return r.moderatePost(postID, moderation, entity.AuditPostApprove, r.postApp.ApprovePost)
+}
+
+func (r *ReportApp) RejectPost(postID int, moderation *entity.Moderation) (*entity.Post, error) {
+	return r.moderatePost(postID, moderation, entity.AuditPostReject, r.postApp.RejectPost)
+}
+
+func (r *ReportApp) moderatePost(
+	postID int, moderation *entity.Moderation, auditAction string,
+	decide func(int) (*entity.Post, error)) (*entity.Post, error) {
+	post, err := r.postApp.GetPostDetails(postID)
+	if err != nil {
+		return nil, err
+	}
+
+	err = r.checkModerator(post.Forum, moderation.Moderator)
+	if err != nil {
+		return nil, err
+	}
+
+	moderatedPost, err := decide(postID)
+	if err != nil {
+		return nil, err
+	}
+
+	err = r.auditApp.Record(moderation.Moderator, auditAction, entity.AuditTargetPost,
+		strconv.Itoa(postID), post, moderatedPost)
+	if err != nil {
+		return nil, err
+	}
+	return moderatedPost, nil
+}
+
+func (r *ReportApp) checkModerator(slug string, nickname string) error {
+	isModerator, err := r.forumApp.IsModerator(slug, nickname)
+	if err != nil {
+		return err
+	}
+	if !isModerator {
+		return entity.NotModeratorError
+	}
+	return nil
+}
@@ -5,23 +5,72 @@ import (
 	"forum/domain/repository"
 )
 
+const defaultStatusForumsLimit = 100
+
 type ServiceApp struct {
-	s repository.ServiceRepository
+	s        repository.ServiceRepository
+	storage  repository.BlobStorage
+	auditApp AuditAppInterface
 }
 
-func NewServiceApp(s repository.ServiceRepository) *ServiceApp {
-	return &ServiceApp{s: s}
+func NewServiceApp(s repository.ServiceRepository, storage repository.BlobStorage, auditApp AuditAppInterface) *ServiceApp {
+	return &ServiceApp{s: s, storage: storage, auditApp: auditApp}
 }
 
 type ServiceAppInterface interface {
-	ClearAllDate() error
+	ClearData(actor string, request *entity.ClearRequest) error
 	GetDBStatus() (*entity.Status, error)
+	GetExtendedStatus(forumsLimit int32) (*entity.ExtendedStatus, error)
 }
 
-func (s *ServiceApp) ClearAllDate() error {
-	return s.s.ClearAllDate()
+// ClearData removes data selected by request together with stored attachment blobs,
+// counters before clearing are kept in the audit log
+func (s *ServiceApp) ClearData(actor string, request *entity.ClearRequest) error {
+	if request.Scope == "" {
+		request.Scope = entity.ClearAllScope
+	}
+
+	status, err := s.s.GetDBStatus()
+	if err != nil {
+		return err
+	}
+
+	keys, err := s.s.ClearData(request)
+	if err != nil {
+		return err
+	}
+
+	// data is already cleared, so the clear is recorded even when blobs fail to be deleted
+	err = s.auditApp.Record(actor, entity.AuditServiceClear, entity.AuditTargetService, request.Forum, status, request)
+	if err != nil {
+		return err
+	}
+	return s.deleteBlobs(request, keys)
+}
+
+// deleteBlobs removes blobs of cleared attachments. Clearing posts of all forums empties the whole storage,
+// which also removes blobs left behind by interrupted uploads
+func (s *ServiceApp) deleteBlobs(request *entity.ClearRequest, keys []string) error {
+	if request.Forum == "" && request.Scope != entity.ClearVotesScope {
+		return s.storage.DeleteAll()
+	}
+
+	for _, key := range keys {
+		err := s.storage.Delete(key)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
 }
 
 func (s *ServiceApp) GetDBStatus() (*entity.Status, error) {
 	return s.s.GetDBStatus()
 }
+
+func (s *ServiceApp) GetExtendedStatus(forumsLimit int32) (*entity.ExtendedStatus, error) {
+	if forumsLimit <= 0 {
+		forumsLimit = defaultStatusForumsLimit
+	}
+	return s.s.GetExtendedStatus(forumsLimit)
+}
@@ -0,0 +1,85 @@
+package app
+
+import (
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+const maxSlugLength = 64
+const slugCreateAttempts = 3
+
+// cyrillicToLatin maps lowercase cyrillic letters to their latin transliteration
+var cyrillicToLatin = map[rune]string{
+	'а': "a", 'б': "b", 'в': "v", 'г': "g", 'д': "d", 'е': "e", 'ё': "yo",
+	'ж': "zh", 'з': "z", 'и': "i", 'й': "y", 'к': "k", 'л': "l", 'м': "m",
+	'н': "n", 'о': "o", 'п': "p", 'р': "r", 'с': "s", 'т': "t", 'у': "u",
+	'ф': "f", 'х': "kh", 'ц': "ts", 'ч': "ch", 'ш': "sh", 'щ': "shch", 'ъ': "",
+	'ы': "y", 'ь': "", 'э': "e", 'ю': "yu", 'я': "ya",
+	'і': "i", 'ї': "yi", 'є': "ye", 'ґ': "g",
+}
+
+// Slugify builds url-friendly slug from title: cyrillic is transliterated,
+// everything except latin letters and digits is collapsed into single dashes.
+// fallback is used when nothing is left of the title or the result is purely numeric,
+// since numeric slugs are indistinguishable from ids in urls
+func Slugify(title string, fallback string) string {
+	var builder strings.Builder
+	dash := false
+	for _, r := range strings.ToLower(title) {
+		var part string
+		if latin, ok := cyrillicToLatin[r]; ok {
+			part = latin
+		} else if r < unicode.MaxASCII && (unicode.IsLetter(r) || unicode.IsDigit(r)) {
+			part = string(r)
+		} else {
+			dash = builder.Len() > 0
+			continue
+		}
+
+		if part == "" {
+			continue
+		}
+		if dash {
+			builder.WriteByte('-')
+			dash = false
+		}
+		builder.WriteString(part)
+	}
+
+	slug := builder.String()
+	if len(slug) > maxSlugLength {
+		slug = slug[:maxSlugLength]
+		if idx := strings.LastIndexByte(slug, '-'); idx > 0 {
+			slug = slug[:idx]
+		}
+		slug = strings.Trim(slug, "-")
+	}
+
+	if slug == "" {
+		return fallback
+	}
+	if _, err := strconv.Atoi(slug); err == nil {
+		return fallback + "-" + slug
+	}
+	return slug
+}
+
+// uniqueSlug returns base if it is not taken, otherwise base with the smallest free numeric suffix
+func uniqueSlug(base string, taken []string) string {
+	takenSet := make(map[string]bool, len(taken))
+	for _, slug := range taken {
+		takenSet[strings.ToLower(slug)] = true
+	}
+
+	if !takenSet[base] {
+		return base
+	}
+
+	for i := 2; ; i++ {
+		candidate := base + "-" + strconv.Itoa(i)
+		if !takenSet[candidate] {
+			return candidate
+		}
+	}
+}
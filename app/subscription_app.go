@@ -0,0 +1,161 @@
+package app
+
+import (
+	"fmt"
+	"forum/domain/entity"
+	"forum/domain/repository"
+	"strings"
+)
+
+// digestMaxItems limits notifications listed in a digest mail, the rest are only counted
+const digestMaxItems = 50
+
+type SubscriptionApp struct {
+	s      repository.SubscriptionRepository
+	us     repository.UserRepository
+	mailer repository.Mailer
+}
+
+func NewSubscriptionApp(s repository.SubscriptionRepository,
+	us repository.UserRepository,
+	mailer repository.Mailer) *SubscriptionApp {
+	return &SubscriptionApp{s: s, us: us, mailer: mailer}
+}
+
+type SubscriptionAppInterface interface {
+	SubscribeThread(nickname string, threadID int) error
+	SubscribeForum(nickname string, slug string) error
+	UnsubscribeThread(nickname string, threadID int) error
+	UnsubscribeForum(nickname string, slug string) error
+	GetSubscriptions(nickname string) ([]entity.Subscription, error)
+	GetNotifications(nickname string, limit int32, since string, desc bool, unread bool) ([]entity.Notification, error)
+	MarkNotificationsRead(nickname string, ids []int) error
+	GetNotificationSettings(nickname string) (*entity.NotificationSettings, error)
+	SetNotificationSettings(nickname string, settings *entity.NotificationSettings) error
+	SendDigests() error
+}
+
+func (s *SubscriptionApp) SubscribeThread(nickname string, threadID int) error {
+	nickname, err := s.us.CheckIfUserExists(nickname)
+	if err != nil {
+		return entity.UserDoesntExistsError
+	}
+	return s.s.SubscribeThread(nickname, threadID)
+}
+
+func (s *SubscriptionApp) SubscribeForum(nickname string, slug string) error {
+	nickname, err := s.us.CheckIfUserExists(nickname)
+	if err != nil {
+		return entity.UserDoesntExistsError
+	}
+	return s.s.SubscribeForum(nickname, slug)
+}
+
+func (s *SubscriptionApp) UnsubscribeThread(nickname string, threadID int) error {
+	nickname, err := s.us.CheckIfUserExists(nickname)
+	if err != nil {
+		return entity.UserDoesntExistsError
+	}
+	return s.s.UnsubscribeThread(nickname, threadID)
+}
+
+func (s *SubscriptionApp) UnsubscribeForum(nickname string, slug string) error {
+	nickname, err := s.us.CheckIfUserExists(nickname)
+	if err != nil {
+		return entity.UserDoesntExistsError
+	}
+	return s.s.UnsubscribeForum(nickname, slug)
+}
+
+func (s *SubscriptionApp) GetSubscriptions(nickname string) ([]entity.Subscription, error) {
+	nickname, err := s.us.CheckIfUserExists(nickname)
+	if err != nil {
+		return nil, entity.UserDoesntExistsError
+	}
+	return s.s.GetSubscriptions(nickname)
+}
+
+func (s *SubscriptionApp) GetNotifications(nickname string,
+	limit int32,
+	since string,
+	desc bool,
+	unread bool) ([]entity.Notification, error) {
+	nickname, err := s.us.CheckIfUserExists(nickname)
+	if err != nil {
+		return nil, entity.UserDoesntExistsError
+	}
+	return s.s.GetNotifications(nickname, limit, since, desc, unread)
+}
+
+func (s *SubscriptionApp) MarkNotificationsRead(nickname string, ids []int) error {
+	nickname, err := s.us.CheckIfUserExists(nickname)
+	if err != nil {
+		return entity.UserDoesntExistsError
+	}
+	return s.s.MarkNotificationsRead(nickname, ids)
+}
+
+func (s *SubscriptionApp) GetNotificationSettings(nickname string) (*entity.NotificationSettings, error) {
+	return s.s.GetNotificationSettings(nickname)
+}
+
+func (s *SubscriptionApp) SetNotificationSettings(nickname string, settings *entity.NotificationSettings) error {
+	nickname, err := s.us.CheckIfUserExists(nickname)
+	if err != nil {
+		return entity.UserDoesntExistsError
+	}
+	return s.s.SetNotificationSettings(nickname, settings)
+}
+
+// SendDigests mails pending notifications to users who turned digests on. Notifications of a user
+// are marked as mailed only after the mail is sent, so failed digests are retried on the next run
+func (s *SubscriptionApp) SendDigests() error {
+	digests, err := s.s.GetPendingDigests()
+	if err != nil {
+		return err
+	}
+
+	var sendErr error
+	for _, digest := range digests {
+		err = s.mailer.Send(&entity.Mail{
+			To:      digest.Email,
+			Subject: fmt.Sprintf("%d new notifications", len(digest.Notifications)),
+			Body:    formatDigest(&digest),
+		})
+		if err != nil {
+			sendErr = err
+			continue
+		}
+
+		ids := make([]int, 0, len(digest.Notifications))
+		for _, notification := range digest.Notifications {
+			ids = append(ids, notification.ID)
+		}
+		err = s.s.MarkDigested(ids)
+		if err != nil {
+			return err
+		}
+	}
+	return sendErr
+}
+
+func formatDigest(digest *entity.Digest) string {
+	body := &strings.Builder{}
+	fmt.Fprintf(body, "Hello, %v!\n\nThere is new activity in threads and forums you are subscribed to:\n\n", digest.Nickname)
+	for i, notification := range digest.Notifications {
+		if i == digestMaxItems {
+			fmt.Fprintf(body, "and %d more\n", len(digest.Notifications)-digestMaxItems)
+			break
+		}
+		switch notification.Type {
+		case entity.ThreadNotification:
+			fmt.Fprintf(body, "- %v started thread #%d in %v\n",
+				notification.Author, notification.Thread, notification.Forum)
+		default:
+			fmt.Fprintf(body, "- %v posted #%d in thread #%d in %v\n",
+				notification.Author, notification.Post, notification.Thread, notification.Forum)
+		}
+	}
+	body.WriteString("\nDigests can be turned off in notification settings.\n")
+	return body.String()
+}
@@ -4,30 +4,78 @@ import (
 	"forum/domain/entity"
 	"forum/domain/repository"
 	"strconv"
+	"time"
 )
 
 type ThreadApp struct {
-	t        repository.ThreadRepository
-	forumApp ForumAppInterface
+	t           repository.ThreadRepository
+	forumApp    ForumAppInterface
+	postApp     PostAppInterface
+	reactionApp ReactionAppInterface
+	filter      ContentFilter
+	auditApp    AuditAppInterface
+	blockApp    BlockAppInterface
 }
 
-func NewThreadApp(f repository.ThreadRepository, forumApp ForumAppInterface) *ThreadApp {
-	return &ThreadApp{t: f, forumApp: forumApp}
+func NewThreadApp(
+	f repository.ThreadRepository,
+	forumApp ForumAppInterface,
+	postApp PostAppInterface,
+	reactionApp ReactionAppInterface,
+	filter ContentFilter,
+	auditApp AuditAppInterface,
+	blockApp BlockAppInterface) *ThreadApp {
+	return &ThreadApp{
+		t:           f,
+		forumApp:    forumApp,
+		postApp:     postApp,
+		reactionApp: reactionApp,
+		filter:      filter,
+		auditApp:    auditApp,
+		blockApp:    blockApp,
+	}
 }
 
 type ThreadAppInterface interface {
 	CreatePosts(thread *entity.Thread, posts []entity.Post) error
 	CreateThread(thread *entity.Thread) error
-	GetThreadPosts(slug string, limit int32, since string, sort string, desc bool) ([]entity.Post, error)
+	GetThreadPosts(slug string, limit int32, since string, sort string, desc bool, viewer string) ([]entity.Post, error)
 	CheckThread(slugOrID string) error
 	VoteForThread(vote *entity.Vote) (*entity.Thread, error)
+	GetThreadVotes(slugOrID string, limit int32, since *time.Time, cursor string, desc bool) (*entity.VotePage, error)
 	GetThread(slugOrID string) (*entity.Thread, error)
 	GetThreadForumAndID(slugOrID string) (*entity.Thread, error)
-	GetThreadsByForumSlug(slug string, limit int32, since string, desc bool) ([]entity.Thread, error)
-	UpdateThread(slugOrID string, newThreadData *entity.Thread) error
+	GetThreadsByForumSlug(slug string, limit int32, since string, desc bool, viewer string) ([]entity.Thread, error)
+	GetUserThreads(nickname string, limit int32, since string, desc bool) ([]entity.Thread, error)
+	UpdateThread(slugOrID string, newThreadData *entity.Thread, actor string) error
+	MarkThreadRead(slugOrID string, nickname string, postID int) error
+	GetFirstUnread(slugOrID string, nickname string, sort string) (*entity.FirstUnread, error)
 }
 
 func (t *ThreadApp) CreatePosts(thread *entity.Thread, posts []entity.Post) error {
+	authors := make([]string, 0, len(posts))
+	for _, post := range posts {
+		authors = append(authors, post.Author)
+	}
+	err := t.forumApp.CheckBanned(thread.Forum, authors)
+	if err != nil {
+		return err
+	}
+
+	// filters run before rendering so that masked words do not get into html
+	err = t.filter.Filter(thread.Forum, posts)
+	if err != nil {
+		return err
+	}
+
+	for i := range posts {
+		posts[i].MessageHTML, err = renderMessage(&posts[i].Format, posts[i].Message)
+		if err != nil {
+			return err
+		}
+		posts[i].Mentions = ParseMentions(posts[i].Message)
+	}
+
 	return t.t.CreatePosts(thread, posts)
 }
 
@@ -37,26 +85,79 @@ func (t *ThreadApp) CreateThread(thread *entity.Thread) error {
 	if err != nil {
 		return entity.ForumNotExistError
 	}
-	return t.t.CreateThread(thread)
+
+	err = t.forumApp.CheckBanned(thread.Forum, []string{thread.Author})
+	if err != nil {
+		return err
+	}
+
+	thread.MessageHTML, err = renderMessage(&thread.Format, thread.Message)
+	if err != nil {
+		return err
+	}
+
+	if thread.Slug != nil && *thread.Slug != "" {
+		return t.t.CreateThread(thread)
+	}
+
+	base := Slugify(thread.Title, "thread")
+	for attempt := 0; attempt < slugCreateAttempts; attempt++ {
+		taken, err := t.t.GetSlugsWithPrefix(base)
+		if err != nil {
+			return err
+		}
+
+		slug := uniqueSlug(base, taken)
+		thread.Slug = &slug
+		err = t.t.CreateThread(thread)
+		if err != entity.SlugExistsError {
+			return err
+		}
+	}
+	return entity.SlugExistsError
 }
 
-func (t *ThreadApp) GetThreadPosts(slug string, limit int32, since string, sort string, desc bool) ([]entity.Post, error) {
+// GetThreadPosts lists posts of the thread, posts of users blocked by viewer are collapsed when viewer is given
+func (t *ThreadApp) GetThreadPosts(slug string,
+	limit int32,
+	since string,
+	sort string,
+	desc bool,
+	viewer string) ([]entity.Post, error) {
 	order := "ASC"
 	switch desc {
 	case true:
 		order = "DESC"
 	}
 
+	var posts []entity.Post
+	var err error
 	switch sort {
 	case "flat":
-		return t.t.GetThreadPosts(slug, limit, since, order)
+		posts, err = t.t.GetThreadPosts(slug, limit, since, order)
 	case "tree":
-		return t.t.GetThreadPostsTree(slug, limit, since, order)
+		posts, err = t.t.GetThreadPostsTree(slug, limit, since, order)
 	case "parent_tree":
-		return t.t.GetThreadPostsParentTree(slug, limit, since, order)
+		posts, err = t.t.GetThreadPostsParentTree(slug, limit, since, order)
+	case "top":
+		posts, err = t.t.GetThreadPostsTop(slug, limit, since, order)
 	default:
-		return t.t.GetThreadPosts(slug, limit, since, order)
+		posts, err = t.t.GetThreadPosts(slug, limit, since, order)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	err = t.postApp.FillPostsDetails(posts)
+	if err != nil {
+		return nil, err
+	}
+
+	err = t.blockApp.CollapseBlockedPosts(viewer, posts)
+	if err != nil {
+		return nil, err
 	}
+	return posts, nil
 }
 
 func (t *ThreadApp) CheckThread(slugOrID string) error {
@@ -70,26 +171,117 @@ func (t *ThreadApp) CheckThread(slugOrID string) error {
 }
 
 func (t *ThreadApp) VoteForThread(vote *entity.Vote) (*entity.Thread, error) {
+	if vote.Voice < -1 || vote.Voice > 1 {
+		return nil, entity.WrongVoiceError
+	}
 	return t.t.VoteForThread(vote)
 }
 
+func (t *ThreadApp) GetThreadVotes(slugOrID string,
+	limit int32,
+	since *time.Time,
+	cursor string,
+	desc bool) (*entity.VotePage, error) {
+	thread, err := t.t.GetThreadForumAndID(slugOrID)
+	if err != nil {
+		return nil, err
+	}
+	return getVotesPage(limit, since, cursor, desc, func(query *entity.VoteQuery) ([]entity.VoteRecord, error) {
+		return t.t.GetThreadVotes(thread.ID, query)
+	})
+}
+
 func (t *ThreadApp) GetThread(slugOrID string) (*entity.Thread, error) {
+	var thread *entity.Thread
 	id, err := strconv.Atoi(slugOrID)
 	if err != nil {
-		return t.t.GetThreadBySlug(slugOrID)
+		thread, err = t.t.GetThreadBySlug(slugOrID)
+	} else {
+		thread, err = t.t.GetThreadByID(id)
 	}
-	return t.t.GetThreadByID(id)
+	if err != nil {
+		return nil, err
+	}
+
+	if thread.Format == entity.MarkdownFormat && thread.MessageHTML == "" {
+		thread.MessageHTML = RenderMarkdown(thread.Message)
+	}
+
+	thread.Reactions, err = t.reactionApp.GetThreadReactions(thread.ID)
+	if err != nil {
+		return nil, err
+	}
+	return thread, nil
 }
 
 func (t *ThreadApp) GetThreadForumAndID(slugOrID string) (*entity.Thread, error) {
 	return t.t.GetThreadForumAndID(slugOrID)
 }
 
-func (t *ThreadApp) GetThreadsByForumSlug(slug string, limit int32, since string, desc bool) ([]entity.Thread, error) {
-	return t.t.GetThreadsByForumSlug(slug, limit, since, desc)
+// GetThreadsByForumSlug lists threads of the forum, unread counts of viewer are filled when viewer is given
+func (t *ThreadApp) GetThreadsByForumSlug(slug string,
+	limit int32,
+	since string,
+	desc bool,
+	viewer string) ([]entity.Thread, error) {
+	threads, err := t.t.GetThreadsByForumSlug(slug, limit, since, desc)
+	if err != nil || viewer == "" || len(threads) == 0 {
+		return threads, err
+	}
+
+	ids := make([]int, 0, len(threads))
+	for _, thread := range threads {
+		ids = append(ids, thread.ID)
+	}
+	counts, err := t.t.GetUnreadCounts(viewer, ids)
+	if err != nil {
+		return nil, err
+	}
+	for i := range threads {
+		unread := counts[threads[i].ID]
+		threads[i].Unread = &unread
+	}
+	return threads, nil
+}
+
+func (t *ThreadApp) GetUserThreads(nickname string, limit int32, since string, desc bool) ([]entity.Thread, error) {
+	return t.t.GetUserThreads(nickname, limit, since, desc)
 }
 
-func (t *ThreadApp) UpdateThread(slugOrID string, newThreadData *entity.Thread) error {
+// UpdateThread edits the thread, actor is who is recorded in the audit log for the edit
+func (t *ThreadApp) UpdateThread(slugOrID string, newThreadData *entity.Thread, actor string) error {
+	oldThread, err := t.GetThread(slugOrID)
+	if err != nil {
+		return err
+	}
+
+	updated := *oldThread
+	if newThreadData.Title != "" {
+		updated.Title = newThreadData.Title
+	}
+	if newThreadData.Message != "" || newThreadData.Format != "" {
+		if newThreadData.Message == "" {
+			newThreadData.Message = oldThread.Message
+		}
+		if newThreadData.Format == "" {
+			newThreadData.Format = oldThread.Format
+		}
+
+		newThreadData.MessageHTML, err = renderMessage(&newThreadData.Format, newThreadData.Message)
+		if err != nil {
+			return err
+		}
+		updated.Message = newThreadData.Message
+		updated.Format = newThreadData.Format
+		updated.MessageHTML = newThreadData.MessageHTML
+	}
+
+	audit, err := newAuditEntry(actor, entity.AuditThreadEdit, entity.AuditTargetThread,
+		strconv.Itoa(oldThread.ID), oldThread, &updated)
+	if err != nil {
+		return err
+	}
+
 	newThreadData.Slug = &slugOrID
 	id, err := strconv.Atoi(slugOrID)
 	if err != nil {
@@ -97,5 +289,53 @@ func (t *ThreadApp) UpdateThread(slugOrID string, newThreadData *entity.Thread)
 	}
 
 	newThreadData.ID = id
-	return t.t.UpdateThread(newThreadData)
+	return t.t.UpdateThread(newThreadData, audit)
+}
+
+// MarkThreadRead moves the last read post of the user forward, zero postID marks all posts of the thread as read
+func (t *ThreadApp) MarkThreadRead(slugOrID string, nickname string, postID int) error {
+	thread, err := t.t.GetThreadForumAndID(slugOrID)
+	if err != nil {
+		return err
+	}
+	return t.t.MarkThreadRead(nickname, thread.ID, postID)
+}
+
+// GetFirstUnread finds the first unread post of the user, its position lets clients open the page
+// of the thread where it is listed with flat or tree sort, flat is the default. The position is zero-based
+// in ascending order and counts every post shown in the thread, so read, own and blocked posts before
+// the unread one are counted too, only held posts are not
+func (t *ThreadApp) GetFirstUnread(slugOrID string, nickname string, sort string) (*entity.FirstUnread, error) {
+	if sort == "" {
+		sort = "flat"
+	}
+	if sort != "flat" && sort != "tree" {
+		return nil, entity.WrongUnreadSortError
+	}
+
+	thread, err := t.t.GetThreadForumAndID(slugOrID)
+	if err != nil {
+		return nil, err
+	}
+
+	post, err := t.t.GetFirstUnreadPost(nickname, thread.ID)
+	if err != nil {
+		return nil, err
+	}
+	posts := []entity.Post{*post}
+	err = t.postApp.FillPostsDetails(posts)
+	if err != nil {
+		return nil, err
+	}
+
+	position, err := t.t.GetPostPosition(thread.ID, post.ID, sort)
+	if err != nil {
+		return nil, err
+	}
+	counts, err := t.t.GetUnreadCounts(nickname, []int{thread.ID})
+	if err != nil {
+		return nil, err
+	}
+
+	return &entity.FirstUnread{Post: &posts[0], Position: position, Unread: counts[thread.ID]}, nil
 }
@@ -0,0 +1,91 @@
+package app
+
+import (
+	"forum/domain/entity"
+	"forum/domain/repository"
+	"testing"
+)
+
+// fakeThreadRepo keeps one thread with its first unread post, positions are looked up by sort
+type fakeThreadRepo struct {
+	repository.ThreadRepository
+	thread    entity.Thread
+	unread    *entity.Post
+	positions map[string]int
+	sorts     []string
+}
+
+func (f *fakeThreadRepo) GetThreadForumAndID(slugOrID string) (*entity.Thread, error) {
+	thread := f.thread
+	return &thread, nil
+}
+
+func (f *fakeThreadRepo) GetFirstUnreadPost(nickname string, threadID int) (*entity.Post, error) {
+	post := *f.unread
+	return &post, nil
+}
+
+func (f *fakeThreadRepo) GetPostPosition(threadID int, postID int, sort string) (int, error) {
+	f.sorts = append(f.sorts, sort)
+	position, ok := f.positions[sort]
+	if !ok {
+		return 0, entity.WrongUnreadSortError
+	}
+	return position, nil
+}
+
+func (f *fakeThreadRepo) GetUnreadCounts(nickname string, threadIDs []int) (map[int]int, error) {
+	return map[int]int{f.thread.ID: 2}, nil
+}
+
+func newTestThreadApp(threads *fakeThreadRepo) *ThreadApp {
+	postApp := newTestPostApp(&fakePostRepo{}, nil)
+	return NewThreadApp(threads, nil, postApp, &fakeReactionApp{}, nil, &fakeAuditApp{}, nil)
+}
+
+func TestGetFirstUnreadSort(t *testing.T) {
+	cases := []struct {
+		sort     string
+		listed   string
+		position int
+	}{
+		{sort: "", listed: "flat", position: 4},
+		{sort: "flat", listed: "flat", position: 4},
+		{sort: "tree", listed: "tree", position: 1},
+	}
+
+	for _, c := range cases {
+		threads := &fakeThreadRepo{
+			thread:    entity.Thread{ID: 1, Forum: "forum"},
+			unread:    &entity.Post{ID: 5, Thread: 1, Parent: 1},
+			positions: map[string]int{"flat": 4, "tree": 1},
+		}
+		unread, err := newTestThreadApp(threads).GetFirstUnread("1", "alice", c.sort)
+		if err != nil {
+			t.Fatalf("sort %q: %v", c.sort, err)
+		}
+		if unread.Post.ID != 5 || unread.Position != c.position || unread.Unread != 2 {
+			t.Errorf("sort %q: got post %d at %d with %d unread, want post 5 at %d with 2 unread",
+				c.sort, unread.Post.ID, unread.Position, unread.Unread, c.position)
+		}
+		if len(threads.sorts) != 1 || threads.sorts[0] != c.listed {
+			t.Errorf("sort %q: position is counted for sorts %v, want %q", c.sort, threads.sorts, c.listed)
+		}
+	}
+}
+
+func TestGetFirstUnreadRejectsOtherSorts(t *testing.T) {
+	for _, sort := range []string{"parent_tree", "top", "Flat"} {
+		threads := &fakeThreadRepo{
+			thread: entity.Thread{ID: 1, Forum: "forum"},
+			unread: &entity.Post{ID: 5, Thread: 1},
+		}
+		_, err := newTestThreadApp(threads).GetFirstUnread("1", "alice", sort)
+		if err != entity.WrongUnreadSortError {
+			t.Errorf("sort %q returned %v, want %v", sort, err, entity.WrongUnreadSortError)
+		}
+		if len(threads.sorts) != 0 {
+			t.Errorf("sort %q reached the repository", sort)
+		}
+	}
+}
@@ -1,16 +1,42 @@
 package app
 
 import (
+	"encoding/base64"
+	"encoding/json"
 	"forum/domain/entity"
 	"forum/domain/repository"
+	"regexp"
+	"strings"
+	"time"
 )
 
+const defaultDirectoryLimit = 100
+
+// userCursor points at the last user of a directory page, Sort keeps cursors from being reused with another sort
+type userCursor struct {
+	Sort     string `json:"s"`
+	Key      string `json:"k"`
+	Nickname string `json:"n"`
+}
+
+var nicknameRegexp = regexp.MustCompile(`^[\w.]+$`)
+
+// deletedNicknameRegexp matches nicknames that deleted accounts get
+var deletedNicknameRegexp = regexp.MustCompile(`(?i)^deleted-\d+$`)
+
 type UserApp struct {
-	us repository.UserRepository
+	us       repository.UserRepository
+	auditApp AuditAppInterface
+	emailApp EmailAppInterface
+	// nicknameCooldown is how long an old nickname stays reserved for its owner, zero disables the reservation
+	nicknameCooldown time.Duration
 }
 
-func NewUserApp(us repository.UserRepository) *UserApp {
-	return &UserApp{us: us}
+func NewUserApp(us repository.UserRepository,
+	auditApp AuditAppInterface,
+	emailApp EmailAppInterface,
+	nicknameCooldown time.Duration) *UserApp {
+	return &UserApp{us: us, auditApp: auditApp, emailApp: emailApp, nicknameCooldown: nicknameCooldown}
 }
 
 type UserAppInterface interface {
@@ -20,10 +46,57 @@ type UserAppInterface interface {
 	UpdateUser(newUser *entity.User) (*entity.User, error)
 	GetUserNicknameWithEmail(email string) (string, error)
 	GetUsersWithNicknameAndEmail(nickname, email string) ([]entity.User, error)
+	GetUserVotes(nickname string, limit int32, since *time.Time, cursor string, desc bool) (*entity.VotePage, error)
+	GetUserStats(nickname string) (*entity.UserStats, error)
+	SearchUsers(search *entity.UserSearch, cursor string) (*entity.UsersPage, error)
+	GetUserPrivacy(nickname string) (*entity.UserPrivacy, error)
+	SetUserPrivacy(nickname string, privacy *entity.UserPrivacy) error
+	RenameUser(nickname, newNickname string) (*entity.User, error)
+	GetRenamedNickname(oldNickname string) (string, error)
+	DeleteUser(nickname string) (*entity.User, error)
+	SendEmailVerification(nickname string) error
+	ConfirmEmail(token string) (*entity.User, error)
 }
 
+// CreateUser creates user with unverified email, verification is sent separately by SendEmailVerification
 func (us *UserApp) CreateUser(user *entity.User) error {
-	return us.us.CreateUser(user)
+	if deletedNicknameRegexp.MatchString(user.Nickname) {
+		return entity.DeletedNicknameError
+	}
+	err := validateEmail(user.Email)
+	if err != nil {
+		return err
+	}
+
+	err = us.checkNicknameReserved(user.Nickname, 0)
+	if err != nil {
+		return err
+	}
+
+	// password is optional, users without it can set one through password reset
+	if user.Password != "" {
+		user.Password, err = hashPassword(user.Password)
+		if err != nil {
+			return err
+		}
+	}
+	err = us.us.CreateUser(user)
+	user.Password = ""
+	return err
+}
+
+func (us *UserApp) checkNicknameReserved(nickname string, userID int) error {
+	if us.nicknameCooldown <= 0 {
+		return nil
+	}
+	reserved, err := us.us.IsNicknameReserved(nickname, userID, time.Now().Add(-us.nicknameCooldown))
+	if err != nil {
+		return err
+	}
+	if reserved {
+		return entity.NicknameReservedError
+	}
+	return nil
 }
 
 func (us *UserApp) CheckIfUserExists(nickname string) (string, error) {
@@ -34,25 +107,61 @@ func (us *UserApp) GetUserByNickname(nickname string) (*entity.User, error) {
 	return us.us.GetUserByNickname(nickname)
 }
 
+// UpdateUser changes profile fields at once, new email is only requested
+// and replaces the current one after confirmation
 func (us *UserApp) UpdateUser(newUser *entity.User) (*entity.User, error) {
 	userFromDB, err := us.GetUserByNickname(newUser.Nickname)
 	if err != nil {
 		return nil, err
 	}
+	if userFromDB.IsDeleted {
+		return nil, entity.UserDoesntExistsError
+	}
 	newUser.ID = userFromDB.ID
+	// password is changed only through password reset
+	newUser.Password = ""
 	if newUser.Fullname == "" {
 		newUser.Fullname = userFromDB.Fullname
 	}
 
-	if newUser.Email == "" {
-		newUser.Email = userFromDB.Email
+	pendingEmail := ""
+	if newUser.Email != "" && !strings.EqualFold(newUser.Email, userFromDB.Email) {
+		err = validateEmail(newUser.Email)
+		if err != nil {
+			return nil, err
+		}
+		owner, err := us.us.GetUserNicknameWithEmail(newUser.Email)
+		if err == nil && !strings.EqualFold(owner, userFromDB.Nickname) {
+			return nil, entity.DataError
+		}
+		pendingEmail = newUser.Email
 	}
+	newUser.Email = userFromDB.Email
+	newUser.EmailVerified = userFromDB.EmailVerified
 
 	if newUser.About == "" {
 		newUser.About = userFromDB.About
 	}
 
-	return us.us.UpdateUser(newUser)
+	updatedUser, err := us.us.UpdateUser(newUser)
+	if err != nil {
+		return nil, err
+	}
+
+	err = us.auditApp.Record(userFromDB.Nickname, entity.AuditUserEdit, entity.AuditTargetUser,
+		userFromDB.Nickname, userFromDB, updatedUser)
+	if err != nil {
+		return nil, err
+	}
+
+	if pendingEmail != "" {
+		err = us.emailApp.RequestEmailChange(userFromDB, pendingEmail)
+		if err != nil {
+			return nil, err
+		}
+		updatedUser.PendingEmail = pendingEmail
+	}
+	return updatedUser, nil
 }
 
 func (us *UserApp) GetUserNicknameWithEmail(email string) (string, error) {
@@ -62,3 +171,165 @@ func (us *UserApp) GetUserNicknameWithEmail(email string) (string, error) {
 func (us *UserApp) GetUsersWithNicknameAndEmail(nickname, email string) ([]entity.User, error) {
 	return us.us.GetUsersWithNicknameAndEmail(nickname, email)
 }
+
+func (us *UserApp) GetUserVotes(nickname string,
+	limit int32,
+	since *time.Time,
+	cursor string,
+	desc bool) (*entity.VotePage, error) {
+	nickname, err := us.CheckIfUserExists(nickname)
+	if err != nil {
+		return nil, entity.UserDoesntExistsError
+	}
+	return getVotesPage(limit, since, cursor, desc, func(query *entity.VoteQuery) ([]entity.VoteRecord, error) {
+		return us.us.GetUserVotes(nickname, query)
+	})
+}
+
+func (us *UserApp) GetUserStats(nickname string) (*entity.UserStats, error) {
+	return us.us.GetUserStats(nickname)
+}
+
+// SearchUsers returns a page of the user directory, relevance is the default sort of queries
+// and is always descending
+func (us *UserApp) SearchUsers(search *entity.UserSearch, cursor string) (*entity.UsersPage, error) {
+	if search.Sort == "" {
+		search.Sort = entity.NicknameUserSort
+		if search.Query != "" {
+			search.Sort = entity.RelevanceUserSort
+		}
+	}
+	if search.Sort == entity.RelevanceUserSort {
+		if search.Query == "" {
+			return nil, entity.WrongUserSortError
+		}
+		search.Desc = true
+	}
+	search.Limit = pageLimit(search.Limit, defaultDirectoryLimit)
+
+	if cursor != "" {
+		data, err := base64.RawURLEncoding.DecodeString(cursor)
+		if err != nil {
+			return nil, entity.WrongCursorError
+		}
+		after := userCursor{}
+		err = json.Unmarshal(data, &after)
+		if err != nil || after.Sort != search.Sort || after.Nickname == "" {
+			return nil, entity.WrongCursorError
+		}
+		search.AfterKey = after.Key
+		search.AfterNickname = after.Nickname
+	}
+
+	// one more user is requested to know whether the next page exists
+	search.Limit++
+	users, keys, err := us.us.SearchUsers(search)
+	search.Limit--
+	if err != nil {
+		return nil, err
+	}
+
+	page := &entity.UsersPage{Users: users}
+	if int32(len(users)) > search.Limit {
+		page.Users = users[:search.Limit]
+		data, err := json.Marshal(userCursor{
+			Sort:     search.Sort,
+			Key:      keys[search.Limit-1],
+			Nickname: page.Users[search.Limit-1].Nickname,
+		})
+		if err != nil {
+			return nil, err
+		}
+		page.Next = base64.RawURLEncoding.EncodeToString(data)
+	}
+	return page, nil
+}
+
+func (us *UserApp) GetUserPrivacy(nickname string) (*entity.UserPrivacy, error) {
+	return us.us.GetUserPrivacy(nickname)
+}
+
+func (us *UserApp) SetUserPrivacy(nickname string, privacy *entity.UserPrivacy) error {
+	nickname, err := us.CheckIfUserExists(nickname)
+	if err != nil {
+		return entity.UserDoesntExistsError
+	}
+	previous, err := us.us.GetUserPrivacy(nickname)
+	if err != nil {
+		return err
+	}
+
+	err = us.us.SetUserPrivacy(nickname, privacy)
+	if err != nil {
+		return err
+	}
+	return us.auditApp.Record(nickname, entity.AuditUserEdit, entity.AuditTargetUser, nickname, previous, privacy)
+}
+
+// RenameUser changes nickname of the user, the user may take back own old nicknames at any time
+func (us *UserApp) RenameUser(nickname, newNickname string) (*entity.User, error) {
+	if !nicknameRegexp.MatchString(newNickname) {
+		return nil, entity.WrongNicknameError
+	}
+
+	user, err := us.us.GetUserByNickname(nickname)
+	if err != nil {
+		return nil, err
+	}
+	if user.IsDeleted {
+		return nil, entity.UserDoesntExistsError
+	}
+
+	var reservedSince time.Time
+	if us.nicknameCooldown > 0 {
+		reservedSince = time.Now().Add(-us.nicknameCooldown)
+	}
+	err = us.us.RenameUser(user.Nickname, newNickname, reservedSince)
+	if err != nil {
+		return nil, err
+	}
+
+	renamed := *user
+	renamed.Nickname = newNickname
+	err = us.auditApp.Record(user.Nickname, entity.AuditUserRename, entity.AuditTargetUser, user.Nickname,
+		entity.UserRename{Nickname: user.Nickname}, entity.UserRename{Nickname: newNickname})
+	if err != nil {
+		return nil, err
+	}
+	return &renamed, nil
+}
+
+func (us *UserApp) GetRenamedNickname(oldNickname string) (string, error) {
+	return us.us.GetRenamedNickname(oldNickname)
+}
+
+// DeleteUser anonymizes the account, threads and posts of the user stay in place under anonymized nickname
+func (us *UserApp) DeleteUser(nickname string) (*entity.User, error) {
+	user, err := us.us.GetUserByNickname(nickname)
+	if err != nil {
+		return nil, err
+	}
+	if user.IsDeleted {
+		return nil, entity.UserDoesntExistsError
+	}
+
+	deleted, err := us.us.DeleteUser(user.Nickname)
+	if err != nil {
+		return nil, err
+	}
+
+	// earlier entries of the user are scrubbed by the deletion, this one keeps only the anonymized id
+	err = us.auditApp.Record(deleted.Nickname, entity.AuditUserDelete, entity.AuditTargetUser, deleted.Nickname, nil, nil)
+	if err != nil {
+		return nil, err
+	}
+	return deleted, nil
+}
+
+func (us *UserApp) SendEmailVerification(nickname string) error {
+	return us.emailApp.SendVerification(nickname)
+}
+
+func (us *UserApp) ConfirmEmail(token string) (*entity.User, error) {
+	return us.emailApp.ConfirmEmail(token)
+}
@@ -0,0 +1,78 @@
+package app
+
+import (
+	"forum/domain/entity"
+	"math"
+	"strings"
+	"testing"
+)
+
+func TestCreateUserRejectsDeletedNicknames(t *testing.T) {
+	userApp := NewUserApp(&fakeUserRepo{}, &fakeAuditApp{}, &fakeEmailApp{}, 0)
+
+	for _, nickname := range []string{"deleted-1", "Deleted-42"} {
+		err := userApp.CreateUser(&entity.User{Nickname: nickname, Email: "someone@example.com"})
+		if err != entity.DeletedNicknameError {
+			t.Errorf("creation of %q returned %v, want %v", nickname, err, entity.DeletedNicknameError)
+		}
+	}
+}
+
+func TestDeleteUserAuditKeepsOnlyID(t *testing.T) {
+	users := &fakeUserRepo{users: map[string]*entity.User{
+		"alice": {ID: 7, Nickname: "alice", Fullname: "Alice Liddell", Email: "alice@example.com", About: "rabbits"},
+	}}
+	auditApp := &fakeAuditApp{}
+	userApp := NewUserApp(users, auditApp, &fakeEmailApp{}, 0)
+
+	deleted, err := userApp.DeleteUser("alice")
+	if err != nil {
+		t.Fatalf("deletion failed: %v", err)
+	}
+	if deleted.Nickname != "deleted-7" || deleted.Email != "" || deleted.About != "" {
+		t.Errorf("user is not anonymized: %+v", deleted)
+	}
+
+	if len(auditApp.entries) != 1 {
+		t.Fatalf("got %d audit entries, want 1", len(auditApp.entries))
+	}
+	entry := auditApp.entries[0]
+	if entry.Action != entity.AuditUserDelete || entry.Actor != "deleted-7" || entry.TargetID != "deleted-7" {
+		t.Errorf("unexpected audit entry %+v", entry)
+	}
+	if entry.Before != nil || entry.After != nil {
+		t.Errorf("audit entry keeps snapshots: before %s, after %s", entry.Before, entry.After)
+	}
+	for _, personal := range []string{"alice", "Liddell", "rabbits"} {
+		if strings.Contains(entry.Actor+entry.TargetID+string(entry.Before)+string(entry.After), personal) {
+			t.Errorf("audit entry contains %q", personal)
+		}
+	}
+}
+
+// searchLimitRepo records the limit the directory is asked for
+type searchLimitRepo struct {
+	fakeUserRepo
+	limit int32
+}
+
+func (f *searchLimitRepo) SearchUsers(search *entity.UserSearch) ([]entity.User, []string, error) {
+	f.limit = search.Limit
+	return nil, nil, nil
+}
+
+func TestSearchUsersCapsLimit(t *testing.T) {
+	users := &searchLimitRepo{}
+	userApp := NewUserApp(users, &fakeAuditApp{}, &fakeEmailApp{}, 0)
+
+	page, err := userApp.SearchUsers(&entity.UserSearch{Limit: math.MaxInt32}, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if users.limit != maxPageLimit+1 {
+		t.Errorf("directory is asked for %d users, want %d", users.limit, maxPageLimit+1)
+	}
+	if page.Next != "" {
+		t.Errorf("got next cursor %q for the last page", page.Next)
+	}
+}
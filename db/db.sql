@@ -1,22 +1,49 @@
 CREATE EXTENSION IF NOT EXISTS CITEXT;
+CREATE EXTENSION IF NOT EXISTS pg_trgm;
 DROP TABLE IF EXISTS users CASCADE;
 DROP TABLE IF EXISTS forums CASCADE;
 DROP TABLE IF EXISTS threads CASCADE;
 DROP TABLE IF EXISTS Thread_vote CASCADE;
 DROP TABLE IF EXISTS posts CASCADE;
 DROP TABLE IF EXISTS Forum_user CASCADE;
+DROP TABLE IF EXISTS Post_vote CASCADE;
+DROP TABLE IF EXISTS Reactions CASCADE;
+DROP TABLE IF EXISTS Mentions CASCADE;
+DROP TABLE IF EXISTS Post_quotes CASCADE;
+DROP TABLE IF EXISTS Attachments CASCADE;
+DROP TABLE IF EXISTS Reports CASCADE;
+DROP TABLE IF EXISTS Report_actions CASCADE;
+DROP TABLE IF EXISTS Forum_bans CASCADE;
+DROP TABLE IF EXISTS Nickname_history CASCADE;
+DROP TABLE IF EXISTS Email_tokens CASCADE;
+DROP TABLE IF EXISTS Sessions CASCADE;
+DROP TABLE IF EXISTS Follows CASCADE;
+DROP TABLE IF EXISTS Blocks CASCADE;
+DROP TABLE IF EXISTS Subscriptions CASCADE;
+DROP TABLE IF EXISTS Notifications CASCADE;
+DROP TABLE IF EXISTS Thread_reads CASCADE;
 
 CREATE UNLOGGED TABLE IF NOT EXISTS users (
     id SERIAL UNIQUE NOT NULL,
     nickname CITEXT NOT NULL PRIMARY KEY,
     email    CITEXT NOT NULL UNIQUE,
     fullname CITEXT NOT NULL,
-    about    TEXT   NOT NULL
+    about    TEXT   NOT NULL,
+    created  TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT now(),
+    hidden_from_directory BOOLEAN NOT NULL DEFAULT FALSE,
+    is_deleted BOOLEAN NOT NULL DEFAULT FALSE,
+    email_verified BOOLEAN NOT NULL DEFAULT FALSE,
+    email_digest   BOOLEAN NOT NULL DEFAULT FALSE,
+    password_hash  TEXT
 );
 
 CREATE  INDEX index_users_id ON users (id);
 CREATE INDEX index_users_nickname ON users (nickname);
 CREATE INDEX index_users_email ON users (email);
+CREATE INDEX index_users_created ON users (created);
+CREATE INDEX index_users_fullname ON users (fullname, nickname);
+CREATE INDEX index_users_nickname_trgm ON users USING GIN ((nickname::text) gin_trgm_ops);
+CREATE INDEX index_users_fullname_trgm ON users USING GIN ((fullname::text) gin_trgm_ops);
 
 
 CREATE UNLOGGED TABLE IF NOT EXISTS forums (
@@ -25,7 +52,11 @@ CREATE UNLOGGED TABLE IF NOT EXISTS forums (
     post_count   INT    NOT NULL DEFAULT 0,
     thread_count INT       NOT NULL DEFAULT 0,
     title        TEXT      NOT NULL,
-    user_nickname  CITEXT      NOT NULL
+    user_nickname  CITEXT      NOT NULL REFERENCES users(nickname) ON UPDATE CASCADE,
+    attachment_max_size BIGINT,
+    attachment_types    TEXT[],
+    banned_words        TEXT[],
+    banned_words_mode   TEXT NOT NULL DEFAULT 'reject'
 );
 
 CREATE INDEX index_forums_id_hash ON forums USING HASH (id);
@@ -35,19 +66,23 @@ CREATE INDEX index_forums_users_foreign ON forums (user_nickname);
 
 CREATE UNLOGGED TABLE IF NOT EXISTS threads (
     id         SERIAL PRIMARY KEY ,
-    author    CITEXT        NOT NULL REFERENCES users(nickname),
+    author    CITEXT        NOT NULL REFERENCES users(nickname) ON UPDATE CASCADE,
     created   TIMESTAMP WITH TIME ZONE DEFAULT now(),
     forum     CITEXT        NOT NULL REFERENCES forums(slug),
     msg       TEXT        NOT NULL,
     slug      CITEXT      UNIQUE,
     title     TEXT        NOT NULL,
     votes     INT         NOT NULL DEFAULT 0,
+    format    TEXT        NOT NULL DEFAULT 'plain',
+    msg_html  TEXT,
     FOREIGN KEY (forum) REFERENCES Forums (slug) ON DELETE CASCADE,
-    FOREIGN KEY (author) REFERENCES Users (nickname) ON DELETE CASCADE
+    FOREIGN KEY (author) REFERENCES Users (nickname) ON UPDATE CASCADE
 );
 
 CREATE INDEX index_threads_slug_hash ON threads USING HASH (slug);
 CREATE INDEX index_threads_id ON threads (id);
+CREATE INDEX index_threads_created ON threads (created);
+CREATE INDEX index_threads_author_created ON threads (author, created);
 
 
 CREATE OR REPLACE FUNCTION threads_forum_counter()
@@ -67,25 +102,35 @@ CREATE TRIGGER threads_forum_counter AFTER INSERT ON threads FOR EACH ROW EXECUT
 CREATE UNLOGGED TABLE posts (
     id SERIAL PRIMARY KEY ,
     path INTEGER[],
-    author CITEXT NOT NULL REFERENCES users(nickname),
+    author CITEXT NOT NULL REFERENCES users(nickname) ON UPDATE CASCADE,
     created TIMESTAMP WITH TIME ZONE DEFAULT now(),
     isEdited BOOLEAN DEFAULT FALSE,
+    isDeleted BOOLEAN NOT NULL DEFAULT FALSE,
+    isHeld   BOOLEAN NOT NULL DEFAULT FALSE,
     msg      TEXT  NOT NULL,
     parent   INTEGER,
     forum CITEXT NOT NULL,
-    thread INTEGER NOT NULL
+    thread INTEGER NOT NULL,
+    votes  INT NOT NULL DEFAULT 0,
+    format   TEXT NOT NULL DEFAULT 'plain',
+    msg_html TEXT
 );
 
 CREATE INDEX index_posts_id on posts (id);
 CREATE INDEX index_posts_thread_id on posts (thread, id);
 CREATE INDEX index_posts_path1_path on posts ((path[1]), path);
+CREATE INDEX index_posts_thread_votes on posts (thread, votes, id);
+CREATE INDEX index_posts_author_created on posts (author, created);
+CREATE INDEX index_posts_created on posts (created);
+CREATE INDEX index_posts_forum_held on posts (forum, id) WHERE isHeld;
+CREATE INDEX index_posts_thread_author on posts (thread, author);
 
 
 CREATE UNLOGGED TABLE Forum_user (
     forum_slug CITEXT NOT NULL,
     nickname CITEXT NOT NULL,
     UNIQUE (forum_slug, nickname),
-    FOREIGN KEY (nickname) REFERENCES Users (nickname)
+    FOREIGN KEY (nickname) REFERENCES Users (nickname) ON UPDATE CASCADE
 );
 
 
@@ -113,7 +158,7 @@ $add_forum_user_thread$ LANGUAGE plpgsql;
 
 CREATE OR REPLACE FUNCTION set_edited() RETURNS TRIGGER AS $set_edited$
 BEGIN
-    IF (NEW.msg = OLD.msg)
+    IF (NEW.msg = OLD.msg) OR NEW.isDeleted
     THEN RETURN NULL;
 END IF;
 UPDATE posts SET isEdited = TRUE
@@ -137,11 +182,15 @@ $check_edited$ LANGUAGE plpgsql;
 
 
 CREATE UNLOGGED TABLE IF NOT EXISTS Thread_vote (
-    nickname   CITEXT REFERENCES users(nickname)   NOT NULL,
+    nickname   CITEXT REFERENCES users(nickname) ON UPDATE CASCADE   NOT NULL,
     thread_id INT REFERENCES threads(id)          NOT NULL,
-    vote     INT                                 NOT NULL
+    vote     INT                                 NOT NULL,
+    created  TIMESTAMP WITH TIME ZONE DEFAULT now(),
+    UNIQUE (nickname, thread_id)
 );
 
+CREATE INDEX index_thread_vote_thread_created ON Thread_vote (thread_id, created);
+
 
 CREATE OR REPLACE FUNCTION vote_insert()
     RETURNS TRIGGER AS $vote_insert$
@@ -164,10 +213,7 @@ BEGIN
         RETURN NULL;
 END IF;
 UPDATE threads
-SET
-    votes = votes + CASE WHEN NEW.vote = -1
-                             THEN -2
-                         ELSE 2 END
+SET votes = votes - OLD.vote + NEW.vote
 WHERE id = NEW.thread_id;
 RETURN NULL;
 END;
@@ -176,12 +222,227 @@ $vote_update$ LANGUAGE  plpgsql;
 DROP TRIGGER IF EXISTS vote_update ON Thread_vote;
 CREATE TRIGGER vote_update AFTER UPDATE ON Thread_vote FOR EACH ROW EXECUTE PROCEDURE vote_update();
 
+
+CREATE OR REPLACE FUNCTION vote_delete() RETURNS TRIGGER AS $vote_delete$
+BEGIN
+UPDATE threads
+SET votes = votes - OLD.vote
+WHERE id = OLD.thread_id;
+RETURN NULL;
+END;
+$vote_delete$ LANGUAGE  plpgsql;
+
+DROP TRIGGER IF EXISTS vote_delete ON Thread_vote;
+CREATE TRIGGER vote_delete AFTER DELETE ON Thread_vote FOR EACH ROW EXECUTE PROCEDURE vote_delete();
+
+CREATE UNLOGGED TABLE IF NOT EXISTS Post_vote (
+    nickname CITEXT REFERENCES users(nickname) ON UPDATE CASCADE  NOT NULL,
+    post_id  INT REFERENCES posts(id)           NOT NULL,
+    vote     INT                                NOT NULL,
+    created  TIMESTAMP WITH TIME ZONE DEFAULT now(),
+    UNIQUE (nickname, post_id)
+);
+
+CREATE INDEX index_post_vote_nickname_created ON Post_vote (nickname, created);
+
+
+CREATE OR REPLACE FUNCTION post_vote_change()
+    RETURNS TRIGGER AS $post_vote_change$
+BEGIN
+    IF TG_OP = 'INSERT' THEN
+        UPDATE posts SET votes = votes + NEW.vote WHERE id = NEW.post_id;
+    ELSIF TG_OP = 'UPDATE' THEN
+        UPDATE posts SET votes = votes - OLD.vote + NEW.vote WHERE id = NEW.post_id;
+    ELSE
+        UPDATE posts SET votes = votes - OLD.vote WHERE id = OLD.post_id;
+    END IF;
+RETURN NULL;
+END;
+$post_vote_change$  LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS post_vote_change ON Post_vote;
+CREATE TRIGGER post_vote_change AFTER INSERT OR UPDATE OR DELETE ON Post_vote FOR EACH ROW EXECUTE PROCEDURE post_vote_change();
+
+CREATE UNLOGGED TABLE IF NOT EXISTS Reactions (
+    nickname  CITEXT NOT NULL REFERENCES users(nickname) ON UPDATE CASCADE,
+    post_id   INT REFERENCES posts(id) ON DELETE CASCADE,
+    thread_id INT REFERENCES threads(id) ON DELETE CASCADE,
+    kind      TEXT NOT NULL,
+    created   TIMESTAMP WITH TIME ZONE DEFAULT now(),
+    CHECK ((post_id IS NULL) <> (thread_id IS NULL))
+);
+
+CREATE UNIQUE INDEX index_reactions_post ON Reactions (post_id, nickname, kind) WHERE post_id IS NOT NULL;
+CREATE UNIQUE INDEX index_reactions_thread ON Reactions (thread_id, nickname, kind) WHERE thread_id IS NOT NULL;
+
+CREATE UNLOGGED TABLE IF NOT EXISTS Mentions (
+    nickname CITEXT NOT NULL REFERENCES users(nickname) ON UPDATE CASCADE,
+    post_id  INT NOT NULL REFERENCES posts(id) ON DELETE CASCADE,
+    PRIMARY KEY (nickname, post_id)
+);
+
+CREATE INDEX index_mentions_post ON Mentions (post_id);
+
+CREATE UNLOGGED TABLE IF NOT EXISTS Post_quotes (
+    post_id   INT NOT NULL REFERENCES posts(id) ON DELETE CASCADE,
+    quoted_id INT NOT NULL REFERENCES posts(id) ON DELETE CASCADE,
+    position  INT NOT NULL DEFAULT 0,
+    PRIMARY KEY (post_id, quoted_id)
+);
+
+CREATE INDEX index_post_quotes_quoted ON Post_quotes (quoted_id);
+
+CREATE UNLOGGED TABLE IF NOT EXISTS Attachments (
+    id           SERIAL PRIMARY KEY,
+    post_id      INT NOT NULL REFERENCES posts(id) ON DELETE CASCADE,
+    author       CITEXT NOT NULL REFERENCES users(nickname) ON UPDATE CASCADE,
+    filename     TEXT NOT NULL,
+    content_type TEXT NOT NULL,
+    size         BIGINT NOT NULL,
+    storage_key  TEXT NOT NULL UNIQUE,
+    created      TIMESTAMP WITH TIME ZONE DEFAULT now()
+);
+
+CREATE INDEX index_attachments_post ON Attachments (post_id);
+
+-- content ids are not foreign keys: reports stay as the audit trail after the content is deleted
+CREATE UNLOGGED TABLE IF NOT EXISTS Reports (
+    id        SERIAL PRIMARY KEY,
+    reporter  CITEXT NOT NULL REFERENCES users(nickname) ON UPDATE CASCADE,
+    forum     CITEXT NOT NULL REFERENCES forums(slug) ON DELETE CASCADE,
+    post_id   INT,
+    thread_id INT,
+    reason    TEXT NOT NULL,
+    status    TEXT NOT NULL DEFAULT 'open',
+    created   TIMESTAMP WITH TIME ZONE DEFAULT now(),
+    moderator CITEXT,
+    action    TEXT,
+    resolved  TIMESTAMP WITH TIME ZONE,
+    CHECK ((post_id IS NULL) <> (thread_id IS NULL))
+);
+
+CREATE INDEX index_reports_forum_status ON Reports (forum, status, id);
+CREATE UNIQUE INDEX index_reports_open_post ON Reports (reporter, post_id) WHERE status = 'open' AND post_id IS NOT NULL;
+CREATE UNIQUE INDEX index_reports_open_thread ON Reports (reporter, thread_id) WHERE status = 'open' AND thread_id IS NOT NULL;
+
+CREATE UNLOGGED TABLE IF NOT EXISTS Report_actions (
+    id            SERIAL PRIMARY KEY,
+    report_id     INT NOT NULL REFERENCES reports(id) ON DELETE CASCADE,
+    moderator     CITEXT NOT NULL,
+    action        TEXT NOT NULL,
+    content_author CITEXT NOT NULL,
+    comment       TEXT NOT NULL DEFAULT '',
+    created       TIMESTAMP WITH TIME ZONE DEFAULT now()
+);
+
+CREATE INDEX index_report_actions_report ON Report_actions (report_id);
+
+CREATE UNLOGGED TABLE IF NOT EXISTS Forum_bans (
+    forum_slug CITEXT NOT NULL REFERENCES forums(slug) ON DELETE CASCADE,
+    nickname   CITEXT NOT NULL REFERENCES users(nickname) ON UPDATE CASCADE,
+    moderator  CITEXT NOT NULL,
+    created    TIMESTAMP WITH TIME ZONE DEFAULT now(),
+    PRIMARY KEY (forum_slug, nickname)
+);
+
+-- old nicknames redirect to the current one and are reserved for their owner during the reuse cooldown
+CREATE UNLOGGED TABLE IF NOT EXISTS Nickname_history (
+    user_id      INT    NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    old_nickname CITEXT NOT NULL,
+    changed      TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT now()
+);
+
+CREATE INDEX index_nickname_history_old_changed ON Nickname_history (old_nickname, changed);
+
+-- tokens are stored as sha256 hex, email is the address confirmed by the token
+CREATE UNLOGGED TABLE IF NOT EXISTS Email_tokens (
+    token_hash TEXT   PRIMARY KEY,
+    user_id    INT    NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    email      CITEXT NOT NULL,
+    purpose    TEXT   NOT NULL,
+    expires    TIMESTAMP WITH TIME ZONE NOT NULL
+);
+
+CREATE INDEX index_email_tokens_user_purpose ON Email_tokens (user_id, purpose);
+
+-- sessions are looked up by sha256 hex of the cookie value
+CREATE UNLOGGED TABLE IF NOT EXISTS Sessions (
+    token_hash TEXT PRIMARY KEY,
+    user_id    INT  NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    created    TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT now(),
+    expires    TIMESTAMP WITH TIME ZONE NOT NULL
+);
+
+CREATE INDEX index_sessions_user ON Sessions (user_id);
+
+-- feeds are built from threads and posts of followees by their author columns
+CREATE UNLOGGED TABLE IF NOT EXISTS Follows (
+    follower CITEXT NOT NULL REFERENCES users(nickname) ON UPDATE CASCADE,
+    followee CITEXT NOT NULL REFERENCES users(nickname) ON UPDATE CASCADE,
+    created  TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT now(),
+    PRIMARY KEY (follower, followee),
+    CHECK (follower <> followee)
+);
+
+CREATE INDEX index_follows_followee ON Follows (followee, follower);
+
+-- posts of blocked users are collapsed for the blocker and their mentions of the blocker are not shown
+CREATE UNLOGGED TABLE IF NOT EXISTS Blocks (
+    blocker CITEXT NOT NULL REFERENCES users(nickname) ON UPDATE CASCADE,
+    blocked CITEXT NOT NULL REFERENCES users(nickname) ON UPDATE CASCADE,
+    created TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT now(),
+    PRIMARY KEY (blocker, blocked),
+    CHECK (blocker <> blocked)
+);
+
+-- a subscription is either to a thread or to a forum, authors are subscribed to threads they post in
+CREATE UNLOGGED TABLE IF NOT EXISTS Subscriptions (
+    nickname   CITEXT NOT NULL REFERENCES users(nickname) ON UPDATE CASCADE,
+    thread_id  INT    REFERENCES threads(id) ON DELETE CASCADE,
+    forum_slug CITEXT REFERENCES forums(slug) ON DELETE CASCADE,
+    created    TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT now(),
+    CHECK ((thread_id IS NULL) <> (forum_slug IS NULL))
+);
+
+CREATE UNIQUE INDEX index_subscriptions_thread ON Subscriptions (thread_id, nickname) WHERE thread_id IS NOT NULL;
+CREATE UNIQUE INDEX index_subscriptions_forum ON Subscriptions (forum_slug, nickname) WHERE forum_slug IS NOT NULL;
+CREATE INDEX index_subscriptions_nickname ON Subscriptions (nickname, created);
+
+-- digested notifications were mailed in a digest, they stay unread until the user reads them
+CREATE UNLOGGED TABLE IF NOT EXISTS Notifications (
+    id        SERIAL PRIMARY KEY,
+    nickname  CITEXT  NOT NULL REFERENCES users(nickname) ON UPDATE CASCADE,
+    kind      TEXT    NOT NULL,
+    forum     CITEXT  NOT NULL REFERENCES forums(slug) ON DELETE CASCADE,
+    thread_id INT     NOT NULL REFERENCES threads(id) ON DELETE CASCADE,
+    post_id   INT     REFERENCES posts(id) ON DELETE CASCADE,
+    author    CITEXT  NOT NULL REFERENCES users(nickname) ON UPDATE CASCADE,
+    created   TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT now(),
+    is_read   BOOLEAN NOT NULL DEFAULT FALSE,
+    digested  BOOLEAN NOT NULL DEFAULT FALSE
+);
+
+CREATE INDEX index_notifications_nickname ON Notifications (nickname, id);
+CREATE INDEX index_notifications_digest ON Notifications (nickname, id) WHERE NOT is_read AND NOT digested;
+
+-- posts with ids above last_read_post are unread, threads without a row are unread entirely
+CREATE UNLOGGED TABLE IF NOT EXISTS Thread_reads (
+    nickname       CITEXT NOT NULL REFERENCES users(nickname) ON UPDATE CASCADE,
+    thread_id      INT    NOT NULL REFERENCES threads(id) ON DELETE CASCADE,
+    last_read_post INT    NOT NULL,
+    updated        TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT now(),
+    PRIMARY KEY (nickname, thread_id)
+);
+
 CREATE OR REPLACE FUNCTION set_post_path()
     RETURNS TRIGGER AS
 $set_post_path$
 BEGIN
     new.path = (SELECT path FROM posts WHERE id = new.parent) || new.id;
-UPDATE forums SET post_count = post_count + 1 WHERE slug = new.forum;
+    -- held posts are counted when a moderator approves them
+    IF NOT new.isHeld THEN
+        UPDATE forums SET post_count = post_count + 1 WHERE slug = new.forum;
+    END IF;
 RETURN new;
 END;
 $set_post_path$ LANGUAGE plpgsql;
@@ -222,3 +483,38 @@ CREATE TRIGGER set_post_path
     ON Posts
     FOR EACH ROW
     EXECUTE PROCEDURE set_post_path();
+
+-- audit log is not dropped with other tables and is never truncated by service clear
+CREATE TABLE IF NOT EXISTS Audit_log (
+    id          BIGSERIAL PRIMARY KEY,
+    actor       TEXT NOT NULL,
+    action      TEXT NOT NULL,
+    target_type TEXT NOT NULL,
+    target_id   TEXT NOT NULL DEFAULT '',
+    before      JSONB,
+    after       JSONB,
+    created     TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS index_audit_log_target ON Audit_log (target_type, target_id, id);
+CREATE INDEX IF NOT EXISTS index_audit_log_actor ON Audit_log (actor, id);
+CREATE INDEX IF NOT EXISTS index_audit_log_action ON Audit_log (action, id);
+
+-- the only allowed change is scrubbing of personal data on user deletion, done by the transaction
+-- that set forum.audit_scrub and never touching ids, actions and times of the entries
+CREATE OR REPLACE FUNCTION audit_log_append_only()
+    RETURNS TRIGGER AS $audit_log_append_only$
+BEGIN
+    IF TG_OP = 'UPDATE' AND current_setting('forum.audit_scrub', true) = 'on'
+        AND NEW.id = OLD.id AND NEW.action = OLD.action AND NEW.target_type = OLD.target_type
+        AND NEW.created = OLD.created THEN
+        RETURN NEW;
+    END IF;
+    RAISE EXCEPTION 'audit_log is append-only';
+END;
+$audit_log_append_only$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS audit_log_append_only ON Audit_log;
+CREATE TRIGGER audit_log_append_only BEFORE UPDATE OR DELETE ON Audit_log FOR EACH ROW EXECUTE PROCEDURE audit_log_append_only();
+DROP TRIGGER IF EXISTS audit_log_no_truncate ON Audit_log;
+CREATE TRIGGER audit_log_no_truncate BEFORE TRUNCATE ON Audit_log FOR EACH STATEMENT EXECUTE PROCEDURE audit_log_append_only();
@@ -0,0 +1,27 @@
+package entity
+
+import "github.com/go-openapi/strfmt"
+
+// DefaultAttachmentMaxSize and DefaultAttachmentTypes are used when settings are empty
+const DefaultAttachmentMaxSize int64 = 5 << 20
+
+var DefaultAttachmentTypes = []string{"image/png", "image/jpeg", "image/gif", "application/pdf", "text/plain"}
+
+type Attachment struct {
+	ID          int             `json:"id"`
+	Post        int             `json:"post"`
+	Author      string          `json:"author"`
+	Filename    string          `json:"filename"`
+	ContentType string          `json:"contentType"`
+	Size        int64           `json:"size"`
+	Created     strfmt.DateTime `json:"created,omitempty"`
+	URL         string          `json:"url"`
+	StorageKey  string          `json:"-"`
+}
+
+// AttachmentLimits restricts attachments of forum posts, User is the forum owner changing the limits
+type AttachmentLimits struct {
+	User    string   `json:"-"`
+	MaxSize int64    `json:"maxSize"`
+	Types   []string `json:"types"`
+}
@@ -0,0 +1,50 @@
+package entity
+
+import (
+	"encoding/json"
+	"github.com/go-openapi/strfmt"
+)
+
+const AuditServiceClear = "service.clear"
+const AuditPostEdit = "post.edit"
+const AuditThreadEdit = "thread.edit"
+const AuditUserEdit = "user.edit"
+const AuditUserRename = "user.rename"
+const AuditUserDelete = "user.delete"
+const AuditUserExport = "user.export"
+const AuditUserPasswordReset = "user.passwordReset"
+const AuditReportResolve = "report.resolve"
+const AuditPostApprove = "post.approve"
+const AuditPostReject = "post.reject"
+const AuditForumFilter = "forum.filter"
+const AuditForumAttachmentLimits = "forum.attachmentLimits"
+
+const AuditTargetService = "service"
+const AuditTargetPost = "post"
+const AuditTargetThread = "thread"
+const AuditTargetUser = "user"
+const AuditTargetReport = "report"
+const AuditTargetForum = "forum"
+
+// AuditEntry records who did what to which object, Before and After are json snapshots of the object
+type AuditEntry struct {
+	ID         int64           `json:"id"`
+	Actor      string          `json:"actor"`
+	Action     string          `json:"action"`
+	TargetType string          `json:"targetType"`
+	TargetID   string          `json:"targetId,omitempty"`
+	Before     json.RawMessage `json:"before,omitempty"`
+	After      json.RawMessage `json:"after,omitempty"`
+	Created    strfmt.DateTime `json:"created"`
+}
+
+// AuditFilter selects audit entries, empty fields are not filtered by. Since is an entry id
+type AuditFilter struct {
+	Actor      string
+	Action     string
+	TargetType string
+	TargetID   string
+	Since      string
+	Limit      int32
+	Desc       bool
+}
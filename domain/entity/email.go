@@ -0,0 +1,25 @@
+package entity
+
+import "time"
+
+const VerifyEmailPurpose = "verify"
+const ChangeEmailPurpose = "change"
+const ResetPasswordPurpose = "reset"
+
+type Mail struct {
+	To      string
+	Subject string
+	Body    string
+}
+
+// EmailToken confirms that the user owns Email, the token itself is stored only as a hash
+type EmailToken struct {
+	UserID  int
+	Email   string
+	Purpose string
+	Expires time.Time
+}
+
+type EmailConfirmation struct {
+	Token string `json:"token"`
+}
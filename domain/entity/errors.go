@@ -6,6 +6,47 @@ const ForumNotExistError customError = "Forum not exists"
 const DataError customError = "Data error"
 const WrongParentError customError = "Wrong parent passed"
 const UserDoesntExistsError customError = "User does not exist"
+const SlugExistsError customError = "Slug already exists"
+const WrongVoiceError customError = "Voice must be -1, 0 or 1"
+const WrongReactionError customError = "Unknown reaction"
+const QuotedPostNotFoundError customError = "Quoted post not found"
+const WrongFormatError customError = "Message format must be plain or markdown"
+const NotPostAuthorError customError = "Only post author can do it"
+const NotForumOwnerError customError = "Only forum owner can do it"
+const AttachmentTypeError customError = "Attachment type is not allowed in this forum"
+const AttachmentTooLargeError customError = "Attachment is too large for this forum"
+const NotModeratorError customError = "Only forum moderator can do it"
+const UserBannedError customError = "User is banned in this forum"
+const ReportExistsError customError = "Report is already sent"
+const ReportNotFoundError customError = "Report not found"
+const ReportResolvedError customError = "Report is already resolved"
+const WrongReportActionError customError = "Action must be dismiss, delete or ban"
+const PostDeletedError customError = "Post is deleted"
+const BannedWordsError customError = "Message contains banned words"
+const DuplicatePostError customError = "Same message was posted recently"
+const WrongFilterModeError customError = "Filter mode must be reject or mask"
+const PostNotHeldError customError = "Post is not held for review"
+const WrongClearScopeError customError = "Clear scope must be all, posts or votes"
+const WrongUserSortError customError = "Sort must be nickname, fullname, created or relevance, relevance needs a query"
+const WrongCursorError customError = "Cursor is malformed or belongs to another sort"
+const WrongNicknameError customError = "Nickname may contain only letters, digits, underscores and dots"
+const NicknameExistsError customError = "Nickname is already taken"
+const NicknameReservedError customError = "Nickname was recently used by another user"
+const DeletedNicknameError customError = "Nicknames like deleted-<number> are reserved for deleted accounts"
+const WrongEmailError customError = "Email address is malformed"
+const EmailTokenError customError = "Token is invalid or expired"
+const EmailVerifiedError customError = "Email is already verified"
+const EmailTakenError customError = "Email is already registered by another user"
+const WeakPasswordError customError = "Password must be at least 8 characters long"
+const WrongCredentialsError customError = "Wrong nickname or password"
+const SessionNotFoundError customError = "Session not found or expired"
+const FollowSelfError customError = "User can not follow themselves"
+const BlockSelfError customError = "User can not block themselves"
+const PostNotInThreadError customError = "Post does not belong to the thread"
+const NoUnreadPostsError customError = "There are no unread posts in the thread"
+const WrongUnreadSortError customError = "Sort must be flat or tree"
+const NoSessionError customError = "Sign in is required"
+const NotSelfError customError = "Only the user themselves can do this"
 
 func (err customError) Error() string { // customError implements error interface
 	return string(err)
@@ -0,0 +1,18 @@
+package entity
+
+const RejectFilterMode = "reject"
+const MaskFilterMode = "mask"
+
+// WordFilter is the list of words banned in a forum.
+// Posts with banned words are rejected or have the words masked depending on Mode,
+// User is the forum owner changing the filter, it is taken from the session
+type WordFilter struct {
+	User  string   `json:"-"`
+	Words []string `json:"words"`
+	Mode  string   `json:"mode"`
+}
+
+// Moderation identifies moderator approving or rejecting a held post, the moderator is the session user
+type Moderation struct {
+	Moderator string `json:"-"`
+}
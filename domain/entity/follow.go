@@ -0,0 +1,33 @@
+package entity
+
+import (
+	"github.com/go-openapi/strfmt"
+	"time"
+)
+
+const ThreadFeedItem = "thread"
+const PostFeedItem = "post"
+
+// FeedItem is a new thread or post of a followed user, only the field of its Type is set
+type FeedItem struct {
+	Type    string          `json:"type"`
+	Created strfmt.DateTime `json:"created"`
+	Thread  *Thread         `json:"thread,omitempty"`
+	Post    *Post           `json:"post,omitempty"`
+}
+
+// FeedQuery selects a page of the feed of Nickname. Items are ordered by creation time, type and id,
+// the page starts after the item with AfterType and AfterID created at After
+type FeedQuery struct {
+	Nickname  string
+	Limit     int32
+	Desc      bool
+	After     *time.Time
+	AfterType string
+	AfterID   int
+}
+
+type FeedPage struct {
+	Items []FeedItem `json:"items"`
+	Next  string     `json:"next,omitempty"`
+}
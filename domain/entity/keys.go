@@ -7,6 +7,8 @@ const CookieNameKey = "session_id"
 
 const IDKey key = "id"
 const NicknameKey key = "nickname"
+const FolloweeKey key = "followee"
+const BlockedKey key = "blocked"
 const SlugKey key = "slug"
 const LimitKey key = "limit"
 const SortKey key = "sort"
@@ -14,6 +16,11 @@ const SlugOrIDKey key = "slug_or_id"
 const RelatedKey key = "related"
 const SinceKey key = "since"
 const DescKey key = "desc"
+const StatusKey key = "status"
+const StatsKey key = "stats"
+const QueryKey key = "q"
+const CursorKey key = "cursor"
+const UnreadKey key = "unread"
 
 const AvatarDefaultPath string = "assets/img/default-avatar.jpg"
 
@@ -2,15 +2,40 @@ package entity
 
 import "github.com/go-openapi/strfmt"
 
+const PlainFormat = "plain"
+const MarkdownFormat = "markdown"
+
 type Post struct {
-	ID       int             `json:"id"`
-	Author   string          `json:"author"`
-	Message  string          `json:"message"`
-	Parent   int             `json:"parent,omitempty"`
-	Forum    string          `json:"forum"`
-	Thread   int             `json:"thread"`
-	Created  strfmt.DateTime `json:"created,omitempty"`
-	IsEdited bool            `json:"isEdited"`
+	ID          int             `json:"id"`
+	Author      string          `json:"author"`
+	Message     string          `json:"message"`
+	Format      string          `json:"format,omitempty"`
+	MessageHTML string          `json:"messageHtml,omitempty"`
+	Parent      int             `json:"parent,omitempty"`
+	Forum       string          `json:"forum"`
+	Thread      int             `json:"thread"`
+	Created     strfmt.DateTime `json:"created,omitempty"`
+	IsEdited    bool            `json:"isEdited"`
+	IsDeleted   bool            `json:"isDeleted,omitempty"`
+	IsHeld      bool            `json:"isHeld,omitempty"`
+	// IsBlocked marks posts of users blocked by the viewer, they keep their place in the thread without content
+	IsBlocked   bool           `json:"isBlocked,omitempty"`
+	Votes       int            `json:"votes"`
+	Reactions   map[string]int `json:"reactions,omitempty"`
+	Quotes      []PostQuote    `json:"quotes,omitempty"`
+	QuotedBy    []int          `json:"quotedBy,omitempty"`
+	Attachments []Attachment   `json:"attachments,omitempty"`
+	// Mentions are nicknames parsed from the message, they are stored along with the post
+	Mentions []string `json:"-"`
+}
+
+// PostQuote references quoted post, only ID is read from client input. Author is kept even when the viewer
+// blocked the quoted user, blocking hides what they wrote, not who wrote it
+type PostQuote struct {
+	ID     int    `json:"id"`
+	Author string `json:"author,omitempty"`
+	Thread int    `json:"thread,omitempty"`
+	Forum  string `json:"forum,omitempty"`
 }
 
 type PostOutput struct {
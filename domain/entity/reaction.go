@@ -0,0 +1,12 @@
+package entity
+
+// Reaction is a typed reaction of a user, either Post or Thread is set
+type Reaction struct {
+	Nickname string `json:"nickname"`
+	Type     string `json:"reaction"`
+	Post     int    `json:"-"`
+	Thread   int    `json:"-"`
+}
+
+// DefaultReactions is used when REACTIONS setting is empty
+var DefaultReactions = []string{"like", "laugh", "thanks", "wow", "sad"}
@@ -0,0 +1,35 @@
+package entity
+
+import "github.com/go-openapi/strfmt"
+
+const OpenReportStatus = "open"
+const ResolvedReportStatus = "resolved"
+
+const DismissReportAction = "dismiss"
+const DeleteReportAction = "delete"
+const BanReportAction = "ban"
+
+// Report is a complaint about a post or a thread, exactly one of Post and Thread is set.
+// ReportedPost and ReportedThread hold the reported content in the moderation queue
+type Report struct {
+	ID             int              `json:"id"`
+	Reporter       string           `json:"reporter"`
+	Reason         string           `json:"reason"`
+	Forum          string           `json:"forum"`
+	Post           int              `json:"post,omitempty"`
+	Thread         int              `json:"thread,omitempty"`
+	Status         string           `json:"status"`
+	Created        strfmt.DateTime  `json:"created"`
+	Moderator      string           `json:"moderator,omitempty"`
+	Action         string           `json:"action,omitempty"`
+	Resolved       *strfmt.DateTime `json:"resolved,omitempty"`
+	ReportedPost   *Post            `json:"reportedPost,omitempty"`
+	ReportedThread *Thread          `json:"reportedThread,omitempty"`
+}
+
+// ReportResolution is a moderator decision closing the report, the moderator is the session user
+type ReportResolution struct {
+	Moderator string `json:"-"`
+	Action    string `json:"action"`
+	Comment   string `json:"comment,omitempty"`
+}
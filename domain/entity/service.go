@@ -6,3 +6,53 @@ type Status struct {
 	Thread int `json:"thread"`
 	Post   int `json:"post"`
 }
+
+// ForumStatus holds maintained counters of a forum, Recent is number of posts in the last day
+type ForumStatus struct {
+	Slug    string `json:"slug"`
+	Title   string `json:"title"`
+	Threads int    `json:"threads"`
+	Posts   int    `json:"posts"`
+	Recent  int    `json:"recent,omitempty"`
+}
+
+// WindowStatus holds numbers of objects created within the window
+type WindowStatus struct {
+	Window string `json:"window"`
+	User   int    `json:"user"`
+	Thread int    `json:"thread"`
+	Post   int    `json:"post"`
+}
+
+type PoolStatus struct {
+	TotalConns           int32  `json:"totalConns"`
+	IdleConns            int32  `json:"idleConns"`
+	AcquiredConns        int32  `json:"acquiredConns"`
+	ConstructingConns    int32  `json:"constructingConns"`
+	MaxConns             int32  `json:"maxConns"`
+	AcquireCount         int64  `json:"acquireCount"`
+	EmptyAcquireCount    int64  `json:"emptyAcquireCount"`
+	CanceledAcquireCount int64  `json:"canceledAcquireCount"`
+	AcquireDuration      string `json:"acquireDuration"`
+}
+
+// ExtendedStatus is the service status with per-forum and time-window statistics.
+// Totals are read from forum counters instead of counting rows
+type ExtendedStatus struct {
+	Status
+	Forums    []ForumStatus  `json:"forums"`
+	TopForums []ForumStatus  `json:"topForums"`
+	Windows   []WindowStatus `json:"windows"`
+	Pool      PoolStatus     `json:"pool"`
+}
+
+const ClearAllScope = "all"
+const ClearPostsScope = "posts"
+const ClearVotesScope = "votes"
+
+// ClearRequest selects data removed by service clear.
+// Empty Forum means all forums, empty Scope means all data
+type ClearRequest struct {
+	Forum string `json:"forum,omitempty"`
+	Scope string `json:"scope,omitempty"`
+}
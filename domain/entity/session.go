@@ -0,0 +1,35 @@
+package entity
+
+import (
+	"context"
+	"time"
+)
+
+// Session is stored in request context under CookieInfoKey for requests with a valid session cookie
+type Session struct {
+	UserID   int       `json:"-"`
+	Nickname string    `json:"nickname"`
+	Expires  time.Time `json:"expires"`
+}
+
+// ViewerNickname returns nickname of the session user of the request context, empty for anonymous requests
+func ViewerNickname(ctx context.Context) string {
+	if session, ok := ctx.Value(CookieInfoKey).(*Session); ok {
+		return session.Nickname
+	}
+	return ""
+}
+
+type Credentials struct {
+	Nickname string `json:"nickname"`
+	Password string `json:"password"`
+}
+
+type PasswordResetRequest struct {
+	Email string `json:"email"`
+}
+
+type PasswordReset struct {
+	Token    string `json:"token"`
+	Password string `json:"password"`
+}
@@ -0,0 +1,41 @@
+package entity
+
+import "github.com/go-openapi/strfmt"
+
+// Subscription is to a thread or to a whole forum, only one of Thread and Forum is set
+type Subscription struct {
+	Thread  int             `json:"thread,omitempty"`
+	Forum   string          `json:"forum,omitempty"`
+	Created strfmt.DateTime `json:"created"`
+}
+
+const PostNotification = "post"
+const ThreadNotification = "thread"
+
+// Notification tells about a new post in a subscribed thread or forum or about a new thread in a subscribed forum
+type Notification struct {
+	ID      int             `json:"id"`
+	Type    string          `json:"type"`
+	Forum   string          `json:"forum"`
+	Thread  int             `json:"thread"`
+	Post    int             `json:"post,omitempty"`
+	Author  string          `json:"author"`
+	Created strfmt.DateTime `json:"created"`
+	IsRead  bool            `json:"isRead"`
+}
+
+type NotificationsRead struct {
+	// IDs of notifications to mark as read, all notifications of the user are marked when empty
+	IDs []int `json:"ids"`
+}
+
+type NotificationSettings struct {
+	EmailDigest bool `json:"emailDigest"`
+}
+
+// Digest is unread notifications of a user that were not mailed yet
+type Digest struct {
+	Nickname      string
+	Email         string
+	Notifications []Notification
+}
@@ -3,12 +3,30 @@ package entity
 import "github.com/go-openapi/strfmt"
 
 type Thread struct {
-	ID      int             `json:"id"`
-	Forum   string          `json:"forum"`
-	Title   string          `json:"title"`
-	Author  string          `json:"author"`
-	Message string          `json:"message"`
-	Slug    *string         `json:"slug,omitempty"`
-	Created strfmt.DateTime `json:"created,omitempty"`
-	Votes   int             `json:"votes"`
+	ID          int             `json:"id"`
+	Forum       string          `json:"forum"`
+	Title       string          `json:"title"`
+	Author      string          `json:"author"`
+	Message     string          `json:"message"`
+	Format      string          `json:"format,omitempty"`
+	MessageHTML string          `json:"messageHtml,omitempty"`
+	Slug        *string         `json:"slug,omitempty"`
+	Created     strfmt.DateTime `json:"created,omitempty"`
+	Votes       int             `json:"votes"`
+	Reactions   map[string]int  `json:"reactions,omitempty"`
+	// Unread is count of unread posts, it is set when the viewer is known
+	Unread *int `json:"unread,omitempty"`
+}
+
+type ThreadRead struct {
+	// Post is the last read post, zero marks the whole thread as read
+	Post int `json:"post"`
+}
+
+// FirstUnread is the first unread post and its zero-based position among all shown posts of the thread
+// in ascending order of the requested sort
+type FirstUnread struct {
+	Post     *Post `json:"post"`
+	Position int   `json:"position"`
+	Unread   int   `json:"unread"`
 }
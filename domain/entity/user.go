@@ -1,9 +1,60 @@
 package entity
 
+import "github.com/go-openapi/strfmt"
+
 type User struct {
-	ID       int    `json:"-"`
+	ID        int        `json:"-"`
+	Nickname  string     `json:"nickname"`
+	Fullname  string     `json:"fullname,omitempty"`
+	Email     string     `json:"email,omitempty"`
+	About     string     `json:"about,omitempty"`
+	IsDeleted bool       `json:"isDeleted,omitempty"`
+	Stats     *UserStats `json:"stats,omitempty"`
+	// PendingEmail is set after email change request until the new address is confirmed
+	EmailVerified bool   `json:"emailVerified,omitempty"`
+	PendingEmail  string `json:"pendingEmail,omitempty"`
+	// Password is accepted on creation only and is never returned
+	Password string `json:"password,omitempty"`
+}
+
+// DeletedUserFullname replaces fullname of deleted users, their nickname becomes deleted-<id>
+const DeletedUserFullname = "deleted user"
+
+// UserStats is activity of the user, deleted and held posts are not counted
+type UserStats struct {
+	Posts         int              `json:"posts"`
+	Threads       int              `json:"threads"`
+	VotesReceived int              `json:"votesReceived"`
+	Forums        []string         `json:"forums"`
+	Joined        strfmt.DateTime  `json:"joined"`
+	LastActive    *strfmt.DateTime `json:"lastActive,omitempty"`
+}
+
+const NicknameUserSort = "nickname"
+const FullnameUserSort = "fullname"
+const CreatedUserSort = "created"
+const RelevanceUserSort = "relevance"
+
+// UserSearch selects a page of the user directory, the page starts after
+// the user with AfterNickname whose sort key is AfterKey
+type UserSearch struct {
+	Query         string
+	Sort          string
+	Desc          bool
+	Limit         int32
+	AfterKey      string
+	AfterNickname string
+}
+
+type UsersPage struct {
+	Users []User `json:"users"`
+	Next  string `json:"next,omitempty"`
+}
+
+type UserRename struct {
 	Nickname string `json:"nickname"`
-	Fullname string `json:"fullname,omitempty"`
-	Email    string `json:"email,omitempty"`
-	About    string `json:"about,omitempty"`
+}
+
+type UserPrivacy struct {
+	HiddenFromDirectory bool `json:"hiddenFromDirectory"`
 }
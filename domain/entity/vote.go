@@ -1,5 +1,10 @@
 package entity
 
+import (
+	"github.com/go-openapi/strfmt"
+	"time"
+)
+
 type Vote struct {
 	UserID   int    `json:"-"`
 	Nickname string `json:"nickname"`
@@ -7,3 +12,26 @@ type Vote struct {
 	ID       int    `json:"id"`
 	Slug     string `json:"slug"`
 }
+
+// VoteRecord is a vote as it is shown in vote listings, Post is set only for votes on posts
+type VoteRecord struct {
+	Nickname string          `json:"nickname"`
+	Voice    int             `json:"voice"`
+	Thread   int             `json:"thread"`
+	Post     int             `json:"post,omitempty"`
+	Created  strfmt.DateTime `json:"created"`
+}
+
+// VoteQuery selects a page of a vote listing ordered by created, nickname, thread and post.
+// Since bounds the first page inclusively, next pages start strictly after the After vote
+type VoteQuery struct {
+	Limit int32
+	Desc  bool
+	Since *time.Time
+	After *VoteRecord
+}
+
+type VotePage struct {
+	Items []VoteRecord `json:"items"`
+	Next  string       `json:"next,omitempty"`
+}
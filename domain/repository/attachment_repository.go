@@ -0,0 +1,9 @@
+package repository
+
+import "forum/domain/entity"
+
+type AttachmentRepository interface {
+	CreateAttachment(attachment *entity.Attachment) error
+	GetAttachment(id int) (*entity.Attachment, error)
+	GetPostsAttachments(postIDs []int) (map[int][]entity.Attachment, error)
+}
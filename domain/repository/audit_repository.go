@@ -0,0 +1,10 @@
+package repository
+
+import "forum/domain/entity"
+
+type AuditRepository interface {
+	AddAuditEntry(entry *entity.AuditEntry) error
+	GetAuditEntries(filter *entity.AuditFilter) ([]entity.AuditEntry, error)
+	// ForEachContentRevision iterates over edits of posts and threads authored by the user
+	ForEachContentRevision(nickname string, fn func(entry *entity.AuditEntry) error) error
+}
@@ -0,0 +1,12 @@
+package repository
+
+import "io"
+
+// BlobStorage keeps binary content of attachments by key
+type BlobStorage interface {
+	Save(key string, content io.Reader) (int64, error)
+	Open(key string) (io.ReadCloser, error)
+	Delete(key string) error
+	// DeleteAll removes every stored blob, including blobs no attachment refers to
+	DeleteAll() error
+}
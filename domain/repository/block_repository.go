@@ -0,0 +1,11 @@
+package repository
+
+import "forum/domain/entity"
+
+type BlockRepository interface {
+	// Block also removes follows between the users in both directions
+	Block(blocker, blocked string) error
+	Unblock(blocker, blocked string) error
+	GetBlocked(nickname string, limit int32, since string, desc bool) ([]entity.User, error)
+	GetBlockedNicknames(nickname string) ([]string, error)
+}
@@ -0,0 +1,13 @@
+package repository
+
+import "forum/domain/entity"
+
+type FollowRepository interface {
+	Follow(follower, followee string) error
+	Unfollow(follower, followee string) error
+	// GetFollowing and GetFollowers list users paginated by nickname
+	GetFollowing(nickname string, limit int32, since string, desc bool) ([]entity.User, error)
+	GetFollowers(nickname string, limit int32, since string, desc bool) ([]entity.User, error)
+	// GetFeed lists threads and posts of users followed by the user and not blocked by the user
+	GetFeed(query *entity.FeedQuery) ([]entity.FeedItem, error)
+}
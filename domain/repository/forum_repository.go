@@ -7,4 +7,10 @@ type ForumRepository interface {
 	GetForumDetails(slug string) (*entity.Forum, error)
 	GetForumUsers(slug string, limit int32, since string, order string, compare string) ([]entity.User, error)
 	CheckForum(slug string) (string, error)
+	GetSlugsWithPrefix(prefix string) ([]string, error)
+	GetAttachmentLimits(slug string) (*entity.AttachmentLimits, error)
+	SetAttachmentLimits(slug string, limits *entity.AttachmentLimits) error
+	GetBannedUsers(slug string, nicknames []string) ([]string, error)
+	GetWordFilter(slug string) (*entity.WordFilter, error)
+	SetWordFilter(slug string, filter *entity.WordFilter) error
 }
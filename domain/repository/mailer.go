@@ -0,0 +1,8 @@
+package repository
+
+import "forum/domain/entity"
+
+// Mailer delivers mail to users
+type Mailer interface {
+	Send(mail *entity.Mail) error
+}
@@ -1,8 +1,23 @@
 package repository
 
-import "forum/domain/entity"
+import (
+	"forum/domain/entity"
+	"time"
+)
 
 type PostRepository interface {
 	GetPostDetails(postID int) (*entity.Post, error)
-	ChangePostMessage(post *entity.Post) (*entity.Post, error)
+	// ChangePostMessage changes the message, replaces its mentions and writes its audit entry in one transaction
+	ChangePostMessage(post *entity.Post, audit *entity.AuditEntry) (*entity.Post, error)
+	VoteForPost(vote *entity.Vote) (*entity.Post, error)
+	GetUserMentions(nickname string, limit int32, since string, desc bool) ([]entity.Post, error)
+	GetUserPosts(nickname string, limit int32, since string, desc bool) ([]entity.Post, error)
+	// ForEachUserPost iterates over all posts of the user including held and deleted ones
+	ForEachUserPost(nickname string, fn func(post *entity.Post) error) error
+	GetPostsQuotes(postIDs []int) (map[int][]entity.PostQuote, map[int][]int, error)
+	// FindRecentDuplicates returns indexes of authors[i] - messages[i] pairs posted after since
+	FindRecentDuplicates(authors []string, messages []string, since time.Time) ([]int, error)
+	GetForumHeldPosts(forum string, limit int32, since string, desc bool) ([]entity.Post, error)
+	ApprovePost(postID int) (*entity.Post, error)
+	RejectPost(postID int) (*entity.Post, error)
 }
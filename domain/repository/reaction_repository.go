@@ -0,0 +1,10 @@
+package repository
+
+import "forum/domain/entity"
+
+type ReactionRepository interface {
+	AddReaction(reaction *entity.Reaction) error
+	RemoveReaction(reaction *entity.Reaction) error
+	GetPostsReactions(postIDs []int) (map[int]map[string]int, error)
+	GetThreadReactions(threadID int) (map[string]int, error)
+}
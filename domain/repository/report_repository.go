@@ -0,0 +1,11 @@
+package repository
+
+import "forum/domain/entity"
+
+type ReportRepository interface {
+	CreateReport(report *entity.Report) error
+	GetReport(id int) (*entity.Report, error)
+	GetForumReports(slug string, status string, limit int32, since string, desc bool) ([]entity.Report, error)
+	// ResolveReport applies resolution and returns storage keys of attachments removed with the content
+	ResolveReport(report *entity.Report, resolution *entity.ReportResolution) ([]string, error)
+}
@@ -3,6 +3,7 @@ package repository
 import "forum/domain/entity"
 
 type ServiceRepository interface {
-	ClearAllDate() error
+	ClearData(request *entity.ClearRequest) ([]string, error)
 	GetDBStatus() (*entity.Status, error)
+	GetExtendedStatus(forumsLimit int32) (*entity.ExtendedStatus, error)
 }
@@ -0,0 +1,10 @@
+package repository
+
+import "forum/domain/entity"
+
+// SessionRepository keeps sessions by hash of the session token
+type SessionRepository interface {
+	CreateSession(tokenHash string, session *entity.Session) error
+	GetSession(tokenHash string) (*entity.Session, error)
+	DeleteSession(tokenHash string) error
+}
@@ -0,0 +1,18 @@
+package repository
+
+import "forum/domain/entity"
+
+type SubscriptionRepository interface {
+	SubscribeThread(nickname string, threadID int) error
+	SubscribeForum(nickname string, slug string) error
+	UnsubscribeThread(nickname string, threadID int) error
+	UnsubscribeForum(nickname string, slug string) error
+	GetSubscriptions(nickname string) ([]entity.Subscription, error)
+	GetNotifications(nickname string, limit int32, since string, desc bool, unread bool) ([]entity.Notification, error)
+	ForEachNotification(nickname string, fn func(notification *entity.Notification) error) error
+	MarkNotificationsRead(nickname string, ids []int) error
+	GetNotificationSettings(nickname string) (*entity.NotificationSettings, error)
+	SetNotificationSettings(nickname string, settings *entity.NotificationSettings) error
+	GetPendingDigests() ([]entity.Digest, error)
+	MarkDigested(ids []int) error
+}
@@ -3,17 +3,32 @@ package repository
 import "forum/domain/entity"
 
 type ThreadRepository interface {
+	// CreatePosts stores the posts with their quotes and mentions, notifies subscribers
+	// and subscribes authors in one transaction
 	CreatePosts(thread *entity.Thread, posts []entity.Post) error
+	// CreateThread stores the thread, notifies subscribers of the forum and subscribes the author
 	CreateThread(thread *entity.Thread) error
 	GetThreadPosts(slug string, limit int32, since string, order string) ([]entity.Post, error)
 	GetThreadPostsTree(slug string, limit int32, since string, order string) ([]entity.Post, error)
 	GetThreadPostsParentTree(slug string, limit int32, since string, order string) ([]entity.Post, error)
+	GetThreadPostsTop(slug string, limit int32, since string, order string) ([]entity.Post, error)
 	CheckThreadBySlug(slug string) (int, error)
 	GetThreadForumAndID(slugOrID string) (*entity.Thread, error)
 	GetThreadsByForumSlug(slug string, limit int32, since string, desc bool) ([]entity.Thread, error)
+	GetUserThreads(nickname string, limit int32, since string, desc bool) ([]entity.Thread, error)
+	ForEachUserThread(nickname string, fn func(thread *entity.Thread) error) error
 	CheckThreadByID(ID int) error
 	VoteForThread(vote *entity.Vote) (*entity.Thread, error)
+	GetThreadVotes(threadID int, query *entity.VoteQuery) ([]entity.VoteRecord, error)
 	GetThreadBySlug(slug string) (*entity.Thread, error)
 	GetThreadByID(ID int) (*entity.Thread, error)
-	UpdateThread(thread *entity.Thread) error
+	// UpdateThread changes the thread and writes its audit entry in one transaction
+	UpdateThread(thread *entity.Thread, audit *entity.AuditEntry) error
+	GetSlugsWithPrefix(prefix string) ([]string, error)
+	// MarkThreadRead never moves the last read post back
+	MarkThreadRead(nickname string, threadID int, postID int) error
+	GetUnreadCounts(nickname string, threadIDs []int) (map[int]int, error)
+	GetFirstUnreadPost(nickname string, threadID int) (*entity.Post, error)
+	// GetPostPosition counts posts listed before the post in flat or tree sort
+	GetPostPosition(threadID int, postID int, sort string) (int, error)
 }
@@ -1,6 +1,9 @@
 package repository
 
-import "forum/domain/entity"
+import (
+	"forum/domain/entity"
+	"time"
+)
 
 type UserRepository interface {
 	CreateUser(user *entity.User) error
@@ -9,4 +12,33 @@ type UserRepository interface {
 	UpdateUser(newUser *entity.User) (*entity.User, error)
 	GetUserNicknameWithEmail(email string) (string, error)
 	GetUsersWithNicknameAndEmail(nickname, email string) ([]entity.User, error)
+	GetUserVotes(nickname string, query *entity.VoteQuery) ([]entity.VoteRecord, error)
+	GetUsersCreated(nicknames []string) (map[string]time.Time, error)
+	GetUserStats(nickname string) (*entity.UserStats, error)
+	// SearchUsers returns users visible in the directory and their sort keys as text
+	SearchUsers(search *entity.UserSearch) ([]entity.User, []string, error)
+	GetUserPrivacy(nickname string) (*entity.UserPrivacy, error)
+	SetUserPrivacy(nickname string, privacy *entity.UserPrivacy) error
+	// RenameUser changes nickname everywhere it is stored and records the old one in nickname history.
+	// It fails with NicknameReservedError when another user gave up newNickname after reservedSince,
+	// zero reservedSince disables the check
+	RenameUser(nickname, newNickname string, reservedSince time.Time) error
+	// IsNicknameReserved reports whether nickname was given up by a user other than userID after since
+	IsNicknameReserved(nickname string, userID int, since time.Time) (bool, error)
+	// GetRenamedNickname returns current nickname of the user who used the old one most recently
+	GetRenamedNickname(oldNickname string) (string, error)
+	// DeleteUser anonymizes the user keeping authored content, returns the anonymized user
+	DeleteUser(nickname string) (*entity.User, error)
+	ForEachUserVote(nickname string, fn func(vote *entity.VoteRecord) error) error
+	// SaveEmailToken stores token hash replacing previous tokens of the user with the same purpose
+	SaveEmailToken(tokenHash string, token *entity.EmailToken) error
+	// ConfirmEmail uses up the token and applies it to the user, returns the used token,
+	// nickname of the user and the email the user had before
+	ConfirmEmail(tokenHash string) (*entity.EmailToken, string, string, error)
+	// GetPasswordHash returns id, stored nickname and password hash of the user, the hash is empty
+	// when password was never set
+	GetPasswordHash(nickname string) (int, string, string, error)
+	// ResetPassword uses up reset token, sets the password and ends all sessions of the user,
+	// returns nickname of the user
+	ResetPassword(tokenHash string, passwordHash string) (string, error)
 }
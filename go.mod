@@ -8,12 +8,13 @@ require (
 	github.com/go-openapi/runtime v0.19.28 // indirect
 	github.com/go-openapi/strfmt v0.20.1
 	github.com/gorilla/mux v1.8.0
+	github.com/jackc/pgconn v1.8.1
 	github.com/jackc/pgx/v4 v4.11.0
 	github.com/joho/godotenv v1.3.0
 	github.com/rs/cors v1.7.0
 	go.mongodb.org/mongo-driver v1.5.3 // indirect
 	go.uber.org/zap v1.17.0
-	golang.org/x/crypto v0.0.0-20210513164829-c07d793c2f9a // indirect
+	golang.org/x/crypto v0.0.0-20210513164829-c07d793c2f9a
 	golang.org/x/net v0.0.0-20210525063256-abc453219eb5 // indirect
 	golang.org/x/sys v0.0.0-20210603125802-9665404d3644 // indirect
 	golang.org/x/term v0.0.0-20210503060354-a79de5458b56 // indirect
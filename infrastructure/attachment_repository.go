@@ -0,0 +1,77 @@
+package infrastructure
+
+import (
+	"context"
+	"forum/domain/entity"
+	"github.com/jackc/pgx/v4/pgxpool"
+)
+
+type AttachmentRepo struct {
+	db *pgxpool.Pool
+}
+
+func NewAttachmentRepository(db *pgxpool.Pool) *AttachmentRepo {
+	return &AttachmentRepo{db: db}
+}
+
+const CreateAttachmentQuery = `INSERT INTO attachments (post_id, author, filename, content_type, size, storage_key)
+	VALUES ($1, $2, $3, $4, $5, $6) RETURNING id, created`
+
+func (a *AttachmentRepo) CreateAttachment(attachment *entity.Attachment) error {
+	return a.db.QueryRow(context.Background(), CreateAttachmentQuery,
+		attachment.Post, attachment.Author, attachment.Filename, attachment.ContentType, attachment.Size,
+		attachment.StorageKey,
+	).Scan(&attachment.ID, &attachment.Created)
+}
+
+const GetAttachmentQuery = `SELECT id, post_id, author, filename, content_type, size, storage_key, created
+	FROM attachments WHERE id = $1`
+
+func (a *AttachmentRepo) GetAttachment(id int) (*entity.Attachment, error) {
+	attachment := &entity.Attachment{}
+	err := a.db.QueryRow(context.Background(), GetAttachmentQuery, id).Scan(
+		&attachment.ID,
+		&attachment.Post,
+		&attachment.Author,
+		&attachment.Filename,
+		&attachment.ContentType,
+		&attachment.Size,
+		&attachment.StorageKey,
+		&attachment.Created)
+
+	if err != nil {
+		return nil, err
+	}
+	return attachment, nil
+}
+
+const GetPostsAttachmentsQuery = `SELECT id, post_id, author, filename, content_type, size, storage_key, created
+	FROM attachments WHERE post_id = ANY($1) ORDER BY id`
+
+func (a *AttachmentRepo) GetPostsAttachments(postIDs []int) (map[int][]entity.Attachment, error) {
+	rows, err := a.db.Query(context.Background(), GetPostsAttachmentsQuery, postIDs)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	attachments := make(map[int][]entity.Attachment)
+	for rows.Next() {
+		attachment := entity.Attachment{}
+		err = rows.Scan(
+			&attachment.ID,
+			&attachment.Post,
+			&attachment.Author,
+			&attachment.Filename,
+			&attachment.ContentType,
+			&attachment.Size,
+			&attachment.StorageKey,
+			&attachment.Created)
+		if err != nil {
+			return nil, err
+		}
+		attachments[attachment.Post] = append(attachments[attachment.Post], attachment)
+	}
+
+	return attachments, rows.Err()
+}
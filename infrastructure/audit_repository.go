@@ -0,0 +1,123 @@
+package infrastructure
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"forum/domain/entity"
+	"github.com/jackc/pgx/v4"
+	"github.com/jackc/pgx/v4/pgxpool"
+)
+
+type AuditRepo struct {
+	db *pgxpool.Pool
+}
+
+func NewAuditRepository(db *pgxpool.Pool) *AuditRepo {
+	return &AuditRepo{db: db}
+}
+
+const AddAuditEntryQuery = `INSERT INTO audit_log (actor, action, target_type, target_id, before, after)
+	VALUES ($1, $2, $3, $4, NULLIF($5, '')::jsonb, NULLIF($6, '')::jsonb) RETURNING id, created`
+
+func (a *AuditRepo) AddAuditEntry(entry *entity.AuditEntry) error {
+	return a.db.QueryRow(context.Background(), AddAuditEntryQuery,
+		entry.Actor, entry.Action, entry.TargetType, entry.TargetID, string(entry.Before), string(entry.After),
+	).Scan(&entry.ID, &entry.Created)
+}
+
+// addAuditEntry writes the entry in the transaction of the change it records
+func addAuditEntry(tx pgx.Tx, entry *entity.AuditEntry) error {
+	return tx.QueryRow(context.Background(), AddAuditEntryQuery,
+		entry.Actor, entry.Action, entry.TargetType, entry.TargetID, string(entry.Before), string(entry.After),
+	).Scan(&entry.ID, &entry.Created)
+}
+
+func (a *AuditRepo) GetAuditEntries(filter *entity.AuditFilter) ([]entity.AuditEntry, error) {
+	query := `SELECT id, actor, action, target_type, target_id, COALESCE(before::text, ''), COALESCE(after::text, ''), created
+		FROM audit_log WHERE TRUE`
+	args := make([]interface{}, 0)
+
+	conditions := []struct {
+		column string
+		value  string
+	}{
+		{"actor", filter.Actor},
+		{"action", filter.Action},
+		{"target_type", filter.TargetType},
+		{"target_id", filter.TargetID},
+	}
+	for _, condition := range conditions {
+		if condition.value != "" {
+			args = append(args, condition.value)
+			query += fmt.Sprintf(" AND %v = $%d", condition.column, len(args))
+		}
+	}
+
+	order := "ASC"
+	compare := ">"
+	if filter.Desc {
+		order = "DESC"
+		compare = "<"
+	}
+	if filter.Since != "" {
+		args = append(args, filter.Since)
+		query += fmt.Sprintf(" AND id %v $%d", compare, len(args))
+	}
+
+	query += fmt.Sprintf(" ORDER BY id %v", order)
+	if filter.Limit != 0 {
+		query += fmt.Sprintf(" LIMIT %v", filter.Limit)
+	}
+
+	rows, err := a.db.Query(context.Background(), query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	entries := make([]entity.AuditEntry, 0, filter.Limit)
+	for rows.Next() {
+		entry := entity.AuditEntry{}
+		var before, after string
+		err = rows.Scan(&entry.ID, &entry.Actor, &entry.Action, &entry.TargetType, &entry.TargetID,
+			&before, &after, &entry.Created)
+		if err != nil {
+			return nil, err
+		}
+		if before != "" {
+			entry.Before = json.RawMessage(before)
+		}
+		if after != "" {
+			entry.After = json.RawMessage(after)
+		}
+		entries = append(entries, entry)
+	}
+
+	return entries, rows.Err()
+}
+
+const ForEachContentRevisionQuery = `SELECT id, actor, action, target_type, target_id,
+	COALESCE(before::text, ''), COALESCE(after::text, ''), created FROM audit_log
+	WHERE (action = $2 AND target_id IN (SELECT id::text FROM posts WHERE author = $1))
+		OR (action = $3 AND target_id IN (SELECT id::text FROM threads WHERE author = $1))
+	ORDER BY id`
+
+func (a *AuditRepo) ForEachContentRevision(nickname string, fn func(entry *entity.AuditEntry) error) error {
+	return forEachRow(a.db, func(rows pgx.Rows) error {
+		entry := &entity.AuditEntry{}
+		var before, after string
+		err := rows.Scan(&entry.ID, &entry.Actor, &entry.Action, &entry.TargetType, &entry.TargetID,
+			&before, &after, &entry.Created)
+		if err != nil {
+			return err
+		}
+		if before != "" {
+			entry.Before = json.RawMessage(before)
+		}
+		if after != "" {
+			entry.After = json.RawMessage(after)
+		}
+		return fn(entry)
+	}, ForEachContentRevisionQuery, nickname, entity.AuditPostEdit, entity.AuditThreadEdit)
+}
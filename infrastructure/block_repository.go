@@ -0,0 +1,109 @@
+package infrastructure
+
+import (
+	"context"
+	"fmt"
+	"forum/domain/entity"
+	"github.com/jackc/pgx/v4/pgxpool"
+)
+
+type BlockRepo struct {
+	db *pgxpool.Pool
+}
+
+func NewBlockRepository(db *pgxpool.Pool) *BlockRepo {
+	return &BlockRepo{db: db}
+}
+
+const BlockQuery = `INSERT INTO blocks (blocker, blocked) VALUES ($1, $2) ON CONFLICT DO NOTHING`
+const DeleteBlockedFollowsQuery = `DELETE FROM follows
+	WHERE (follower = $1 AND followee = $2) OR (follower = $2 AND followee = $1)`
+
+func (b *BlockRepo) Block(blocker, blocked string) error {
+	tx, err := b.db.Begin(context.Background())
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(context.Background())
+
+	_, err = tx.Exec(context.Background(), BlockQuery, blocker, blocked)
+	if err != nil {
+		return err
+	}
+	_, err = tx.Exec(context.Background(), DeleteBlockedFollowsQuery, blocker, blocked)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit(context.Background())
+}
+
+const UnblockQuery = `DELETE FROM blocks WHERE blocker = $1 AND blocked = $2`
+
+func (b *BlockRepo) Unblock(blocker, blocked string) error {
+	_, err := b.db.Exec(context.Background(), UnblockQuery, blocker, blocked)
+	return err
+}
+
+func (b *BlockRepo) GetBlocked(nickname string, limit int32, since string, desc bool) ([]entity.User, error) {
+	order := "ASC"
+	compare := ">"
+	if desc {
+		order = "DESC"
+		compare = "<"
+	}
+
+	query := `SELECT u.about, u.email, u.fullname, u.nickname FROM users AS u
+		JOIN blocks AS b ON u.nickname = b.blocked
+		WHERE b.blocker = $1`
+	args := []interface{}{nickname}
+	if since != "" {
+		query += fmt.Sprintf(" AND u.nickname %v $2", compare)
+		args = append(args, since)
+	}
+
+	query += fmt.Sprintf(" ORDER BY u.nickname %v", order)
+	if limit != 0 {
+		query += fmt.Sprintf(" LIMIT %v", limit)
+	}
+
+	rows, err := b.db.Query(context.Background(), query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	users := make([]entity.User, 0, limit)
+	for rows.Next() {
+		user := entity.User{}
+		err = rows.Scan(&user.About, &user.Email, &user.Fullname, &user.Nickname)
+		if err != nil {
+			return nil, err
+		}
+		users = append(users, user)
+	}
+
+	return users, rows.Err()
+}
+
+const GetBlockedNicknamesQuery = `SELECT blocked FROM blocks WHERE blocker = $1`
+
+func (b *BlockRepo) GetBlockedNicknames(nickname string) ([]string, error) {
+	rows, err := b.db.Query(context.Background(), GetBlockedNicknamesQuery, nickname)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	nicknames := make([]string, 0)
+	for rows.Next() {
+		var blocked string
+		err = rows.Scan(&blocked)
+		if err != nil {
+			return nil, err
+		}
+		nicknames = append(nicknames, blocked)
+	}
+
+	return nicknames, rows.Err()
+}
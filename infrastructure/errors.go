@@ -0,0 +1,14 @@
+package infrastructure
+
+import (
+	"errors"
+	"github.com/jackc/pgconn"
+)
+
+const uniqueViolationCode = "23505"
+
+// isUniqueViolation reports whether err was caused by unique constraint of the table
+func isUniqueViolation(err error) bool {
+	var pgErr *pgconn.PgError
+	return errors.As(err, &pgErr) && pgErr.Code == uniqueViolationCode
+}
@@ -0,0 +1,185 @@
+package infrastructure
+
+import (
+	"context"
+	"fmt"
+	"forum/domain/entity"
+	"github.com/jackc/pgx/v4"
+	"github.com/jackc/pgx/v4/pgxpool"
+	"sort"
+	"time"
+)
+
+type FollowRepo struct {
+	db *pgxpool.Pool
+}
+
+func NewFollowRepository(db *pgxpool.Pool) *FollowRepo {
+	return &FollowRepo{db: db}
+}
+
+const FollowQuery = `INSERT INTO follows (follower, followee) VALUES ($1, $2) ON CONFLICT DO NOTHING`
+
+func (f *FollowRepo) Follow(follower, followee string) error {
+	_, err := f.db.Exec(context.Background(), FollowQuery, follower, followee)
+	return err
+}
+
+const UnfollowQuery = `DELETE FROM follows WHERE follower = $1 AND followee = $2`
+
+func (f *FollowRepo) Unfollow(follower, followee string) error {
+	_, err := f.db.Exec(context.Background(), UnfollowQuery, follower, followee)
+	return err
+}
+
+func (f *FollowRepo) GetFollowing(nickname string, limit int32, since string, desc bool) ([]entity.User, error) {
+	return f.getFollowUsers("followee", "follower", nickname, limit, since, desc)
+}
+
+func (f *FollowRepo) GetFollowers(nickname string, limit int32, since string, desc bool) ([]entity.User, error) {
+	return f.getFollowUsers("follower", "followee", nickname, limit, since, desc)
+}
+
+// getFollowUsers lists users of the listed column of follows rows whose by column is nickname
+func (f *FollowRepo) getFollowUsers(listed, by string, nickname string, limit int32, since string, desc bool) ([]entity.User, error) {
+	order := "ASC"
+	compare := ">"
+	if desc {
+		order = "DESC"
+		compare = "<"
+	}
+
+	query := fmt.Sprintf(`SELECT u.about, u.email, u.fullname, u.nickname FROM users AS u
+		JOIN follows AS f ON u.nickname = f.%v
+		WHERE f.%v = $1`, listed, by)
+	args := []interface{}{nickname}
+	if since != "" {
+		query += fmt.Sprintf(" AND u.nickname %v $2", compare)
+		args = append(args, since)
+	}
+
+	query += fmt.Sprintf(" ORDER BY u.nickname %v", order)
+	if limit != 0 {
+		query += fmt.Sprintf(" LIMIT %v", limit)
+	}
+
+	rows, err := f.db.Query(context.Background(), query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	users := make([]entity.User, 0, limit)
+	for rows.Next() {
+		user := entity.User{}
+		err = rows.Scan(&user.About, &user.Email, &user.Fullname, &user.Nickname)
+		if err != nil {
+			return nil, err
+		}
+		users = append(users, user)
+	}
+
+	return users, rows.Err()
+}
+
+// feedAuthors are users followed by $1 except the ones $1 blocked, a user may follow and block the same user
+const feedAuthors = `(SELECT f.followee FROM follows AS f WHERE f.follower = $1
+	AND NOT EXISTS (SELECT 1 FROM blocks AS b WHERE b.blocker = $1 AND b.blocked = f.followee))`
+
+const FeedThreadsQuery = `SELECT ` + ThreadColumns + ` FROM threads
+	WHERE author IN ` + feedAuthors
+const FeedPostsQuery = `SELECT ` + PostColumns + ` FROM posts
+	WHERE author IN ` + feedAuthors + ` AND NOT isHeld AND NOT isDeleted`
+
+// GetFeed reads up to the limit of threads and of posts past the cursor and merges them,
+// so the first limit items of the merge are the page
+func (f *FollowRepo) GetFeed(query *entity.FeedQuery) ([]entity.FeedItem, error) {
+	items := make([]entity.FeedItem, 0, 2*query.Limit)
+
+	rows, err := f.queryFeed(FeedThreadsQuery, entity.ThreadFeedItem, query)
+	if err != nil {
+		return nil, err
+	}
+	for rows.Next() {
+		thread := &entity.Thread{}
+		err = scanThread(rows, thread)
+		if err != nil {
+			rows.Close()
+			return nil, err
+		}
+		items = append(items, entity.FeedItem{Type: entity.ThreadFeedItem, Created: thread.Created, Thread: thread})
+	}
+	rows.Close()
+	if rows.Err() != nil {
+		return nil, rows.Err()
+	}
+
+	rows, err = f.queryFeed(FeedPostsQuery, entity.PostFeedItem, query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		post := &entity.Post{}
+		err = scanPost(rows, post)
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, entity.FeedItem{Type: entity.PostFeedItem, Created: post.Created, Post: post})
+	}
+	if rows.Err() != nil {
+		return nil, rows.Err()
+	}
+
+	sortFeed(items, query.Desc)
+	if int32(len(items)) > query.Limit {
+		items = items[:query.Limit]
+	}
+	return items, nil
+}
+
+func (f *FollowRepo) queryFeed(base string, itemType string, query *entity.FeedQuery) (pgx.Rows, error) {
+	order := "ASC"
+	compare := ">"
+	if query.Desc {
+		order = "DESC"
+		compare = "<"
+	}
+
+	sql := base
+	args := []interface{}{query.Nickname}
+	if query.After != nil {
+		sql += fmt.Sprintf(" AND (created, $2::text, id) %v ($3::timestamptz, $4::text, $5::int)", compare)
+		args = append(args, itemType, *query.After, query.AfterType, query.AfterID)
+	}
+	sql += fmt.Sprintf(" ORDER BY created %v, id %v LIMIT %v", order, order, query.Limit)
+
+	return f.db.Query(context.Background(), sql, args...)
+}
+
+// sortFeed orders items by creation time, type and id like the feed queries do
+func sortFeed(items []entity.FeedItem, desc bool) {
+	id := func(item *entity.FeedItem) int {
+		if item.Thread != nil {
+			return item.Thread.ID
+		}
+		return item.Post.ID
+	}
+	less := func(a, b *entity.FeedItem) bool {
+		aCreated, bCreated := time.Time(a.Created), time.Time(b.Created)
+		if !aCreated.Equal(bCreated) {
+			return aCreated.Before(bCreated)
+		}
+		if a.Type != b.Type {
+			return a.Type < b.Type
+		}
+		return id(a) < id(b)
+	}
+
+	sort.Slice(items, func(i, j int) bool {
+		if desc {
+			return less(&items[j], &items[i])
+		}
+		return less(&items[i], &items[j])
+	})
+}
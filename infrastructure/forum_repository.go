@@ -19,6 +19,9 @@ const CreateForumQuery = `INSERT INTO forums (slug, title, user_nickname) VALUES
 
 func (f *ForumRepo) CreateForum(forumInput *entity.Forum) error {
 	_, err := f.db.Exec(context.Background(), CreateForumQuery, forumInput.Slug, forumInput.Title, forumInput.User)
+	if isUniqueViolation(err) {
+		return entity.SlugExistsError
+	}
 	return err
 }
 
@@ -100,3 +103,79 @@ func (f *ForumRepo) CheckForum(slug string) (string, error) {
 
 	return slug, nil
 }
+
+const GetForumSlugsWithPrefixQuery = `SELECT slug FROM forums WHERE slug = $1 OR slug LIKE $1 || '-%'`
+
+func (f *ForumRepo) GetSlugsWithPrefix(prefix string) ([]string, error) {
+	return getSlugsWithPrefix(f.db, GetForumSlugsWithPrefixQuery, prefix)
+}
+
+const GetAttachmentLimitsQuery = `SELECT COALESCE(attachment_max_size, 0), COALESCE(attachment_types, '{}')
+	FROM forums WHERE slug = $1`
+
+// GetAttachmentLimits returns own limits of the forum, zero values mean that limit is not set
+func (f *ForumRepo) GetAttachmentLimits(slug string) (*entity.AttachmentLimits, error) {
+	limits := &entity.AttachmentLimits{}
+	err := f.db.QueryRow(context.Background(), GetAttachmentLimitsQuery, slug).Scan(&limits.MaxSize, &limits.Types)
+	if err != nil {
+		return nil, err
+	}
+	return limits, nil
+}
+
+const SetAttachmentLimitsQuery = `UPDATE forums SET attachment_max_size = NULLIF($1, 0),
+	attachment_types = NULLIF($2::text[], '{}') WHERE slug = $3`
+
+func (f *ForumRepo) SetAttachmentLimits(slug string, limits *entity.AttachmentLimits) error {
+	types := limits.Types
+	if types == nil {
+		types = []string{}
+	}
+	_, err := f.db.Exec(context.Background(), SetAttachmentLimitsQuery, limits.MaxSize, types, slug)
+	return err
+}
+
+const GetBannedUsersQuery = `SELECT nickname FROM forum_bans WHERE forum_slug = $1 AND nickname = ANY($2::text[]::citext[])`
+
+// GetBannedUsers returns those of nicknames that are banned in the forum
+func (f *ForumRepo) GetBannedUsers(slug string, nicknames []string) ([]string, error) {
+	rows, err := f.db.Query(context.Background(), GetBannedUsersQuery, slug, nicknames)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	banned := make([]string, 0)
+	for rows.Next() {
+		var nickname string
+		err = rows.Scan(&nickname)
+		if err != nil {
+			return nil, err
+		}
+		banned = append(banned, nickname)
+	}
+	return banned, rows.Err()
+}
+
+const GetWordFilterQuery = `SELECT COALESCE(banned_words, '{}'), banned_words_mode FROM forums WHERE slug = $1`
+
+func (f *ForumRepo) GetWordFilter(slug string) (*entity.WordFilter, error) {
+	filter := &entity.WordFilter{}
+	err := f.db.QueryRow(context.Background(), GetWordFilterQuery, slug).Scan(&filter.Words, &filter.Mode)
+	if err != nil {
+		return nil, err
+	}
+	return filter, nil
+}
+
+const SetWordFilterQuery = `UPDATE forums SET banned_words = NULLIF($1::text[], '{}'), banned_words_mode = $2
+	WHERE slug = $3`
+
+func (f *ForumRepo) SetWordFilter(slug string, filter *entity.WordFilter) error {
+	words := filter.Words
+	if words == nil {
+		words = []string{}
+	}
+	_, err := f.db.Exec(context.Background(), SetWordFilterQuery, words, filter.Mode, slug)
+	return err
+}
@@ -0,0 +1,84 @@
+package infrastructure
+
+import (
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// LocalBlobStorage keeps blobs as files in a directory on local disk
+type LocalBlobStorage struct {
+	dir string
+}
+
+func NewLocalBlobStorage(dir string) (*LocalBlobStorage, error) {
+	err := os.MkdirAll(dir, 0755)
+	if err != nil {
+		return nil, err
+	}
+	return &LocalBlobStorage{dir: dir}, nil
+}
+
+// path keeps only the base name of the key so that blobs can't escape storage directory
+func (s *LocalBlobStorage) path(key string) string {
+	return filepath.Join(s.dir, filepath.Base(key))
+}
+
+// Save writes content into temporary file first, so partially written blobs are never visible
+func (s *LocalBlobStorage) Save(key string, content io.Reader) (int64, error) {
+	tmp, err := ioutil.TempFile(s.dir, ".upload-")
+	if err != nil {
+		return 0, err
+	}
+	defer os.Remove(tmp.Name())
+
+	size, err := io.Copy(tmp, content)
+	if err != nil {
+		tmp.Close()
+		return 0, err
+	}
+
+	err = tmp.Close()
+	if err != nil {
+		return 0, err
+	}
+
+	err = os.Rename(tmp.Name(), s.path(key))
+	if err != nil {
+		return 0, err
+	}
+	return size, nil
+}
+
+func (s *LocalBlobStorage) Open(key string) (io.ReadCloser, error) {
+	return os.Open(s.path(key))
+}
+
+func (s *LocalBlobStorage) Delete(key string) error {
+	err := os.Remove(s.path(key))
+	if os.IsNotExist(err) {
+		return nil
+	}
+	return err
+}
+
+// DeleteAll leaves temporary files of uploads in progress, Save removes them itself
+func (s *LocalBlobStorage) DeleteAll() error {
+	files, err := ioutil.ReadDir(s.dir)
+	if err != nil {
+		return err
+	}
+
+	for _, file := range files {
+		if file.IsDir() || strings.HasPrefix(file.Name(), ".upload-") {
+			continue
+		}
+		err = s.Delete(file.Name())
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,43 @@
+package infrastructure
+
+import (
+	"forum/domain/entity"
+	"go.uber.org/zap"
+	"os"
+	"sync"
+)
+
+// LogMailer is a mail sink for local development, mail is appended to the file
+// or written to the log when there is no file
+type LogMailer struct {
+	mu     sync.Mutex
+	path   string
+	from   string
+	logger *zap.Logger
+}
+
+func NewLogMailer(path, from string, logger *zap.Logger) *LogMailer {
+	return &LogMailer{path: path, from: from, logger: logger}
+}
+
+func (m *LogMailer) Send(mail *entity.Mail) error {
+	if m.path == "" {
+		m.logger.Info("Mail", zap.String("to", mail.To), zap.String("subject", mail.Subject),
+			zap.String("body", mail.Body))
+		return nil
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	file, err := os.OpenFile(m.path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
+	if err != nil {
+		return err
+	}
+	_, err = file.Write(append(formatMail(m.from, mail), "\r\n\r\n"...))
+	if err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
+}
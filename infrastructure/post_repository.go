@@ -2,8 +2,11 @@ package infrastructure
 
 import (
 	"context"
+	"fmt"
 	"forum/domain/entity"
+	"github.com/jackc/pgx/v4"
 	"github.com/jackc/pgx/v4/pgxpool"
+	"time"
 )
 
 type PostRepo struct {
@@ -14,20 +17,52 @@ func NewPostRepository(db *pgxpool.Pool) *PostRepo {
 	return &PostRepo{db: db}
 }
 
-const GetPostDetailsQuery = `SELECT author, created, forum, id, msg, thread, isEdited, parent FROM posts WHERE id = $1`
+// PostColumns is the list of posts columns scanned by scanPost
+const PostColumns = `author, created, forum, id, msg, parent, thread, isEdited, votes, format, COALESCE(msg_html, ''), isDeleted, isHeld`
 
-func (p *PostRepo) GetPostDetails(postID int) (*entity.Post, error) {
-	post := &entity.Post{}
-	err := p.db.QueryRow(context.Background(), GetPostDetailsQuery, postID).Scan(
+func scanPost(row pgx.Row, post *entity.Post) error {
+	return row.Scan(
 		&post.Author,
 		&post.Created,
 		&post.Forum,
 		&post.ID,
 		&post.Message,
+		&post.Parent,
 		&post.Thread,
 		&post.IsEdited,
-		&post.Parent)
+		&post.Votes,
+		&post.Format,
+		&post.MessageHTML,
+		&post.IsDeleted,
+		&post.IsHeld)
+}
 
+// queryPosts runs query selecting PostColumns and collects the result
+func queryPosts(db *pgxpool.Pool, limit int32, query string, args ...interface{}) ([]entity.Post, error) {
+	rows, err := db.Query(context.Background(), query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	posts := make([]entity.Post, 0, limit)
+	for rows.Next() {
+		post := entity.Post{}
+		err = scanPost(rows, &post)
+		if err != nil {
+			return nil, err
+		}
+		posts = append(posts, post)
+	}
+
+	return posts, rows.Err()
+}
+
+const GetPostDetailsQuery = `SELECT ` + PostColumns + ` FROM posts WHERE id = $1`
+
+func (p *PostRepo) GetPostDetails(postID int) (*entity.Post, error) {
+	post := &entity.Post{}
+	err := scanPost(p.db.QueryRow(context.Background(), GetPostDetailsQuery, postID), post)
 	if err != nil {
 		return nil, err
 	}
@@ -35,22 +70,309 @@ func (p *PostRepo) GetPostDetails(postID int) (*entity.Post, error) {
 	return post, nil
 }
 
-const ChangePostMessageQuery = `UPDATE posts SET msg = $1, isEdited = true 
-	          WHERE id = $2
-	          RETURNING author, created, forum, id, msg, thread, isEdited, parent`
+// ChangePostMessageQuery replaces cached html along with the message, edits held by filters hide the post
+const ChangePostMessageQuery = `UPDATE posts SET msg = $1, format = $2, msg_html = NULLIF($3, ''), isEdited = true,
+	          isHeld = isHeld OR $5
+	          WHERE id = $4
+	          RETURNING ` + PostColumns
+const LockPostHeldQuery = `SELECT isHeld FROM posts WHERE id = $1 FOR UPDATE`
 
-func (p *PostRepo) ChangePostMessage(post *entity.Post) (*entity.Post, error) {
-	err := p.db.QueryRow(context.Background(), ChangePostMessageQuery, post.Message, post.ID).Scan(
-		&post.Author,
-		&post.Created,
-		&post.Forum,
-		&post.ID,
-		&post.Message,
-		&post.Thread,
-		&post.IsEdited,
-		&post.Parent)
+// DecreasePostCountQuery uncounts a post held by an edit, forum post_count counts only shown posts
+const DecreasePostCountQuery = `UPDATE forums SET post_count = post_count - 1 WHERE slug = $1`
+
+func (p *PostRepo) ChangePostMessage(post *entity.Post, audit *entity.AuditEntry) (*entity.Post, error) {
+	tx, err := p.db.Begin(context.Background())
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback(context.Background())
+
+	var wasHeld bool
+	err = tx.QueryRow(context.Background(), LockPostHeldQuery, post.ID).Scan(&wasHeld)
+	if err != nil {
+		return nil, err
+	}
+
+	err = scanPost(tx.QueryRow(context.Background(), ChangePostMessageQuery,
+		post.Message, post.Format, post.MessageHTML, post.ID, post.IsHeld), post)
+	if err != nil {
+		return nil, err
+	}
+	if post.IsHeld && !wasHeld {
+		_, err = tx.Exec(context.Background(), DecreasePostCountQuery, post.Forum)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	_, err = tx.Exec(context.Background(), ClearMentionsQuery, post.ID)
+	if err != nil {
+		return nil, err
+	}
+	err = saveMentions(tx, []entity.Post{*post})
+	if err != nil {
+		return nil, err
+	}
+
+	err = addAuditEntry(tx, audit)
+	if err != nil {
+		return nil, err
+	}
+	return post, tx.Commit(context.Background())
+}
+
+const UpsertPostVoteQuery = `INSERT INTO post_vote (nickname, post_id, vote) VALUES ($1, $2, $3)
+	ON CONFLICT (nickname, post_id) DO UPDATE SET vote = EXCLUDED.vote, created = now()
+	WHERE post_vote.vote <> EXCLUDED.vote`
+const DeletePostVoteQuery = `DELETE FROM post_vote WHERE nickname = $1 AND post_id = $2`
+
+// VoteForPost sets user's vote for the post, zero voice retracts the vote.
+// posts.votes is maintained by post_vote triggers
+func (p *PostRepo) VoteForPost(vote *entity.Vote) (*entity.Post, error) {
+	var err error
+	if vote.Voice == 0 {
+		_, err = p.db.Exec(context.Background(), DeletePostVoteQuery, vote.Nickname, vote.ID)
+	} else {
+		_, err = p.db.Exec(context.Background(), UpsertPostVoteQuery, vote.Nickname, vote.ID, vote.Voice)
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	return p.GetPostDetails(vote.ID)
+}
+
+// SaveMentionsQuery keeps only mentions of existing users, nickname is stored as registered
+const SaveMentionsQuery = `INSERT INTO mentions (post_id, nickname)
+	SELECT m.post_id, u.nickname FROM unnest($1::int[], $2::text[]) AS m(post_id, nickname)
+	JOIN users AS u ON u.nickname = m.nickname::citext
+	ON CONFLICT DO NOTHING`
+
+// saveMentions stores parsed mentions of the posts at once, unknown nicknames are skipped
+func saveMentions(tx pgx.Tx, posts []entity.Post) error {
+	postIDs := make([]int, 0)
+	nicknames := make([]string, 0)
+	for _, post := range posts {
+		for _, nickname := range post.Mentions {
+			postIDs = append(postIDs, post.ID)
+			nicknames = append(nicknames, nickname)
+		}
+	}
+
+	if len(postIDs) == 0 {
+		return nil
+	}
+	_, err := tx.Exec(context.Background(), SaveMentionsQuery, postIDs, nicknames)
+	return err
+}
+
+const ClearMentionsQuery = `DELETE FROM mentions WHERE post_id = $1`
+
+func (p *PostRepo) GetUserMentions(nickname string, limit int32, since string, desc bool) ([]entity.Post, error) {
+	order := "ASC"
+	compare := ">"
+	if desc {
+		order = "DESC"
+		compare = "<"
+	}
+
+	// mentions by users blocked by the mentioned user are not shown to them
+	query := `SELECT ` + PostColumns + ` FROM posts WHERE NOT isHeld
+		AND author NOT IN (SELECT blocked FROM blocks WHERE blocker = $1)
+		AND id IN (SELECT post_id FROM mentions WHERE nickname = $1`
+	args := []interface{}{nickname}
+	if since != "" {
+		query += fmt.Sprintf(" AND post_id %v $2", compare)
+		args = append(args, since)
+	}
+
+	query += fmt.Sprintf(") ORDER BY id %v", order)
+	if limit != 0 {
+		query += fmt.Sprintf(" LIMIT %v", limit)
+	}
+
+	return queryPosts(p.db, limit, query, args...)
+}
+
+// GetUserPosts lists visible posts of the user paginated by id
+func (p *PostRepo) GetUserPosts(nickname string, limit int32, since string, desc bool) ([]entity.Post, error) {
+	order := "ASC"
+	compare := ">"
+	if desc {
+		order = "DESC"
+		compare = "<"
+	}
+
+	query := `SELECT ` + PostColumns + ` FROM posts WHERE author = $1 AND NOT isHeld AND NOT isDeleted`
+	args := []interface{}{nickname}
+	if since != "" {
+		query += fmt.Sprintf(" AND id %v $2", compare)
+		args = append(args, since)
+	}
+
+	query += fmt.Sprintf(" ORDER BY id %v", order)
+	if limit != 0 {
+		query += fmt.Sprintf(" LIMIT %v", limit)
+	}
+
+	return queryPosts(p.db, limit, query, args...)
+}
+
+const ForEachUserPostQuery = `SELECT ` + PostColumns + ` FROM posts WHERE author = $1 ORDER BY id`
+
+func (p *PostRepo) ForEachUserPost(nickname string, fn func(post *entity.Post) error) error {
+	return forEachRow(p.db, func(rows pgx.Rows) error {
+		post := &entity.Post{}
+		err := scanPost(rows, post)
+		if err != nil {
+			return err
+		}
+		return fn(post)
+	}, ForEachUserPostQuery, nickname)
+}
+
+const GetPostsQuotesQuery = `SELECT q.post_id, p.id, p.author, p.thread, p.forum FROM post_quotes AS q
+	JOIN posts AS p ON p.id = q.quoted_id
+	WHERE q.post_id = ANY($1) ORDER BY q.post_id, q.position`
+const GetPostsQuotedByQuery = `SELECT quoted_id, post_id FROM post_quotes WHERE quoted_id = ANY($1) ORDER BY post_id`
 
+// GetPostsQuotes returns posts quoted by given posts and ids of posts quoting them, keyed by given post id
+func (p *PostRepo) GetPostsQuotes(postIDs []int) (map[int][]entity.PostQuote, map[int][]int, error) {
+	rows, err := p.db.Query(context.Background(), GetPostsQuotesQuery, postIDs)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer rows.Close()
+
+	quotes := make(map[int][]entity.PostQuote)
+	for rows.Next() {
+		var postID int
+		quote := entity.PostQuote{}
+		err = rows.Scan(&postID, &quote.ID, &quote.Author, &quote.Thread, &quote.Forum)
+		if err != nil {
+			return nil, nil, err
+		}
+		quotes[postID] = append(quotes[postID], quote)
+	}
+	if rows.Err() != nil {
+		return nil, nil, rows.Err()
+	}
+	rows.Close()
+
+	rows, err = p.db.Query(context.Background(), GetPostsQuotedByQuery, postIDs)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer rows.Close()
+
+	quotedBy := make(map[int][]int)
+	for rows.Next() {
+		var quotedID, postID int
+		err = rows.Scan(&quotedID, &postID)
+		if err != nil {
+			return nil, nil, err
+		}
+		quotedBy[quotedID] = append(quotedBy[quotedID], postID)
+	}
+
+	return quotes, quotedBy, rows.Err()
+}
+
+const FindRecentDuplicatesQuery = `SELECT DISTINCT d.idx FROM unnest($1::text[], $2::text[]) WITH ORDINALITY AS d(author, msg, idx)
+	JOIN posts AS p ON p.author = d.author::citext AND p.created > $3 AND p.msg = d.msg`
+
+func (p *PostRepo) FindRecentDuplicates(authors []string, messages []string, since time.Time) ([]int, error) {
+	rows, err := p.db.Query(context.Background(), FindRecentDuplicatesQuery, authors, messages, since)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	indexes := make([]int, 0)
+	for rows.Next() {
+		var idx int
+		err = rows.Scan(&idx)
+		if err != nil {
+			return nil, err
+		}
+		indexes = append(indexes, idx-1)
+	}
+	return indexes, rows.Err()
+}
+
+// GetForumHeldPosts lists posts waiting for moderator review, rejected posts are not included
+func (p *PostRepo) GetForumHeldPosts(forum string, limit int32, since string, desc bool) ([]entity.Post, error) {
+	order := "ASC"
+	compare := ">"
+	if desc {
+		order = "DESC"
+		compare = "<"
+	}
+
+	query := `SELECT ` + PostColumns + ` FROM posts WHERE forum = $1 AND isHeld AND NOT isDeleted`
+	args := []interface{}{forum}
+	if since != "" {
+		query += fmt.Sprintf(" AND id %v $2", compare)
+		args = append(args, since)
+	}
+
+	query += fmt.Sprintf(" ORDER BY id %v", order)
+	if limit != 0 {
+		query += fmt.Sprintf(" LIMIT %v", limit)
+	}
+
+	return queryPosts(p.db, limit, query, args...)
+}
+
+// ApprovePostQuery shows the post and counts it in the forum posts
+const ApprovePostQuery = `WITH approved AS (
+		UPDATE posts SET isHeld = FALSE WHERE id = $1 AND isHeld AND NOT isDeleted RETURNING *
+	), counted AS (
+		UPDATE forums SET post_count = post_count + 1 WHERE slug = (SELECT forum FROM approved)
+	)
+	SELECT ` + PostColumns + ` FROM approved`
+
+// ApprovePost shows the post and notifies subscribers of it in one transaction
+func (p *PostRepo) ApprovePost(postID int) (*entity.Post, error) {
+	tx, err := p.db.Begin(context.Background())
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback(context.Background())
+
+	post := &entity.Post{}
+	err = scanPost(tx.QueryRow(context.Background(), ApprovePostQuery, postID), post)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return nil, entity.PostNotHeldError
+		}
+		return nil, err
+	}
+
+	err = notifyPosts(tx, []int{postID})
+	if err != nil {
+		return nil, err
+	}
+	return post, tx.Commit(context.Background())
+}
+
+// RejectPostQuery deletes the content, the post stays held so it never shows up in listings
+const RejectPostQuery = `UPDATE posts SET msg = '', msg_html = NULL, isDeleted = TRUE
+	WHERE id = $1 AND isHeld AND NOT isDeleted
+	RETURNING ` + PostColumns
+
+func (p *PostRepo) RejectPost(postID int) (*entity.Post, error) {
+	return p.moderateHeldPost(RejectPostQuery, postID)
+}
+
+func (p *PostRepo) moderateHeldPost(query string, postID int) (*entity.Post, error) {
+	post := &entity.Post{}
+	err := scanPost(p.db.QueryRow(context.Background(), query, postID), post)
 	if err != nil {
+		if err == pgx.ErrNoRows {
+			return nil, entity.PostNotHeldError
+		}
 		return nil, err
 	}
 	return post, nil
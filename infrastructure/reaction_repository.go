@@ -0,0 +1,93 @@
+package infrastructure
+
+import (
+	"context"
+	"forum/domain/entity"
+	"github.com/jackc/pgx/v4/pgxpool"
+)
+
+type ReactionRepo struct {
+	db *pgxpool.Pool
+}
+
+func NewReactionRepository(db *pgxpool.Pool) *ReactionRepo {
+	return &ReactionRepo{db: db}
+}
+
+const AddPostReactionQuery = `INSERT INTO reactions (nickname, post_id, kind) VALUES ($1, $2, $3) ON CONFLICT DO NOTHING`
+const AddThreadReactionQuery = `INSERT INTO reactions (nickname, thread_id, kind) VALUES ($1, $2, $3) ON CONFLICT DO NOTHING`
+
+func (r *ReactionRepo) AddReaction(reaction *entity.Reaction) error {
+	var err error
+	if reaction.Post != 0 {
+		_, err = r.db.Exec(context.Background(), AddPostReactionQuery, reaction.Nickname, reaction.Post, reaction.Type)
+	} else {
+		_, err = r.db.Exec(context.Background(), AddThreadReactionQuery, reaction.Nickname, reaction.Thread, reaction.Type)
+	}
+	return err
+}
+
+const RemovePostReactionQuery = `DELETE FROM reactions WHERE nickname = $1 AND post_id = $2 AND kind = $3`
+const RemoveThreadReactionQuery = `DELETE FROM reactions WHERE nickname = $1 AND thread_id = $2 AND kind = $3`
+
+func (r *ReactionRepo) RemoveReaction(reaction *entity.Reaction) error {
+	var err error
+	if reaction.Post != 0 {
+		_, err = r.db.Exec(context.Background(), RemovePostReactionQuery, reaction.Nickname, reaction.Post, reaction.Type)
+	} else {
+		_, err = r.db.Exec(context.Background(), RemoveThreadReactionQuery, reaction.Nickname, reaction.Thread, reaction.Type)
+	}
+	return err
+}
+
+const GetPostsReactionsQuery = `SELECT post_id, kind, COUNT(*) FROM reactions
+	WHERE post_id = ANY($1) GROUP BY post_id, kind`
+
+// GetPostsReactions counts reactions of all given posts with a single query
+func (r *ReactionRepo) GetPostsReactions(postIDs []int) (map[int]map[string]int, error) {
+	rows, err := r.db.Query(context.Background(), GetPostsReactionsQuery, postIDs)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	reactions := make(map[int]map[string]int)
+	for rows.Next() {
+		var postID, count int
+		var kind string
+		err = rows.Scan(&postID, &kind, &count)
+		if err != nil {
+			return nil, err
+		}
+
+		if reactions[postID] == nil {
+			reactions[postID] = make(map[string]int)
+		}
+		reactions[postID][kind] = count
+	}
+
+	return reactions, rows.Err()
+}
+
+const GetThreadReactionsQuery = `SELECT kind, COUNT(*) FROM reactions WHERE thread_id = $1 GROUP BY kind`
+
+func (r *ReactionRepo) GetThreadReactions(threadID int) (map[string]int, error) {
+	rows, err := r.db.Query(context.Background(), GetThreadReactionsQuery, threadID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	reactions := make(map[string]int)
+	for rows.Next() {
+		var kind string
+		var count int
+		err = rows.Scan(&kind, &count)
+		if err != nil {
+			return nil, err
+		}
+		reactions[kind] = count
+	}
+
+	return reactions, rows.Err()
+}
@@ -0,0 +1,344 @@
+package infrastructure
+
+import (
+	"context"
+	"fmt"
+	"forum/domain/entity"
+	"github.com/jackc/pgx/v4"
+	"github.com/jackc/pgx/v4/pgxpool"
+)
+
+type ReportRepo struct {
+	db *pgxpool.Pool
+}
+
+func NewReportRepository(db *pgxpool.Pool) *ReportRepo {
+	return &ReportRepo{db: db}
+}
+
+// ReportColumns is the list of reports columns scanned by scanReport
+const ReportColumns = `id, reporter, reason, forum, COALESCE(post_id, 0), COALESCE(thread_id, 0), status, created,
+	COALESCE(moderator, ''), COALESCE(action, ''), resolved`
+
+func scanReport(row pgx.Row, report *entity.Report) error {
+	return row.Scan(
+		&report.ID,
+		&report.Reporter,
+		&report.Reason,
+		&report.Forum,
+		&report.Post,
+		&report.Thread,
+		&report.Status,
+		&report.Created,
+		&report.Moderator,
+		&report.Action,
+		&report.Resolved)
+}
+
+const CreateReportQuery = `INSERT INTO reports (reporter, reason, forum, post_id, thread_id)
+	VALUES ($1, $2, $3, NULLIF($4, 0), NULLIF($5, 0)) RETURNING ` + ReportColumns
+
+func (r *ReportRepo) CreateReport(report *entity.Report) error {
+	err := scanReport(r.db.QueryRow(context.Background(), CreateReportQuery,
+		report.Reporter, report.Reason, report.Forum, report.Post, report.Thread), report)
+	if err != nil {
+		if isUniqueViolation(err) {
+			return entity.ReportExistsError
+		}
+		return err
+	}
+	return nil
+}
+
+const GetReportQuery = `SELECT ` + ReportColumns + ` FROM reports WHERE id = $1`
+
+func (r *ReportRepo) GetReport(id int) (*entity.Report, error) {
+	report := &entity.Report{}
+	err := scanReport(r.db.QueryRow(context.Background(), GetReportQuery, id), report)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return nil, entity.ReportNotFoundError
+		}
+		return nil, err
+	}
+	return report, nil
+}
+
+// GetForumReports lists reports with the reported content, content of deleted threads is left empty
+func (r *ReportRepo) GetForumReports(slug string, status string, limit int32, since string, desc bool) ([]entity.Report, error) {
+	query := `SELECT ` + ReportColumns + ` FROM reports WHERE forum = $1 AND status = $2`
+	args := []interface{}{slug, status}
+
+	order := "ASC"
+	compare := ">"
+	if desc {
+		order = "DESC"
+		compare = "<"
+	}
+	if since != "" {
+		args = append(args, since)
+		query += fmt.Sprintf(" AND id %v $%d", compare, len(args))
+	}
+	query += fmt.Sprintf(" ORDER BY id %v", order)
+	if limit != 0 {
+		query += fmt.Sprintf(" LIMIT %v", limit)
+	}
+
+	rows, err := r.db.Query(context.Background(), query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	reports := make([]entity.Report, 0, limit)
+	for rows.Next() {
+		report := entity.Report{}
+		err = scanReport(rows, &report)
+		if err != nil {
+			return nil, err
+		}
+		reports = append(reports, report)
+	}
+	if rows.Err() != nil {
+		return nil, rows.Err()
+	}
+	rows.Close()
+
+	return reports, r.fillReportedContent(reports)
+}
+
+const GetReportedPostsQuery = `SELECT ` + PostColumns + ` FROM posts WHERE id = ANY($1)`
+const GetReportedThreadsQuery = `SELECT ` + ThreadColumns + ` FROM threads WHERE id = ANY($1)`
+
+// fillReportedContent loads reported posts and threads of all reports at once
+func (r *ReportRepo) fillReportedContent(reports []entity.Report) error {
+	postIDs := make([]int, 0)
+	threadIDs := make([]int, 0)
+	for _, report := range reports {
+		if report.Post != 0 {
+			postIDs = append(postIDs, report.Post)
+		} else {
+			threadIDs = append(threadIDs, report.Thread)
+		}
+	}
+
+	posts := make(map[int]*entity.Post, len(postIDs))
+	if len(postIDs) != 0 {
+		postList, err := queryPosts(r.db, int32(len(postIDs)), GetReportedPostsQuery, postIDs)
+		if err != nil {
+			return err
+		}
+		for i := range postList {
+			posts[postList[i].ID] = &postList[i]
+		}
+	}
+
+	threads := make(map[int]*entity.Thread, len(threadIDs))
+	if len(threadIDs) != 0 {
+		rows, err := r.db.Query(context.Background(), GetReportedThreadsQuery, threadIDs)
+		if err != nil {
+			return err
+		}
+		defer rows.Close()
+
+		for rows.Next() {
+			thread := &entity.Thread{}
+			err = scanThread(rows, thread)
+			if err != nil {
+				return err
+			}
+			threads[thread.ID] = thread
+		}
+		if rows.Err() != nil {
+			return rows.Err()
+		}
+	}
+
+	for i := range reports {
+		reports[i].ReportedPost = posts[reports[i].Post]
+		reports[i].ReportedThread = threads[reports[i].Thread]
+	}
+	return nil
+}
+
+const CloseReportQuery = `UPDATE reports SET status = 'resolved', moderator = $2, action = $3, resolved = now()
+	WHERE id = $1 AND status = 'open'`
+const InsertReportActionQuery = `INSERT INTO report_actions (report_id, moderator, action, content_author, comment)
+	VALUES ($1, $2, $3, $4, $5)`
+const GetReportedPostAuthorQuery = `SELECT author FROM posts WHERE id = $1`
+const GetReportedThreadAuthorQuery = `SELECT author FROM threads WHERE id = $1`
+const BanUserQuery = `INSERT INTO forum_bans (forum_slug, nickname, moderator) VALUES ($1, $2, $3)
+	ON CONFLICT DO NOTHING`
+
+// ResolveReport closes the report and applies the action in one transaction,
+// each resolution is recorded into report_actions
+func (r *ReportRepo) ResolveReport(report *entity.Report, resolution *entity.ReportResolution) ([]string, error) {
+	tx, err := r.db.Begin(context.Background())
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback(context.Background())
+
+	tag, err := tx.Exec(context.Background(), CloseReportQuery, report.ID, resolution.Moderator, resolution.Action)
+	if err != nil {
+		return nil, err
+	}
+	if tag.RowsAffected() == 0 {
+		return nil, entity.ReportResolvedError
+	}
+
+	var author string
+	if report.Post != 0 {
+		err = tx.QueryRow(context.Background(), GetReportedPostAuthorQuery, report.Post).Scan(&author)
+	} else {
+		err = tx.QueryRow(context.Background(), GetReportedThreadAuthorQuery, report.Thread).Scan(&author)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = tx.Exec(context.Background(), InsertReportActionQuery,
+		report.ID, resolution.Moderator, resolution.Action, author, resolution.Comment)
+	if err != nil {
+		return nil, err
+	}
+
+	keys := make([]string, 0)
+	switch resolution.Action {
+	case entity.DeleteReportAction:
+		if report.Post != 0 {
+			keys, err = deletePost(tx, report.Post, resolution)
+		} else {
+			keys, err = deleteThread(tx, report.Thread, resolution)
+		}
+	case entity.BanReportAction:
+		_, err = tx.Exec(context.Background(), BanUserQuery, report.Forum, author, resolution.Moderator)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return keys, tx.Commit(context.Background())
+}
+
+const DeletePostAttachmentsQuery = `DELETE FROM attachments WHERE post_id = $1 RETURNING storage_key`
+const SoftDeletePostQuery = `UPDATE posts SET msg = '', msg_html = NULL, isDeleted = TRUE WHERE id = $1`
+const DeletePostMentionsQuery = `DELETE FROM mentions WHERE post_id = $1`
+const ClosePostReportsQuery = `UPDATE reports SET status = 'resolved', moderator = $2, action = $3, resolved = now()
+	WHERE status = 'open' AND post_id = ANY($1)`
+
+// deletePost keeps the post as a placeholder so that replies stay in the tree
+func deletePost(tx pgx.Tx, postID int, resolution *entity.ReportResolution) ([]string, error) {
+	keys, err := queryStorageKeys(tx, DeletePostAttachmentsQuery, postID)
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = tx.Exec(context.Background(), SoftDeletePostQuery, postID)
+	if err != nil {
+		return nil, err
+	}
+	_, err = tx.Exec(context.Background(), DeletePostMentionsQuery, postID)
+	if err != nil {
+		return nil, err
+	}
+	_, err = tx.Exec(context.Background(), ClosePostReportsQuery, []int{postID}, resolution.Moderator, resolution.Action)
+	if err != nil {
+		return nil, err
+	}
+	return keys, nil
+}
+
+const GetThreadAttachmentsQuery = `SELECT a.storage_key FROM attachments AS a
+	JOIN posts AS p ON p.id = a.post_id WHERE p.thread = $1`
+const DeleteThreadPostVotesQuery = `DELETE FROM post_vote WHERE post_id IN (SELECT id FROM posts WHERE thread = $1)`
+const DeleteThreadPostsQuery = `DELETE FROM posts WHERE thread = $1 RETURNING id, isHeld`
+const DeleteThreadVotesQuery = `DELETE FROM thread_vote WHERE thread_id = $1`
+const DeleteThreadQuery = `DELETE FROM threads WHERE id = $1 RETURNING forum`
+const DecreaseForumCountersQuery = `UPDATE forums SET thread_count = thread_count - 1, post_count = post_count - $1
+	WHERE slug = $2`
+const CloseThreadReportsQuery = `UPDATE reports SET status = 'resolved', moderator = $2, action = $3, resolved = now()
+	WHERE status = 'open' AND thread_id = $1`
+
+// deleteThread removes the thread with all its posts, open reports on them are closed with the same action
+func deleteThread(tx pgx.Tx, threadID int, resolution *entity.ReportResolution) ([]string, error) {
+	keys, err := queryStorageKeys(tx, GetThreadAttachmentsQuery, threadID)
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = tx.Exec(context.Background(), DeleteThreadPostVotesQuery, threadID)
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := tx.Query(context.Background(), DeleteThreadPostsQuery, threadID)
+	if err != nil {
+		return nil, err
+	}
+	postIDs := make([]int, 0)
+	// held posts are not in forum post_count
+	shown := 0
+	for rows.Next() {
+		var id int
+		var isHeld bool
+		err = rows.Scan(&id, &isHeld)
+		if err != nil {
+			rows.Close()
+			return nil, err
+		}
+		postIDs = append(postIDs, id)
+		if !isHeld {
+			shown++
+		}
+	}
+	rows.Close()
+	if rows.Err() != nil {
+		return nil, rows.Err()
+	}
+
+	_, err = tx.Exec(context.Background(), DeleteThreadVotesQuery, threadID)
+	if err != nil {
+		return nil, err
+	}
+
+	var forum string
+	err = tx.QueryRow(context.Background(), DeleteThreadQuery, threadID).Scan(&forum)
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = tx.Exec(context.Background(), DecreaseForumCountersQuery, shown, forum)
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = tx.Exec(context.Background(), CloseThreadReportsQuery, threadID, resolution.Moderator, resolution.Action)
+	if err != nil {
+		return nil, err
+	}
+	_, err = tx.Exec(context.Background(), ClosePostReportsQuery, postIDs, resolution.Moderator, resolution.Action)
+	if err != nil {
+		return nil, err
+	}
+	return keys, nil
+}
+
+func queryStorageKeys(tx pgx.Tx, query string, args ...interface{}) ([]string, error) {
+	rows, err := tx.Query(context.Background(), query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	keys := make([]string, 0)
+	for rows.Next() {
+		var key string
+		err = rows.Scan(&key)
+		if err != nil {
+			return nil, err
+		}
+		keys = append(keys, key)
+	}
+	return keys, rows.Err()
+}
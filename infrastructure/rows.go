@@ -0,0 +1,24 @@
+package infrastructure
+
+import (
+	"context"
+	"github.com/jackc/pgx/v4"
+	"github.com/jackc/pgx/v4/pgxpool"
+)
+
+// forEachRow runs query and calls scan for every row as it is read, so the result is never held in memory
+func forEachRow(db *pgxpool.Pool, scan func(rows pgx.Rows) error, query string, args ...interface{}) error {
+	rows, err := db.Query(context.Background(), query, args...)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		err = scan(rows)
+		if err != nil {
+			return err
+		}
+	}
+	return rows.Err()
+}
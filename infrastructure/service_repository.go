@@ -2,6 +2,7 @@ package infrastructure
 
 import (
 	"context"
+	"fmt"
 	"forum/domain/entity"
 	"github.com/jackc/pgx/v4/pgxpool"
 )
@@ -15,18 +16,114 @@ func NewServiceRepository(db *pgxpool.Pool) *ServiceRepo {
 }
 
 const ClearDBQuery = `TRUNCATE TABLE Forum_user RESTART IDENTITY CASCADE;
+			  TRUNCATE TABLE Report_actions RESTART IDENTITY CASCADE;
+			  TRUNCATE TABLE Reports RESTART IDENTITY CASCADE;
+			  TRUNCATE TABLE Forum_bans RESTART IDENTITY CASCADE;
+			  TRUNCATE TABLE Nickname_history RESTART IDENTITY CASCADE;
+			  TRUNCATE TABLE Email_tokens RESTART IDENTITY CASCADE;
+			  TRUNCATE TABLE Sessions RESTART IDENTITY CASCADE;
+			  TRUNCATE TABLE Follows RESTART IDENTITY CASCADE;
+			  TRUNCATE TABLE Blocks RESTART IDENTITY CASCADE;
+			  TRUNCATE TABLE Subscriptions RESTART IDENTITY CASCADE;
+			  TRUNCATE TABLE Notifications RESTART IDENTITY CASCADE;
+			  TRUNCATE TABLE Thread_reads RESTART IDENTITY CASCADE;
+			  TRUNCATE TABLE Post_vote RESTART IDENTITY CASCADE;
+			  TRUNCATE TABLE Reactions RESTART IDENTITY CASCADE;
+			  TRUNCATE TABLE Mentions RESTART IDENTITY CASCADE;
+			  TRUNCATE TABLE Post_quotes RESTART IDENTITY CASCADE;
+			  TRUNCATE TABLE Attachments RESTART IDENTITY CASCADE;
 			  TRUNCATE TABLE Thread_vote RESTART IDENTITY CASCADE;
 			  TRUNCATE TABLE Posts RESTART IDENTITY CASCADE;
 			  TRUNCATE TABLE Threads RESTART IDENTITY CASCADE;
 			  TRUNCATE TABLE Forums RESTART IDENTITY CASCADE;
 			  TRUNCATE TABLE Users RESTART IDENTITY CASCADE;`
 
-func (s *ServiceRepo) ClearAllDate() error {
-	_, err := s.db.Exec(context.Background(), ClearDBQuery)
+// clearQueries lists statements of every clear scope, forum scoped statements take forum slug as $1.
+// Post votes are deleted before posts since post_vote has no cascade
+var clearQueries = map[bool]map[string][]string{
+	false: {
+		entity.ClearAllScope: {ClearDBQuery},
+		entity.ClearPostsScope: {
+			`TRUNCATE TABLE Post_vote, Mentions, Post_quotes, Attachments RESTART IDENTITY`,
+			`DELETE FROM reactions WHERE post_id IS NOT NULL`,
+			`DELETE FROM reports WHERE post_id IS NOT NULL`,
+			`DELETE FROM posts`,
+			`UPDATE forums SET post_count = 0`,
+		},
+		entity.ClearVotesScope: {
+			`TRUNCATE TABLE Post_vote, Thread_vote`,
+			`UPDATE posts SET votes = 0 WHERE votes <> 0`,
+			`UPDATE threads SET votes = 0 WHERE votes <> 0`,
+		},
+	},
+	true: {
+		entity.ClearAllScope: {
+			`DELETE FROM post_vote WHERE post_id IN (SELECT id FROM posts WHERE forum = $1)`,
+			`DELETE FROM posts WHERE forum = $1`,
+			`DELETE FROM thread_vote WHERE thread_id IN (SELECT id FROM threads WHERE forum = $1)`,
+			`DELETE FROM threads WHERE forum = $1`,
+			`DELETE FROM forum_user WHERE forum_slug = $1`,
+			`DELETE FROM forums WHERE slug = $1`,
+		},
+		entity.ClearPostsScope: {
+			`DELETE FROM post_vote WHERE post_id IN (SELECT id FROM posts WHERE forum = $1)`,
+			`DELETE FROM reports WHERE forum = $1 AND post_id IS NOT NULL`,
+			`DELETE FROM posts WHERE forum = $1`,
+			`UPDATE forums SET post_count = 0 WHERE slug = $1`,
+		},
+		entity.ClearVotesScope: {
+			`DELETE FROM post_vote WHERE post_id IN (SELECT id FROM posts WHERE forum = $1)`,
+			`DELETE FROM thread_vote WHERE thread_id IN (SELECT id FROM threads WHERE forum = $1)`,
+		},
+	},
+}
+
+const GetAllStorageKeysQuery = `SELECT storage_key FROM attachments`
+const GetForumStorageKeysQuery = `SELECT a.storage_key FROM attachments AS a
+	JOIN posts AS p ON p.id = a.post_id WHERE p.forum = $1`
+const CheckClearForumQuery = `SELECT slug FROM forums WHERE slug = $1`
+
+// ClearData removes data selected by request in one transaction and
+// returns storage keys of removed attachments
+func (s *ServiceRepo) ClearData(request *entity.ClearRequest) ([]string, error) {
+	queries, ok := clearQueries[request.Forum != ""][request.Scope]
+	if !ok {
+		return nil, entity.WrongClearScopeError
+	}
+
+	tx, err := s.db.Begin(context.Background())
 	if err != nil {
-		return err
+		return nil, err
+	}
+	defer tx.Rollback(context.Background())
+
+	args := make([]interface{}, 0, 1)
+	keysQuery := GetAllStorageKeysQuery
+	if request.Forum != "" {
+		err = tx.QueryRow(context.Background(), CheckClearForumQuery, request.Forum).Scan(&request.Forum)
+		if err != nil {
+			return nil, entity.ForumNotExistError
+		}
+		args = append(args, request.Forum)
+		keysQuery = GetForumStorageKeysQuery
+	}
+
+	keys := make([]string, 0)
+	if request.Scope != entity.ClearVotesScope {
+		keys, err = queryStorageKeys(tx, keysQuery, args...)
+		if err != nil {
+			return nil, err
+		}
 	}
-	return nil
+
+	for _, query := range queries {
+		_, err = tx.Exec(context.Background(), query, args...)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return keys, tx.Commit(context.Background())
 }
 
 const GetUserStatusQuery = `SELECT COUNT(*) AS user_count FROM Users;`
@@ -56,3 +153,90 @@ func (s *ServiceRepo) GetDBStatus() (*entity.Status, error) {
 
 	return status, nil
 }
+
+const GetTotalsFromCountersQuery = `SELECT (SELECT COUNT(*) FROM users), COUNT(*),
+	COALESCE(SUM(thread_count), 0), COALESCE(SUM(post_count), 0) FROM forums`
+const GetForumsStatusQuery = `SELECT slug, title, thread_count, post_count FROM forums ORDER BY slug LIMIT $1`
+const GetTopForumsQuery = `SELECT f.slug, f.title, f.thread_count, f.post_count, p.recent FROM
+	(SELECT forum, COUNT(*) AS recent FROM posts WHERE created > now() - interval '1 day'
+		GROUP BY forum ORDER BY recent DESC LIMIT $1) AS p
+	JOIN forums AS f ON f.slug = p.forum ORDER BY p.recent DESC, f.slug`
+
+// windowCountQuery counts rows of table created within the last hour, day and week
+const windowCountQuery = `SELECT
+	COUNT(*) FILTER (WHERE created > now() - interval '1 hour'),
+	COUNT(*) FILTER (WHERE created > now() - interval '1 day'),
+	COUNT(*)
+	FROM %s WHERE created > now() - interval '1 week'`
+
+func (s *ServiceRepo) GetExtendedStatus(forumsLimit int32) (*entity.ExtendedStatus, error) {
+	status := &entity.ExtendedStatus{}
+	err := s.db.QueryRow(context.Background(), GetTotalsFromCountersQuery).Scan(
+		&status.User, &status.Forum, &status.Thread, &status.Post)
+	if err != nil {
+		return nil, err
+	}
+
+	status.Forums, err = s.queryForumsStatus(GetForumsStatusQuery, forumsLimit, false)
+	if err != nil {
+		return nil, err
+	}
+	status.TopForums, err = s.queryForumsStatus(GetTopForumsQuery, forumsLimit, true)
+	if err != nil {
+		return nil, err
+	}
+
+	status.Windows = []entity.WindowStatus{{Window: "hour"}, {Window: "day"}, {Window: "week"}}
+	tables := []struct {
+		name   string
+		target func(window *entity.WindowStatus) *int
+	}{
+		{"users", func(window *entity.WindowStatus) *int { return &window.User }},
+		{"threads", func(window *entity.WindowStatus) *int { return &window.Thread }},
+		{"posts", func(window *entity.WindowStatus) *int { return &window.Post }},
+	}
+	for _, table := range tables {
+		err = s.db.QueryRow(context.Background(), fmt.Sprintf(windowCountQuery, table.name)).Scan(
+			table.target(&status.Windows[0]), table.target(&status.Windows[1]), table.target(&status.Windows[2]))
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	stat := s.db.Stat()
+	status.Pool = entity.PoolStatus{
+		TotalConns:           stat.TotalConns(),
+		IdleConns:            stat.IdleConns(),
+		AcquiredConns:        stat.AcquiredConns(),
+		ConstructingConns:    stat.ConstructingConns(),
+		MaxConns:             stat.MaxConns(),
+		AcquireCount:         stat.AcquireCount(),
+		EmptyAcquireCount:    stat.EmptyAcquireCount(),
+		CanceledAcquireCount: stat.CanceledAcquireCount(),
+		AcquireDuration:      stat.AcquireDuration().String(),
+	}
+	return status, nil
+}
+
+func (s *ServiceRepo) queryForumsStatus(query string, limit int32, withRecent bool) ([]entity.ForumStatus, error) {
+	rows, err := s.db.Query(context.Background(), query, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	forums := make([]entity.ForumStatus, 0, limit)
+	for rows.Next() {
+		forum := entity.ForumStatus{}
+		dest := []interface{}{&forum.Slug, &forum.Title, &forum.Threads, &forum.Posts}
+		if withRecent {
+			dest = append(dest, &forum.Recent)
+		}
+		err = rows.Scan(dest...)
+		if err != nil {
+			return nil, err
+		}
+		forums = append(forums, forum)
+	}
+	return forums, rows.Err()
+}
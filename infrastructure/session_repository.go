@@ -0,0 +1,53 @@
+package infrastructure
+
+import (
+	"context"
+	"forum/domain/entity"
+	"github.com/jackc/pgx/v4"
+	"github.com/jackc/pgx/v4/pgxpool"
+)
+
+type SessionRepo struct {
+	db *pgxpool.Pool
+}
+
+func NewSessionRepository(db *pgxpool.Pool) *SessionRepo {
+	return &SessionRepo{db: db}
+}
+
+const DeleteExpiredSessionsQuery = `DELETE FROM sessions WHERE user_id = $1 AND expires <= now()`
+const CreateSessionQuery = `INSERT INTO sessions (token_hash, user_id, expires) VALUES ($1, $2, $3)`
+
+// CreateSession also drops expired sessions of the user
+func (s *SessionRepo) CreateSession(tokenHash string, session *entity.Session) error {
+	_, err := s.db.Exec(context.Background(), DeleteExpiredSessionsQuery, session.UserID)
+	if err != nil {
+		return err
+	}
+	_, err = s.db.Exec(context.Background(), CreateSessionQuery, tokenHash, session.UserID, session.Expires)
+	return err
+}
+
+const GetSessionQuery = `SELECT s.user_id, u.nickname, s.expires FROM sessions AS s
+	JOIN users AS u ON u.id = s.user_id
+	WHERE s.token_hash = $1 AND s.expires > now()`
+
+func (s *SessionRepo) GetSession(tokenHash string) (*entity.Session, error) {
+	session := &entity.Session{}
+	err := s.db.QueryRow(context.Background(), GetSessionQuery, tokenHash).Scan(
+		&session.UserID, &session.Nickname, &session.Expires)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return nil, entity.SessionNotFoundError
+		}
+		return nil, err
+	}
+	return session, nil
+}
+
+const DeleteSessionQuery = `DELETE FROM sessions WHERE token_hash = $1`
+
+func (s *SessionRepo) DeleteSession(tokenHash string) error {
+	_, err := s.db.Exec(context.Background(), DeleteSessionQuery, tokenHash)
+	return err
+}
@@ -0,0 +1,43 @@
+package infrastructure
+
+import (
+	"bytes"
+	"fmt"
+	"forum/domain/entity"
+	"mime"
+	"net"
+	"net/smtp"
+	"time"
+)
+
+// SMTPMailer sends mail through SMTP server, auth is used only when user is set
+type SMTPMailer struct {
+	addr string
+	auth smtp.Auth
+	from string
+}
+
+func NewSMTPMailer(host, port, user, password, from string) *SMTPMailer {
+	mailer := &SMTPMailer{addr: net.JoinHostPort(host, port), from: from}
+	if user != "" {
+		mailer.auth = smtp.PlainAuth("", user, password, host)
+	}
+	return mailer
+}
+
+func (m *SMTPMailer) Send(mail *entity.Mail) error {
+	return smtp.SendMail(m.addr, m.auth, m.from, []string{mail.To}, formatMail(m.from, mail))
+}
+
+// formatMail builds plain text message with headers
+func formatMail(from string, mail *entity.Mail) []byte {
+	message := &bytes.Buffer{}
+	fmt.Fprintf(message, "From: %v\r\n", from)
+	fmt.Fprintf(message, "To: %v\r\n", mail.To)
+	fmt.Fprintf(message, "Subject: %v\r\n", mime.QEncoding.Encode("utf-8", mail.Subject))
+	fmt.Fprintf(message, "Date: %v\r\n", time.Now().Format(time.RFC1123Z))
+	message.WriteString("MIME-Version: 1.0\r\n")
+	message.WriteString("Content-Type: text/plain; charset=UTF-8\r\n\r\n")
+	message.WriteString(mail.Body)
+	return message.Bytes()
+}
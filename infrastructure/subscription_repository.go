@@ -0,0 +1,287 @@
+package infrastructure
+
+import (
+	"context"
+	"fmt"
+	"forum/domain/entity"
+	"github.com/jackc/pgx/v4"
+	"github.com/jackc/pgx/v4/pgxpool"
+)
+
+type SubscriptionRepo struct {
+	db *pgxpool.Pool
+}
+
+func NewSubscriptionRepository(db *pgxpool.Pool) *SubscriptionRepo {
+	return &SubscriptionRepo{db: db}
+}
+
+const SubscribeThreadQuery = `INSERT INTO subscriptions (nickname, thread_id) VALUES ($1, $2) ON CONFLICT DO NOTHING`
+
+func (s *SubscriptionRepo) SubscribeThread(nickname string, threadID int) error {
+	_, err := s.db.Exec(context.Background(), SubscribeThreadQuery, nickname, threadID)
+	return err
+}
+
+const SubscribeForumQuery = `INSERT INTO subscriptions (nickname, forum_slug) VALUES ($1, $2) ON CONFLICT DO NOTHING`
+
+func (s *SubscriptionRepo) SubscribeForum(nickname string, slug string) error {
+	_, err := s.db.Exec(context.Background(), SubscribeForumQuery, nickname, slug)
+	return err
+}
+
+const UnsubscribeThreadQuery = `DELETE FROM subscriptions WHERE nickname = $1 AND thread_id = $2`
+
+func (s *SubscriptionRepo) UnsubscribeThread(nickname string, threadID int) error {
+	_, err := s.db.Exec(context.Background(), UnsubscribeThreadQuery, nickname, threadID)
+	return err
+}
+
+const UnsubscribeForumQuery = `DELETE FROM subscriptions WHERE nickname = $1 AND forum_slug = $2`
+
+func (s *SubscriptionRepo) UnsubscribeForum(nickname string, slug string) error {
+	_, err := s.db.Exec(context.Background(), UnsubscribeForumQuery, nickname, slug)
+	return err
+}
+
+const GetSubscriptionsQuery = `SELECT COALESCE(thread_id, 0), COALESCE(forum_slug, ''), created FROM subscriptions
+	WHERE nickname = $1 ORDER BY created`
+
+func (s *SubscriptionRepo) GetSubscriptions(nickname string) ([]entity.Subscription, error) {
+	rows, err := s.db.Query(context.Background(), GetSubscriptionsQuery, nickname)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	subscriptions := make([]entity.Subscription, 0)
+	for rows.Next() {
+		subscription := entity.Subscription{}
+		err = rows.Scan(&subscription.Thread, &subscription.Forum, &subscription.Created)
+		if err != nil {
+			return nil, err
+		}
+		subscriptions = append(subscriptions, subscription)
+	}
+
+	return subscriptions, rows.Err()
+}
+
+// NotifyPostsQuery notifies subscribers of the thread or the forum of every post once,
+// authors are not notified of own posts and users are not notified of posts of users they blocked.
+// Thread and forum subscribers are joined separately so that each join uses its own index
+const NotifyPostsQuery = `INSERT INTO notifications (nickname, kind, forum, thread_id, post_id, author)
+	SELECT DISTINCT r.nickname, 'post', r.forum, r.thread, r.id, r.author FROM (
+		SELECT s.nickname, p.forum, p.thread, p.id, p.author FROM posts AS p
+		JOIN subscriptions AS s ON s.thread_id = p.thread
+		WHERE p.id = ANY($1) AND NOT p.isHeld AND NOT p.isDeleted
+		UNION ALL
+		SELECT s.nickname, p.forum, p.thread, p.id, p.author FROM posts AS p
+		JOIN subscriptions AS s ON s.forum_slug = p.forum
+		WHERE p.id = ANY($1) AND NOT p.isHeld AND NOT p.isDeleted
+	) AS r
+	WHERE r.nickname <> r.author
+	AND NOT EXISTS (SELECT 1 FROM blocks AS b WHERE b.blocker = r.nickname AND b.blocked = r.author)`
+
+// SubscribeFirstPostsAuthorsQuery subscribes authors to threads they posted to for the first time,
+// so users who unsubscribed are not subscribed again by their next post. Thread authors are subscribed
+// when the thread is created
+const SubscribeFirstPostsAuthorsQuery = `INSERT INTO subscriptions (nickname, thread_id)
+	SELECT DISTINCT p.author, p.thread FROM posts AS p
+	JOIN threads AS t ON t.id = p.thread
+	WHERE p.id = ANY($1) AND p.author <> t.author
+	AND NOT EXISTS (SELECT 1 FROM posts AS e WHERE e.thread = p.thread AND e.author = p.author AND e.id <> ALL($1))
+	ON CONFLICT DO NOTHING`
+
+// notifyPosts notifies subscribers of visible posts in the transaction that created or approved the posts
+func notifyPosts(tx pgx.Tx, postIDs []int) error {
+	_, err := tx.Exec(context.Background(), NotifyPostsQuery, postIDs)
+	return err
+}
+
+func subscribeFirstPostsAuthors(tx pgx.Tx, postIDs []int) error {
+	_, err := tx.Exec(context.Background(), SubscribeFirstPostsAuthorsQuery, postIDs)
+	return err
+}
+
+const NotifyThreadQuery = `INSERT INTO notifications (nickname, kind, forum, thread_id, author)
+	SELECT s.nickname, 'thread', t.forum, t.id, t.author FROM threads AS t
+	JOIN subscriptions AS s ON s.forum_slug = t.forum
+	WHERE t.id = $1 AND s.nickname <> t.author
+	AND NOT EXISTS (SELECT 1 FROM blocks AS b WHERE b.blocker = s.nickname AND b.blocked = t.author)`
+const SubscribeThreadAuthorQuery = `INSERT INTO subscriptions (nickname, thread_id)
+	SELECT author, id FROM threads WHERE id = $1
+	ON CONFLICT DO NOTHING`
+
+// notifyThread notifies subscribers of the forum and subscribes the author in the transaction that created the thread
+func notifyThread(tx pgx.Tx, threadID int) error {
+	_, err := tx.Exec(context.Background(), NotifyThreadQuery, threadID)
+	if err != nil {
+		return err
+	}
+	_, err = tx.Exec(context.Background(), SubscribeThreadAuthorQuery, threadID)
+	return err
+}
+
+const NotificationColumns = `n.id, n.kind, n.forum, n.thread_id, COALESCE(n.post_id, 0), n.author, n.created, n.is_read`
+
+func scanNotification(row pgx.Row, notification *entity.Notification) error {
+	return row.Scan(
+		&notification.ID,
+		&notification.Type,
+		&notification.Forum,
+		&notification.Thread,
+		&notification.Post,
+		&notification.Author,
+		&notification.Created,
+		&notification.IsRead)
+}
+
+// GetNotifications skips notifications of deleted posts and of users blocked after the notification was made
+func (s *SubscriptionRepo) GetNotifications(nickname string,
+	limit int32,
+	since string,
+	desc bool,
+	unread bool) ([]entity.Notification, error) {
+	order := "ASC"
+	compare := ">"
+	if desc {
+		order = "DESC"
+		compare = "<"
+	}
+
+	query := `SELECT ` + NotificationColumns + ` FROM notifications AS n
+		LEFT JOIN posts AS p ON p.id = n.post_id
+		WHERE n.nickname = $1 AND NOT COALESCE(p.isDeleted, FALSE)
+		AND n.author NOT IN (SELECT blocked FROM blocks WHERE blocker = $1)`
+	args := []interface{}{nickname}
+	if unread {
+		query += " AND NOT n.is_read"
+	}
+	if since != "" {
+		query += fmt.Sprintf(" AND n.id %v $2", compare)
+		args = append(args, since)
+	}
+
+	query += fmt.Sprintf(" ORDER BY n.id %v", order)
+	if limit != 0 {
+		query += fmt.Sprintf(" LIMIT %v", limit)
+	}
+
+	rows, err := s.db.Query(context.Background(), query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	notifications := make([]entity.Notification, 0, limit)
+	for rows.Next() {
+		notification := entity.Notification{}
+		err = scanNotification(rows, &notification)
+		if err != nil {
+			return nil, err
+		}
+		notifications = append(notifications, notification)
+	}
+
+	return notifications, rows.Err()
+}
+
+const ForEachNotificationQuery = `SELECT ` + NotificationColumns + ` FROM notifications AS n
+	WHERE n.nickname = $1 ORDER BY n.id`
+
+func (s *SubscriptionRepo) ForEachNotification(nickname string, fn func(notification *entity.Notification) error) error {
+	return forEachRow(s.db, func(rows pgx.Rows) error {
+		notification := &entity.Notification{}
+		err := scanNotification(rows, notification)
+		if err != nil {
+			return err
+		}
+		return fn(notification)
+	}, ForEachNotificationQuery, nickname)
+}
+
+const MarkAllNotificationsReadQuery = `UPDATE notifications SET is_read = TRUE WHERE nickname = $1 AND NOT is_read`
+const MarkNotificationsReadQuery = `UPDATE notifications SET is_read = TRUE
+	WHERE nickname = $1 AND id = ANY($2) AND NOT is_read`
+
+func (s *SubscriptionRepo) MarkNotificationsRead(nickname string, ids []int) error {
+	var err error
+	if len(ids) == 0 {
+		_, err = s.db.Exec(context.Background(), MarkAllNotificationsReadQuery, nickname)
+	} else {
+		_, err = s.db.Exec(context.Background(), MarkNotificationsReadQuery, nickname, ids)
+	}
+	return err
+}
+
+const GetNotificationSettingsQuery = `SELECT email_digest FROM users WHERE nickname = $1`
+
+func (s *SubscriptionRepo) GetNotificationSettings(nickname string) (*entity.NotificationSettings, error) {
+	settings := &entity.NotificationSettings{}
+	err := s.db.QueryRow(context.Background(), GetNotificationSettingsQuery, nickname).Scan(&settings.EmailDigest)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return nil, entity.UserDoesntExistsError
+		}
+		return nil, err
+	}
+	return settings, nil
+}
+
+const SetNotificationSettingsQuery = `UPDATE users SET email_digest = $2 WHERE nickname = $1`
+
+func (s *SubscriptionRepo) SetNotificationSettings(nickname string, settings *entity.NotificationSettings) error {
+	_, err := s.db.Exec(context.Background(), SetNotificationSettingsQuery, nickname, settings.EmailDigest)
+	return err
+}
+
+// GetPendingDigestsQuery selects unread and not yet mailed notifications of users with digests on and a verified email
+const GetPendingDigestsQuery = `SELECT u.nickname, u.email, ` + NotificationColumns + ` FROM notifications AS n
+	JOIN users AS u ON u.nickname = n.nickname
+	LEFT JOIN posts AS p ON p.id = n.post_id
+	WHERE u.email_digest AND u.email_verified AND NOT u.is_deleted
+	AND NOT n.is_read AND NOT n.digested AND NOT COALESCE(p.isDeleted, FALSE)
+	AND n.author NOT IN (SELECT blocked FROM blocks WHERE blocker = n.nickname)
+	ORDER BY u.nickname, n.id`
+
+func (s *SubscriptionRepo) GetPendingDigests() ([]entity.Digest, error) {
+	rows, err := s.db.Query(context.Background(), GetPendingDigestsQuery)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	digests := make([]entity.Digest, 0)
+	for rows.Next() {
+		var nickname, email string
+		notification := entity.Notification{}
+		err = rows.Scan(&nickname, &email,
+			&notification.ID,
+			&notification.Type,
+			&notification.Forum,
+			&notification.Thread,
+			&notification.Post,
+			&notification.Author,
+			&notification.Created,
+			&notification.IsRead)
+		if err != nil {
+			return nil, err
+		}
+
+		if len(digests) == 0 || digests[len(digests)-1].Nickname != nickname {
+			digests = append(digests, entity.Digest{Nickname: nickname, Email: email})
+		}
+		last := &digests[len(digests)-1]
+		last.Notifications = append(last.Notifications, notification)
+	}
+
+	return digests, rows.Err()
+}
+
+const MarkDigestedQuery = `UPDATE notifications SET digested = TRUE WHERE id = ANY($1)`
+
+func (s *SubscriptionRepo) MarkDigested(ids []int) error {
+	_, err := s.db.Exec(context.Background(), MarkDigestedQuery, ids)
+	return err
+}
@@ -23,11 +23,19 @@ const UpdatePostsCountQuery = `UPDATE forums SET post_count = post_count + $1 WH
 const GetThreadFromPostsQuery = `SELECT thread FROM posts WHERE id = $1`
 const SelectSlugFromThread = `SELECT forum FROM threads WHERE id = $1`
 
+// CreatePosts stores the posts with their quotes and mentions, notifies subscribers
+// and subscribes authors in one transaction
 func (t *ThreadRepo) CreatePosts(thread *entity.Thread, posts []entity.Post) error {
-	var CreatePostsQuery = `INSERT INTO posts(author, created, forum, msg, parent, thread) VALUES `
+	var CreatePostsQuery = `INSERT INTO posts(author, created, forum, msg, parent, thread, format, msg_html, isHeld) VALUES `
+	tx, err := t.db.Begin(context.Background())
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(context.Background())
+
 	if posts[0].Parent != 0 {
 		var parentThread int
-		err := t.db.QueryRow(context.Background(), GetThreadFromPostsQuery, posts[0].Parent).Scan(&parentThread)
+		err := tx.QueryRow(context.Background(), GetThreadFromPostsQuery, posts[0].Parent).Scan(&parentThread)
 
 		if err != nil {
 			return err
@@ -38,6 +46,11 @@ func (t *ThreadRepo) CreatePosts(thread *entity.Thread, posts []entity.Post) err
 		}
 	}
 
+	quoted, err := getQuotedPosts(tx, posts)
+	if err != nil {
+		return err
+	}
+
 	var postArray []interface{}
 	created := strfmt.DateTime(time.Now())
 
@@ -47,16 +60,17 @@ func (t *ThreadRepo) CreatePosts(thread *entity.Thread, posts []entity.Post) err
 		posts[i].Created = created
 
 		CreatePostsQuery += fmt.Sprintf(
-			"($%d, $%d, $%d, $%d, $%d, $%d),",
-			i*6+1, i*6+2, i*6+3, i*6+4, i*6+5, i*6+6,
+			"($%d, $%d, $%d, $%d, $%d, $%d, $%d, NULLIF($%d, ''), $%d),",
+			i*9+1, i*9+2, i*9+3, i*9+4, i*9+5, i*9+6, i*9+7, i*9+8, i*9+9,
 		)
 
-		postArray = append(postArray, post.Author, created, thread.Forum, post.Message, post.Parent, thread.ID)
+		postArray = append(postArray, post.Author, created, thread.Forum, post.Message, post.Parent, thread.ID,
+			post.Format, post.MessageHTML, post.IsHeld)
 	}
 
 	CreatePostsQuery = CreatePostsQuery[:len(CreatePostsQuery)-1]
 	CreatePostsQuery += ` RETURNING id`
-	rows, err := t.db.Query(context.Background(), CreatePostsQuery, postArray...)
+	rows, err := tx.Query(context.Background(), CreatePostsQuery, postArray...)
 	if err != nil {
 		return err
 	}
@@ -70,23 +84,145 @@ func (t *ThreadRepo) CreatePosts(thread *entity.Thread, posts []entity.Post) err
 
 		idx++
 	}
+	rows.Close()
+	if rows.Err() != nil {
+		return rows.Err()
+	}
 
-	return nil
+	if len(quoted) != 0 {
+		err = saveQuotes(tx, posts, quoted)
+		if err != nil {
+			return err
+		}
+	}
+	err = saveMentions(tx, posts)
+	if err != nil {
+		return err
+	}
+
+	postIDs := make([]int, 0, len(posts))
+	for _, post := range posts {
+		postIDs = append(postIDs, post.ID)
+	}
+	err = notifyPosts(tx, postIDs)
+	if err != nil {
+		return err
+	}
+	err = subscribeFirstPostsAuthors(tx, postIDs)
+	if err != nil {
+		return err
+	}
+	return tx.Commit(context.Background())
 }
 
-const CreateThreadQuery = `INSERT INTO threads (author, created, forum, msg, title, slug)
-	VALUES ($1, $2, $3, $4, $5, $6) RETURNING id`
+const GetQuotedPostsQuery = `SELECT id, author, thread, forum FROM posts WHERE id = ANY($1)`
+
+// getQuotedPosts loads all posts quoted by new posts, quoted posts may belong to any thread
+func getQuotedPosts(tx pgx.Tx, posts []entity.Post) (map[int]entity.PostQuote, error) {
+	ids := make([]int, 0)
+	for _, post := range posts {
+		for _, quote := range post.Quotes {
+			ids = append(ids, quote.ID)
+		}
+	}
+
+	quoted := make(map[int]entity.PostQuote, len(ids))
+	if len(ids) == 0 {
+		return quoted, nil
+	}
 
+	rows, err := tx.Query(context.Background(), GetQuotedPostsQuery, ids)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		quote := entity.PostQuote{}
+		err = rows.Scan(&quote.ID, &quote.Author, &quote.Thread, &quote.Forum)
+		if err != nil {
+			return nil, err
+		}
+		quoted[quote.ID] = quote
+	}
+	if rows.Err() != nil {
+		return nil, rows.Err()
+	}
+
+	for _, id := range ids {
+		if _, ok := quoted[id]; !ok {
+			return nil, entity.QuotedPostNotFoundError
+		}
+	}
+	return quoted, nil
+}
+
+const SaveQuotesQuery = `INSERT INTO post_quotes (post_id, quoted_id, position)
+	SELECT * FROM unnest($1::int[], $2::int[], $3::int[]) ON CONFLICT DO NOTHING`
+
+// saveQuotes stores quote references of created posts and fills quoted posts info
+func saveQuotes(tx pgx.Tx, posts []entity.Post, quoted map[int]entity.PostQuote) error {
+	postIDs := make([]int, 0)
+	quotedIDs := make([]int, 0)
+	positions := make([]int, 0)
+	for i := range posts {
+		for j, quote := range posts[i].Quotes {
+			postIDs = append(postIDs, posts[i].ID)
+			quotedIDs = append(quotedIDs, quote.ID)
+			positions = append(positions, j)
+			posts[i].Quotes[j] = quoted[quote.ID]
+		}
+	}
+
+	_, err := tx.Exec(context.Background(), SaveQuotesQuery, postIDs, quotedIDs, positions)
+	return err
+}
+
+// ThreadColumns is the list of threads columns scanned by scanThread
+const ThreadColumns = `author, created, forum, id, msg, slug, title, votes, format, COALESCE(msg_html, '')`
+
+func scanThread(row pgx.Row, thread *entity.Thread) error {
+	return row.Scan(
+		&thread.Author,
+		&thread.Created,
+		&thread.Forum,
+		&thread.ID,
+		&thread.Message,
+		&thread.Slug,
+		&thread.Title,
+		&thread.Votes,
+		&thread.Format,
+		&thread.MessageHTML)
+}
+
+const CreateThreadQuery = `INSERT INTO threads (author, created, forum, msg, title, slug, format, msg_html)
+	VALUES ($1, $2, $3, $4, $5, $6, $7, NULLIF($8, '')) RETURNING id`
+
+// CreateThread stores the thread, notifies subscribers of the forum and subscribes the author in one transaction
 func (t *ThreadRepo) CreateThread(thread *entity.Thread) error {
-	err := t.db.QueryRow(context.Background(), CreateThreadQuery,
+	tx, err := t.db.Begin(context.Background())
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(context.Background())
+
+	err = tx.QueryRow(context.Background(), CreateThreadQuery,
 		thread.Author, thread.Created, thread.Forum, thread.Message, thread.Title, thread.Slug,
+		thread.Format, thread.MessageHTML,
 	).Scan(&thread.ID)
 
 	if err != nil {
+		if isUniqueViolation(err) {
+			return entity.SlugExistsError
+		}
 		return err
 	}
 
-	return nil
+	err = notifyThread(tx, thread.ID)
+	if err != nil {
+		return err
+	}
+	return tx.Commit(context.Background())
 }
 
 func (t *ThreadRepo) GetThreadPosts(slug string, limit int32, since string, order string) ([]entity.Post, error) {
@@ -107,31 +243,15 @@ func (t *ThreadRepo) GetThreadPosts(slug string, limit int32, since string, orde
 		}
 	}
 
-	query := fmt.Sprintf(`SELECT author, created, forum, id, msg, parent, thread FROM posts
-	WHERE thread = $1 %v
-	ORDER BY id %v`, sinceQuery, order)
+	query := fmt.Sprintf(`SELECT %s FROM posts
+	WHERE thread = $1 AND NOT isHeld %v
+	ORDER BY id %v`, PostColumns, sinceQuery, order)
 
 	if limit != 0 {
 		query += fmt.Sprintf(" LIMIT %v", limit)
 	}
 
-	rows, err := t.db.Query(context.Background(), query, threadID)
-	if err != nil {
-		return nil, err
-	}
-	defer rows.Close()
-
-	posts := make([]entity.Post, 0, limit)
-	for rows.Next() {
-		post := entity.Post{}
-		err = rows.Scan(&post.Author, &post.Created, &post.Forum, &post.ID, &post.Message, &post.Parent, &post.Thread)
-		if err != nil {
-			return nil, err
-		}
-		posts = append(posts, post)
-	}
-
-	return posts, nil
+	return queryPosts(t.db, limit, query, threadID)
 }
 
 func (t *ThreadRepo) GetThreadPostsTree(slug string, limit int32, since string, order string) ([]entity.Post, error) {
@@ -154,41 +274,25 @@ func (t *ThreadRepo) GetThreadPostsTree(slug string, limit int32, since string,
 
 	if since == "" {
 		if desc {
-			query = fmt.Sprintf(`SELECT author, created, forum, id, msg, parent, thread FROM posts
-				WHERE thread = %d ORDER BY path DESC, id  DESC LIMIT %d;`, threadID, limit)
+			query = fmt.Sprintf(`SELECT %s FROM posts
+				WHERE thread = %d AND NOT isHeld ORDER BY path DESC, id  DESC LIMIT %d;`, PostColumns, threadID, limit)
 		} else {
-			query = fmt.Sprintf(`SELECT author, created, forum, id, msg, parent, thread FROM posts
-				WHERE thread = %d ORDER BY path ASC, id  ASC LIMIT %d;`, threadID, limit)
+			query = fmt.Sprintf(`SELECT %s FROM posts
+				WHERE thread = %d AND NOT isHeld ORDER BY path ASC, id  ASC LIMIT %d;`, PostColumns, threadID, limit)
 		}
 	} else {
 		if desc {
-			query = fmt.Sprintf(`SELECT author, created, forum, id, msg, parent, thread FROM posts
-				WHERE thread = %d AND path < (SELECT path FROM posts WHERE id = %s)
-				ORDER BY path DESC, id  DESC LIMIT %d;`, threadID, since, limit)
+			query = fmt.Sprintf(`SELECT %s FROM posts
+				WHERE thread = %d AND NOT isHeld AND path < (SELECT path FROM posts WHERE id = %s)
+				ORDER BY path DESC, id  DESC LIMIT %d;`, PostColumns, threadID, since, limit)
 		} else {
-			query = fmt.Sprintf(`SELECT author, created, forum, id, msg, parent, thread FROM posts
-				WHERE thread = %d AND path > (SELECT path FROM posts WHERE id = %s)
-				ORDER BY path ASC, id  ASC LIMIT %d;`, threadID, since, limit)
+			query = fmt.Sprintf(`SELECT %s FROM posts
+				WHERE thread = %d AND NOT isHeld AND path > (SELECT path FROM posts WHERE id = %s)
+				ORDER BY path ASC, id  ASC LIMIT %d;`, PostColumns, threadID, since, limit)
 		}
 	}
 
-	rows, err := t.db.Query(context.Background(), query)
-	if err != nil {
-		return nil, err
-	}
-	defer rows.Close()
-
-	posts := make([]entity.Post, 0)
-	for rows.Next() {
-		post := entity.Post{}
-		err = rows.Scan(&post.Author, &post.Created, &post.Forum, &post.ID, &post.Message, &post.Parent, &post.Thread)
-		if err != nil {
-			return nil, err
-		}
-		posts = append(posts, post)
-	}
-
-	return posts, nil
+	return queryPosts(t.db, limit, query)
 }
 
 func (t *ThreadRepo) GetThreadPostsParentTree(slug string, limit int32, since string, order string) ([]entity.Post, error) {
@@ -210,46 +314,70 @@ func (t *ThreadRepo) GetThreadPostsParentTree(slug string, limit int32, since st
 	var query string
 	if since == "" {
 		if desc {
-			query = fmt.Sprintf(`SELECT author, created, forum, id, msg, parent, thread FROM posts
-				WHERE path[1] IN (SELECT id FROM posts WHERE thread = %d AND parent = 0 ORDER BY id DESC LIMIT %d)
-				ORDER BY path[1] DESC, path, id;`, threadID, limit)
+			query = fmt.Sprintf(`SELECT %s FROM posts
+				WHERE NOT isHeld AND path[1] IN (SELECT id FROM posts WHERE thread = %d AND NOT isHeld AND parent = 0 ORDER BY id DESC LIMIT %d)
+				ORDER BY path[1] DESC, path, id;`, PostColumns, threadID, limit)
 		} else {
-			query = fmt.Sprintf(`SELECT author, created, forum, id, msg, parent, thread FROM posts
-				WHERE path[1] IN (SELECT id FROM posts WHERE thread = %d AND parent = 0 ORDER BY id LIMIT %d)
-				ORDER BY path, id;`, threadID, limit)
+			query = fmt.Sprintf(`SELECT %s FROM posts
+				WHERE NOT isHeld AND path[1] IN (SELECT id FROM posts WHERE thread = %d AND NOT isHeld AND parent = 0 ORDER BY id LIMIT %d)
+				ORDER BY path, id;`, PostColumns, threadID, limit)
 		}
 	} else {
 		if desc {
-			query = fmt.Sprintf(`SELECT author, created, forum, id, msg, parent, thread FROM posts
-				WHERE path[1] IN (SELECT id FROM posts WHERE thread = %d AND parent = 0 AND path[1] <
+			query = fmt.Sprintf(`SELECT %s FROM posts
+				WHERE NOT isHeld AND path[1] IN (SELECT id FROM posts WHERE thread = %d AND NOT isHeld AND parent = 0 AND path[1] <
 				(SELECT path[1] FROM posts WHERE id = %s) ORDER BY id DESC LIMIT %d) ORDER BY path[1] DESC, path, id;`,
-				threadID, since, limit)
+				PostColumns, threadID, since, limit)
 		} else {
-			query = fmt.Sprintf(`SELECT author, created, forum, id, msg, parent, thread FROM posts
-				WHERE path[1] IN (SELECT id FROM posts WHERE thread = %d AND parent = 0 AND path[1] >
+			query = fmt.Sprintf(`SELECT %s FROM posts
+				WHERE NOT isHeld AND path[1] IN (SELECT id FROM posts WHERE thread = %d AND NOT isHeld AND parent = 0 AND path[1] >
 				(SELECT path[1] FROM posts WHERE id = %s) ORDER BY id ASC LIMIT %d) ORDER BY path, id;`,
-				threadID, since, limit)
+				PostColumns, threadID, since, limit)
 		}
 	}
 
-	rows, err := t.db.Query(context.Background(), query)
+	return queryPosts(t.db, limit, query)
+}
+
+// GetThreadPostsTop orders posts by votes, ties are broken by id.
+// since is id of the last post of previous page
+func (t *ThreadRepo) GetThreadPostsTop(slug string, limit int32, since string, order string) ([]entity.Post, error) {
+	threadID, err := strconv.Atoi(slug)
 	if err != nil {
-		return nil, err
+		threadID, err = t.CheckThreadBySlug(slug)
+		if err != nil {
+			return nil, err
+		}
 	}
 
-	defer rows.Close()
+	compare := "<"
+	if order == "ASC" {
+		compare = ">"
+	}
 
-	posts := make([]entity.Post, 0)
-	for rows.Next() {
-		post := entity.Post{}
-		err = rows.Scan(&post.Author, &post.Created, &post.Forum, &post.ID, &post.Message, &post.Parent, &post.Thread)
+	var query string
+	var args []interface{}
+	if since == "" {
+		query = fmt.Sprintf(`SELECT %s FROM posts WHERE thread = $1 AND NOT isHeld
+			ORDER BY votes %v, id`, PostColumns, order)
+		args = []interface{}{threadID}
+	} else {
+		sinceID, err := strconv.Atoi(since)
 		if err != nil {
 			return nil, err
 		}
-		posts = append(posts, post)
+		query = fmt.Sprintf(`SELECT %s FROM posts WHERE thread = $1 AND NOT isHeld AND (
+			votes %v (SELECT votes FROM posts WHERE id = $2) OR
+			(votes = (SELECT votes FROM posts WHERE id = $2) AND id > $2))
+			ORDER BY votes %v, id`, PostColumns, compare, order)
+		args = []interface{}{threadID, sinceID}
 	}
 
-	return posts, nil
+	if limit != 0 {
+		query += fmt.Sprintf(" LIMIT %v", limit)
+	}
+
+	return queryPosts(t.db, limit, query, args...)
 }
 
 const CheckThreadBySlugQuery = `SELECT id FROM threads WHERE slug = $1`
@@ -297,7 +425,7 @@ func (t *ThreadRepo) GetThreadForumAndID(slugOrID string) (*entity.Thread, error
 }
 
 func (t *ThreadRepo) GetThreadsByForumSlug(slug string, limit int32, since string, desc bool) ([]entity.Thread, error) {
-	var GetThreadsByForumSlugQuery = `SELECT author, created, forum, id, msg, slug, title, votes FROM threads WHERE forum = $1`
+	var GetThreadsByForumSlugQuery = `SELECT ` + ThreadColumns + ` FROM threads WHERE forum = $1`
 	order := "ASC"
 	var compare string
 	if desc == false {
@@ -328,7 +456,7 @@ func (t *ThreadRepo) GetThreadsByForumSlug(slug string, limit int32, since strin
 	threads := make([]entity.Thread, 0, limit)
 	for rows.Next() {
 		thread := entity.Thread{}
-		err = rows.Scan(&thread.Author, &thread.Created, &thread.Forum, &thread.ID, &thread.Message, &thread.Slug, &thread.Title, &thread.Votes)
+		err = scanThread(rows, &thread)
 		if err != nil {
 			return nil, err
 		}
@@ -338,9 +466,66 @@ func (t *ThreadRepo) GetThreadsByForumSlug(slug string, limit int32, since strin
 	return threads, nil
 }
 
+// GetUserThreads lists threads of the user paginated by creation time like forum threads
+func (t *ThreadRepo) GetUserThreads(nickname string, limit int32, since string, desc bool) ([]entity.Thread, error) {
+	order := "ASC"
+	compare := ">"
+	if desc {
+		order = "DESC"
+		compare = "<"
+	}
+
+	query := `SELECT ` + ThreadColumns + ` FROM threads WHERE author = $1`
+	args := []interface{}{nickname}
+	if since != "" {
+		query += fmt.Sprintf(" AND created %v= $2", compare)
+		args = append(args, since)
+	}
+
+	query += fmt.Sprintf(" ORDER BY created %v, id %v", order, order)
+	if limit != 0 {
+		query += fmt.Sprintf(" LIMIT %v", limit)
+	}
+
+	rows, err := t.db.Query(context.Background(), query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	threads := make([]entity.Thread, 0, limit)
+	for rows.Next() {
+		thread := entity.Thread{}
+		err = scanThread(rows, &thread)
+		if err != nil {
+			return nil, err
+		}
+		threads = append(threads, thread)
+	}
+
+	return threads, rows.Err()
+}
+
+const ForEachUserThreadQuery = `SELECT ` + ThreadColumns + ` FROM threads WHERE author = $1 ORDER BY id`
+
+func (t *ThreadRepo) ForEachUserThread(nickname string, fn func(thread *entity.Thread) error) error {
+	return forEachRow(t.db, func(rows pgx.Rows) error {
+		thread := &entity.Thread{}
+		err := scanThread(rows, thread)
+		if err != nil {
+			return err
+		}
+		return fn(thread)
+	}, ForEachUserThreadQuery, nickname)
+}
+
 const GetVoteQuery = `SELECT vote FROM thread_vote WHERE nickname = $1 AND thread_id = $2`
-const InsertVoteQuery = `INSERT INTO thread_vote (nickname, thread_id, vote) VALUES($1, $2, $3)`
-const UpdateVoteQuery = `UPDATE thread_vote SET vote = $1 WHERE nickname = $2 AND thread_id = $3`
+
+// InsertVoteQuery overwrites a vote inserted concurrently by the same user, the update trigger then fixes thread votes
+const InsertVoteQuery = `INSERT INTO thread_vote (nickname, thread_id, vote) VALUES($1, $2, $3)
+	ON CONFLICT (nickname, thread_id) DO UPDATE SET vote = EXCLUDED.vote, created = now()`
+const UpdateVoteQuery = `UPDATE thread_vote SET vote = $1, created = now() WHERE nickname = $2 AND thread_id = $3`
+const DeleteVoteQuery = `DELETE FROM thread_vote WHERE nickname = $1 AND thread_id = $2`
 
 func (t *ThreadRepo) VoteForThread(vote *entity.Vote) (*entity.Thread, error) {
 	thread := &entity.Thread{}
@@ -363,6 +548,9 @@ func (t *ThreadRepo) VoteForThread(vote *entity.Vote) (*entity.Thread, error) {
 	}
 
 	if err == pgx.ErrNoRows {
+		if vote.Voice == 0 {
+			return thread, nil
+		}
 		_, err = t.db.Exec(context.Background(), InsertVoteQuery, vote.Nickname, thread.ID, vote.Voice)
 
 		if err != nil {
@@ -379,26 +567,81 @@ func (t *ThreadRepo) VoteForThread(vote *entity.Vote) (*entity.Thread, error) {
 
 	thread.Votes = thread.Votes - voteValue + vote.Voice
 
-	_, err = t.db.Exec(context.Background(), UpdateVoteQuery, vote.Voice, vote.Nickname, thread.ID)
+	if vote.Voice == 0 {
+		_, err = t.db.Exec(context.Background(), DeleteVoteQuery, vote.Nickname, thread.ID)
+	} else {
+		_, err = t.db.Exec(context.Background(), UpdateVoteQuery, vote.Voice, vote.Nickname, thread.ID)
+	}
 	if err != nil {
 		return nil, err
 	}
 	return thread, nil
 }
 
-const GetThreadBySlugQuery = `SELECT author, created, forum, id, msg, slug, title, votes FROM threads WHERE slug = $1`
+func (t *ThreadRepo) GetThreadVotes(threadID int, query *entity.VoteQuery) ([]entity.VoteRecord, error) {
+	sql := `SELECT nickname, vote, thread, post, created FROM (
+		SELECT nickname, vote, thread_id AS thread, 0 AS post, created FROM thread_vote WHERE thread_id = $1) AS votes
+		WHERE TRUE`
+	args := []interface{}{threadID}
+	sql += votesPageClause(query, &args)
+
+	return queryVoteRecords(t.db, query.Limit, sql, args...)
+}
+
+// votesPageClause appends page condition, order and limit shared by vote listings. Votes are ordered
+// by the unique key (created, nickname, thread, post), so that votes with the same created time
+// are neither repeated nor skipped between pages
+func votesPageClause(query *entity.VoteQuery, args *[]interface{}) string {
+	order := "ASC"
+	compare := ">"
+	if query.Desc {
+		order = "DESC"
+		compare = "<"
+	}
+
+	var clause string
+	if query.After != nil {
+		*args = append(*args, time.Time(query.After.Created), query.After.Nickname, query.After.Thread, query.After.Post)
+		n := len(*args)
+		clause += fmt.Sprintf(" AND (created, nickname, thread, post) %v ($%d::timestamptz, $%d::citext, $%d::int, $%d::int)",
+			compare, n-3, n-2, n-1, n)
+	} else if query.Since != nil {
+		*args = append(*args, *query.Since)
+		clause += fmt.Sprintf(" AND created %v= $%d", compare, len(*args))
+	}
+
+	clause += fmt.Sprintf(" ORDER BY created %v, nickname %v, thread %v, post %v", order, order, order, order)
+	if query.Limit != 0 {
+		clause += fmt.Sprintf(" LIMIT %v", query.Limit)
+	}
+	return clause
+}
+
+func queryVoteRecords(db *pgxpool.Pool, limit int32, query string, args ...interface{}) ([]entity.VoteRecord, error) {
+	rows, err := db.Query(context.Background(), query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	votes := make([]entity.VoteRecord, 0, limit)
+	for rows.Next() {
+		vote := entity.VoteRecord{}
+		err = rows.Scan(&vote.Nickname, &vote.Voice, &vote.Thread, &vote.Post, &vote.Created)
+		if err != nil {
+			return nil, err
+		}
+		votes = append(votes, vote)
+	}
+
+	return votes, rows.Err()
+}
+
+const GetThreadBySlugQuery = `SELECT ` + ThreadColumns + ` FROM threads WHERE slug = $1`
 
 func (t *ThreadRepo) GetThreadBySlug(slug string) (*entity.Thread, error) {
 	thread := &entity.Thread{}
-	err := t.db.QueryRow(context.Background(), GetThreadBySlugQuery, slug).Scan(
-		&thread.Author,
-		&thread.Created,
-		&thread.Forum,
-		&thread.ID,
-		&thread.Message,
-		&thread.Slug,
-		&thread.Title,
-		&thread.Votes)
+	err := scanThread(t.db.QueryRow(context.Background(), GetThreadBySlugQuery, slug), thread)
 
 	if err != nil {
 		return nil, err
@@ -406,19 +649,11 @@ func (t *ThreadRepo) GetThreadBySlug(slug string) (*entity.Thread, error) {
 	return thread, nil
 }
 
-const GetThreadByIDQuery = `SELECT author, created, forum, id, msg, slug, title, votes FROM threads WHERE id = $1`
+const GetThreadByIDQuery = `SELECT ` + ThreadColumns + ` FROM threads WHERE id = $1`
 
 func (t *ThreadRepo) GetThreadByID(ID int) (*entity.Thread, error) {
 	thread := &entity.Thread{}
-	err := t.db.QueryRow(context.Background(), GetThreadByIDQuery, ID).Scan(
-		&thread.Author,
-		&thread.Created,
-		&thread.Forum,
-		&thread.ID,
-		&thread.Message,
-		&thread.Slug,
-		&thread.Title,
-		&thread.Votes)
+	err := scanThread(t.db.QueryRow(context.Background(), GetThreadByIDQuery, ID), thread)
 
 	if err != nil {
 		return nil, err
@@ -426,11 +661,12 @@ func (t *ThreadRepo) GetThreadByID(ID int) (*entity.Thread, error) {
 	return thread, nil
 }
 
-const UpdateThreadQuery = `UPDATE threads SET title = $1, msg = $2
-		WHERE slug = $3 OR id = $4
-		RETURNING author, created, forum, id, msg, slug, title`
+// UpdateThreadQuery replaces cached html along with the message
+const UpdateThreadQuery = `UPDATE threads SET title = $1, msg = $2, format = $3, msg_html = NULLIF($4, '')
+		WHERE slug = $5 OR id = $6
+		RETURNING ` + ThreadColumns
 
-func (t *ThreadRepo) UpdateThread(thread *entity.Thread) error {
+func (t *ThreadRepo) UpdateThread(thread *entity.Thread, audit *entity.AuditEntry) error {
 	if thread.Title == "" || thread.Message == "" {
 		oldThread := &entity.Thread{}
 		var err error
@@ -450,16 +686,145 @@ func (t *ThreadRepo) UpdateThread(thread *entity.Thread) error {
 
 		if thread.Message == "" {
 			thread.Message = oldThread.Message
+			thread.Format = oldThread.Format
+			thread.MessageHTML = oldThread.MessageHTML
 		}
 	}
 
-	err := t.db.QueryRow(context.Background(), UpdateThreadQuery,
-		thread.Title, thread.Message, thread.Slug, thread.ID,
-	).Scan(&thread.Author, &thread.Created, &thread.Forum, &thread.ID, &thread.Message, &thread.Slug, &thread.Title)
+	tx, err := t.db.Begin(context.Background())
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(context.Background())
+
+	err = scanThread(tx.QueryRow(context.Background(), UpdateThreadQuery,
+		thread.Title, thread.Message, thread.Format, thread.MessageHTML, thread.Slug, thread.ID,
+	), thread)
+	if err != nil {
+		return err
+	}
 
+	err = addAuditEntry(tx, audit)
 	if err != nil {
 		return err
 	}
+	return tx.Commit(context.Background())
+}
+
+const GetThreadSlugsWithPrefixQuery = `SELECT slug FROM threads WHERE slug = $1 OR slug LIKE $1 || '-%'`
 
+func (t *ThreadRepo) GetSlugsWithPrefix(prefix string) ([]string, error) {
+	return getSlugsWithPrefix(t.db, GetThreadSlugsWithPrefixQuery, prefix)
+}
+
+// getSlugsWithPrefix collects slugs returned by query, used to pick free slug suffix
+func getSlugsWithPrefix(db *pgxpool.Pool, query string, prefix string) ([]string, error) {
+	rows, err := db.Query(context.Background(), query, prefix)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	slugs := make([]string, 0)
+	for rows.Next() {
+		var slug string
+		err = rows.Scan(&slug)
+		if err != nil {
+			return nil, err
+		}
+		slugs = append(slugs, slug)
+	}
+
+	return slugs, rows.Err()
+}
+
+const MarkThreadReadQuery = `INSERT INTO thread_reads (nickname, thread_id, last_read_post)
+	SELECT $1, thread, id FROM posts WHERE id = $3 AND thread = $2
+	ON CONFLICT (nickname, thread_id) DO UPDATE
+	SET last_read_post = GREATEST(thread_reads.last_read_post, EXCLUDED.last_read_post), updated = now()`
+const MarkThreadReadAllQuery = `INSERT INTO thread_reads (nickname, thread_id, last_read_post)
+	SELECT $1, $2, COALESCE(MAX(id), 0) FROM posts WHERE thread = $2
+	ON CONFLICT (nickname, thread_id) DO UPDATE
+	SET last_read_post = GREATEST(thread_reads.last_read_post, EXCLUDED.last_read_post), updated = now()`
+
+func (t *ThreadRepo) MarkThreadRead(nickname string, threadID int, postID int) error {
+	if postID == 0 {
+		_, err := t.db.Exec(context.Background(), MarkThreadReadAllQuery, nickname, threadID)
+		return err
+	}
+
+	tag, err := t.db.Exec(context.Background(), MarkThreadReadQuery, nickname, threadID, postID)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return entity.PostNotInThreadError
+	}
 	return nil
 }
+
+// unreadPostsCondition selects posts of thread t.id unread by $1, own posts, deleted posts
+// and posts of blocked users are never unread
+const unreadPostsCondition = `p.thread = t.id AND p.id > COALESCE(r.last_read_post, 0)
+	AND NOT p.isHeld AND NOT p.isDeleted AND p.author <> $1
+	AND p.author NOT IN (SELECT blocked FROM blocks WHERE blocker = $1)`
+
+const GetUnreadCountsQuery = `SELECT t.id, (SELECT count(*) FROM posts AS p WHERE ` + unreadPostsCondition + `)
+	FROM unnest($2::int[]) AS t(id)
+	LEFT JOIN thread_reads AS r ON r.thread_id = t.id AND r.nickname = $1`
+
+func (t *ThreadRepo) GetUnreadCounts(nickname string, threadIDs []int) (map[int]int, error) {
+	rows, err := t.db.Query(context.Background(), GetUnreadCountsQuery, nickname, threadIDs)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	counts := make(map[int]int, len(threadIDs))
+	for rows.Next() {
+		var threadID, count int
+		err = rows.Scan(&threadID, &count)
+		if err != nil {
+			return nil, err
+		}
+		counts[threadID] = count
+	}
+
+	return counts, rows.Err()
+}
+
+const GetFirstUnreadPostQuery = `SELECT ` + PostColumns + ` FROM posts WHERE id = (
+	SELECT min(p.id) FROM (SELECT $2::int AS id) AS t
+	LEFT JOIN thread_reads AS r ON r.thread_id = t.id AND r.nickname = $1
+	JOIN posts AS p ON ` + unreadPostsCondition + `)`
+
+func (t *ThreadRepo) GetFirstUnreadPost(nickname string, threadID int) (*entity.Post, error) {
+	post := &entity.Post{}
+	err := scanPost(t.db.QueryRow(context.Background(), GetFirstUnreadPostQuery, nickname, threadID), post)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return nil, entity.NoUnreadPostsError
+		}
+		return nil, err
+	}
+	return post, nil
+}
+
+// postPositionQueries count posts shown before the post in ascending flat or tree order. Held posts are not
+// shown in threads, own, deleted and blocked posts are, so they are counted even though they are never unread
+var postPositionQueries = map[string]string{
+	"flat": `SELECT count(*) FROM posts WHERE thread = $1 AND NOT isHeld AND id < $2`,
+	"tree": `SELECT count(*) FROM posts WHERE thread = $1 AND NOT isHeld
+		AND path < (SELECT path FROM posts WHERE id = $2)`,
+}
+
+func (t *ThreadRepo) GetPostPosition(threadID int, postID int, sort string) (int, error) {
+	query, ok := postPositionQueries[sort]
+	if !ok {
+		return 0, entity.WrongUnreadSortError
+	}
+
+	var position int
+	err := t.db.QueryRow(context.Background(), query, threadID, postID).Scan(&position)
+	return position, err
+}
@@ -2,9 +2,13 @@ package infrastructure
 
 import (
 	"context"
+	"fmt"
 	"forum/domain/entity"
 	"github.com/jackc/pgx/v4"
 	"github.com/jackc/pgx/v4/pgxpool"
+	"sort"
+	"strings"
+	"time"
 )
 
 type UserRepo struct {
@@ -23,12 +27,14 @@ func emptyIfNil(input *string) *string {
 	return input
 }
 
-const CreateUserQuery = `INSERT INTO users (nickname, fullname, email, about) VALUES ($1, $2, $3, $4)`
+const CreateUserQuery = `INSERT INTO users (nickname, fullname, email, about, password_hash)
+	VALUES ($1, $2, $3, $4, NULLIF($5, ''))`
 
+// CreateUser stores user.Password as it is, it must be hashed already
 func (us *UserRepo) CreateUser(user *entity.User) error {
 	_, err := us.db.Exec(context.Background(),
 		CreateUserQuery,
-		user.Nickname, user.Fullname, user.Email, user.About,
+		user.Nickname, user.Fullname, user.Email, user.About, user.Password,
 	)
 
 	if err != nil {
@@ -38,7 +44,7 @@ func (us *UserRepo) CreateUser(user *entity.User) error {
 	return nil
 }
 
-const CheckUserExistQuery = `SELECT nickname FROM users WHERE nickname = $1`
+const CheckUserExistQuery = `SELECT nickname FROM users WHERE nickname = $1 AND NOT is_deleted`
 
 func (us *UserRepo) CheckIfUserExists(nickname string) (string, error) {
 	err := us.db.QueryRow(context.Background(), CheckUserExistQuery, nickname).Scan(&nickname)
@@ -48,7 +54,8 @@ func (us *UserRepo) CheckIfUserExists(nickname string) (string, error) {
 	return nickname, nil
 }
 
-const GetUserByNickname = `SELECT id, nickname, fullname, email, about FROM users WHERE nickname = $1`
+const GetUserByNickname = `SELECT id, nickname, fullname, email, about, is_deleted, email_verified
+	FROM users WHERE nickname = $1`
 
 func (us *UserRepo) GetUserByNickname(nickname string) (*entity.User, error) {
 	user := &entity.User{}
@@ -57,7 +64,9 @@ func (us *UserRepo) GetUserByNickname(nickname string) (*entity.User, error) {
 		&user.Nickname,
 		&user.Fullname,
 		&user.Email,
-		&user.About)
+		&user.About,
+		&user.IsDeleted,
+		&user.EmailVerified)
 
 	if err != nil {
 		if err == pgx.ErrNoRows {
@@ -115,3 +124,564 @@ func (us *UserRepo) GetUsersWithNicknameAndEmail(nickname, email string) ([]enti
 
 	return users, nil
 }
+
+// GetUserVotes lists both thread and post votes of the user, post votes carry thread of the post
+func (us *UserRepo) GetUserVotes(nickname string, query *entity.VoteQuery) ([]entity.VoteRecord, error) {
+	sql := `SELECT nickname, vote, thread, post, created FROM (
+		SELECT nickname, vote, thread_id AS thread, 0 AS post, created FROM thread_vote WHERE nickname = $1
+		UNION ALL
+		SELECT v.nickname, v.vote, p.thread, v.post_id, v.created FROM post_vote AS v
+		JOIN posts AS p ON p.id = v.post_id WHERE v.nickname = $1) AS votes WHERE TRUE`
+	args := []interface{}{nickname}
+	sql += votesPageClause(query, &args)
+
+	return queryVoteRecords(us.db, query.Limit, sql, args...)
+}
+
+const GetUsersCreatedQuery = `SELECT nickname, created FROM users WHERE nickname = ANY($1::text[]::citext[])`
+
+// GetUsersCreated returns registration time of users keyed by lowercase nickname
+func (us *UserRepo) GetUsersCreated(nicknames []string) (map[string]time.Time, error) {
+	rows, err := us.db.Query(context.Background(), GetUsersCreatedQuery, nicknames)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	created := make(map[string]time.Time, len(nicknames))
+	for rows.Next() {
+		var nickname string
+		var userCreated time.Time
+		err = rows.Scan(&nickname, &userCreated)
+		if err != nil {
+			return nil, err
+		}
+		created[strings.ToLower(nickname)] = userCreated
+	}
+	return created, rows.Err()
+}
+
+const GetUserStatsQuery = `SELECT u.created,
+	(SELECT COUNT(*) FROM posts WHERE author = u.nickname AND NOT isHeld AND NOT isDeleted),
+	(SELECT COUNT(*) FROM threads WHERE author = u.nickname),
+	(SELECT COALESCE(SUM(votes), 0) FROM posts WHERE author = u.nickname AND NOT isDeleted) +
+		(SELECT COALESCE(SUM(votes), 0) FROM threads WHERE author = u.nickname),
+	GREATEST(
+		(SELECT MAX(created) FROM posts WHERE author = u.nickname),
+		(SELECT MAX(created) FROM threads WHERE author = u.nickname),
+		(SELECT MAX(created) FROM post_vote WHERE nickname = u.nickname),
+		(SELECT MAX(created) FROM thread_vote WHERE nickname = u.nickname))
+	FROM users AS u WHERE u.nickname = $1`
+const GetUserForumsQuery = `SELECT forum_slug FROM forum_user WHERE nickname = $1 ORDER BY forum_slug`
+
+// GetUserStats computes activity of the user, last active is nil if the user has done nothing yet
+func (us *UserRepo) GetUserStats(nickname string) (*entity.UserStats, error) {
+	stats := &entity.UserStats{}
+	err := us.db.QueryRow(context.Background(), GetUserStatsQuery, nickname).Scan(
+		&stats.Joined, &stats.Posts, &stats.Threads, &stats.VotesReceived, &stats.LastActive)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return nil, entity.UserDoesntExistsError
+		}
+		return nil, err
+	}
+
+	rows, err := us.db.Query(context.Background(), GetUserForumsQuery, nickname)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	stats.Forums = make([]string, 0)
+	for rows.Next() {
+		var forum string
+		err = rows.Scan(&forum)
+		if err != nil {
+			return nil, err
+		}
+		stats.Forums = append(stats.Forums, forum)
+	}
+	return stats, rows.Err()
+}
+
+// userSortKeys maps directory sort to its key expression and the type the key is cast back to from cursor.
+// Relevance needs the query as $1 and the escaped prefix pattern as $2
+var userSortKeys = map[string]struct {
+	expr string
+	cast string
+}{
+	entity.NicknameUserSort: {"u.nickname", "citext"},
+	entity.FullnameUserSort: {"u.fullname", "citext"},
+	entity.CreatedUserSort:  {"u.created", "timestamptz"},
+	entity.RelevanceUserSort: {`(GREATEST(similarity(u.nickname::text, $1), similarity(u.fullname::text, $1)) +
+		CASE WHEN u.nickname::text ILIKE $2 OR u.fullname::text ILIKE $2 THEN 1 ELSE 0 END)::real`, "real"},
+}
+
+var likeEscaper = strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+
+// SearchUsers matches users by nickname or fullname prefix or by trigram similarity,
+// users hidden from directory are never returned
+func (us *UserRepo) SearchUsers(search *entity.UserSearch) ([]entity.User, []string, error) {
+	sortKey, ok := userSortKeys[search.Sort]
+	if !ok {
+		return nil, nil, entity.WrongUserSortError
+	}
+
+	order := "ASC"
+	compare := ">"
+	if search.Desc {
+		order = "DESC"
+		compare = "<"
+	}
+
+	query := `SELECT u.nickname, u.fullname, u.about, (` + sortKey.expr + `)::text FROM users AS u
+		WHERE NOT u.hidden_from_directory`
+	args := make([]interface{}, 0, 4)
+	if search.Query != "" {
+		args = append(args, search.Query, likeEscaper.Replace(search.Query)+"%")
+		query += ` AND (u.nickname::text ILIKE $2 OR u.fullname::text ILIKE $2
+			OR u.nickname::text % $1 OR u.fullname::text % $1)`
+	}
+	if search.AfterNickname != "" {
+		args = append(args, search.AfterKey, search.AfterNickname)
+		query += fmt.Sprintf(" AND (%v, u.nickname) %v ($%v::%v, $%v::citext)",
+			sortKey.expr, compare, len(args)-1, sortKey.cast, len(args))
+	}
+
+	query += fmt.Sprintf(" ORDER BY %v %v, u.nickname %v", sortKey.expr, order, order)
+	if search.Limit != 0 {
+		query += fmt.Sprintf(" LIMIT %v", search.Limit)
+	}
+
+	rows, err := us.db.Query(context.Background(), query, args...)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer rows.Close()
+
+	users := make([]entity.User, 0, search.Limit)
+	keys := make([]string, 0, search.Limit)
+	for rows.Next() {
+		user := entity.User{}
+		var key string
+		err = rows.Scan(&user.Nickname, &user.Fullname, &user.About, &key)
+		if err != nil {
+			return nil, nil, err
+		}
+		users = append(users, user)
+		keys = append(keys, key)
+	}
+	return users, keys, rows.Err()
+}
+
+const GetUserPrivacyQuery = `SELECT hidden_from_directory FROM users WHERE nickname = $1`
+const SetUserPrivacyQuery = `UPDATE users SET hidden_from_directory = $2 WHERE nickname = $1`
+
+func (us *UserRepo) GetUserPrivacy(nickname string) (*entity.UserPrivacy, error) {
+	privacy := &entity.UserPrivacy{}
+	err := us.db.QueryRow(context.Background(), GetUserPrivacyQuery, nickname).Scan(&privacy.HiddenFromDirectory)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return nil, entity.UserDoesntExistsError
+		}
+		return nil, err
+	}
+	return privacy, nil
+}
+
+func (us *UserRepo) SetUserPrivacy(nickname string, privacy *entity.UserPrivacy) error {
+	tag, err := us.db.Exec(context.Background(), SetUserPrivacyQuery, nickname, privacy.HiddenFromDirectory)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return entity.UserDoesntExistsError
+	}
+	return nil
+}
+
+const RenameUserQuery = `UPDATE users SET nickname = $2 WHERE nickname = $1 RETURNING id, nickname`
+const SaveNicknameHistoryQuery = `INSERT INTO nickname_history (user_id, old_nickname) VALUES ($1, $2)`
+
+// renameUnreferencedQueries update nickname columns that are not foreign keys, the rest is cascaded by foreign keys.
+// Audit log is left as it was written
+var renameUnreferencedQueries = []string{
+	`UPDATE reports SET moderator = $2 WHERE moderator = $1`,
+	`UPDATE report_actions SET moderator = $2 WHERE moderator = $1`,
+	`UPDATE report_actions SET content_author = $2 WHERE content_author = $1`,
+	`UPDATE forum_bans SET moderator = $2 WHERE moderator = $1`,
+}
+
+// LockNicknameQuery serializes renames that give up or take the same nickname,
+// so that a nickname given up by a concurrent rename is seen as reserved
+const LockNicknameQuery = `SELECT pg_advisory_xact_lock(hashtext(lower($1)))`
+
+func (us *UserRepo) RenameUser(nickname, newNickname string, reservedSince time.Time) error {
+	tx, err := us.db.Begin(context.Background())
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(context.Background())
+
+	// locks are taken in the same order by every rename to avoid deadlocks
+	locked := []string{strings.ToLower(nickname), strings.ToLower(newNickname)}
+	sort.Strings(locked)
+	for _, lock := range locked {
+		_, err = tx.Exec(context.Background(), LockNicknameQuery, lock)
+		if err != nil {
+			return err
+		}
+	}
+
+	var userID int
+	var oldNickname string
+	err = tx.QueryRow(context.Background(), RenameUserQuery, nickname, newNickname).Scan(&userID, &oldNickname)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return entity.UserDoesntExistsError
+		}
+		if isUniqueViolation(err) {
+			return entity.NicknameExistsError
+		}
+		return err
+	}
+
+	if !reservedSince.IsZero() {
+		var reserved bool
+		err = tx.QueryRow(context.Background(), IsNicknameReservedQuery, newNickname, userID, reservedSince).Scan(&reserved)
+		if err != nil {
+			return err
+		}
+		if reserved {
+			return entity.NicknameReservedError
+		}
+	}
+
+	for _, query := range renameUnreferencedQueries {
+		_, err = tx.Exec(context.Background(), query, oldNickname, newNickname)
+		if err != nil {
+			return err
+		}
+	}
+
+	// change of letter case only keeps the same citext nickname, there is nothing to redirect
+	if !strings.EqualFold(oldNickname, newNickname) {
+		_, err = tx.Exec(context.Background(), SaveNicknameHistoryQuery, userID, oldNickname)
+		if err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit(context.Background())
+}
+
+const IsNicknameReservedQuery = `SELECT EXISTS (SELECT 1 FROM nickname_history
+	WHERE old_nickname = $1 AND user_id <> $2 AND changed > $3)`
+
+func (us *UserRepo) IsNicknameReserved(nickname string, userID int, since time.Time) (bool, error) {
+	var reserved bool
+	err := us.db.QueryRow(context.Background(), IsNicknameReservedQuery, nickname, userID, since).Scan(&reserved)
+	return reserved, err
+}
+
+const GetRenamedNicknameQuery = `SELECT u.nickname FROM nickname_history AS h
+	JOIN users AS u ON u.id = h.user_id
+	WHERE h.old_nickname = $1 ORDER BY h.changed DESC LIMIT 1`
+
+func (us *UserRepo) GetRenamedNickname(oldNickname string) (string, error) {
+	var nickname string
+	err := us.db.QueryRow(context.Background(), GetRenamedNicknameQuery, oldNickname).Scan(&nickname)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return "", entity.UserDoesntExistsError
+		}
+		return "", err
+	}
+	return nickname, nil
+}
+
+const LockUserQuery = `SELECT id, nickname, created FROM users WHERE nickname = $1 AND NOT is_deleted FOR UPDATE`
+const GetNicknameHistoryQuery = `SELECT old_nickname, changed FROM nickname_history WHERE user_id = $1 ORDER BY changed`
+const AnonymizeUserQuery = `UPDATE users SET nickname = $2, fullname = $3, email = $2 || '@deleted.invalid', about = '',
+	is_deleted = TRUE, hidden_from_directory = TRUE, email_digest = FALSE, password_hash = NULL WHERE id = $1`
+
+// deleteUserDataQueries remove personal data of the user, vote triggers correct votes of the content.
+// Authored threads, posts and attachments are kept and get anonymized nickname by foreign keys
+var deleteUserDataQueries = []string{
+	`DELETE FROM post_vote WHERE nickname = $1`,
+	`DELETE FROM thread_vote WHERE nickname = $1`,
+	`DELETE FROM reactions WHERE nickname = $1`,
+	`DELETE FROM mentions WHERE nickname = $1`,
+	`DELETE FROM forum_user WHERE nickname = $1`,
+	`DELETE FROM forum_bans WHERE nickname = $1`,
+	`DELETE FROM follows WHERE follower = $1 OR followee = $1`,
+	`DELETE FROM blocks WHERE blocker = $1 OR blocked = $1`,
+	`DELETE FROM subscriptions WHERE nickname = $1`,
+	`DELETE FROM notifications WHERE nickname = $1`,
+	`DELETE FROM thread_reads WHERE nickname = $1`,
+}
+
+// deleteUserIDQueries remove data kept by user id, the users row itself stays anonymized
+var deleteUserIDQueries = []string{
+	`DELETE FROM nickname_history WHERE user_id = $1`,
+	`DELETE FROM email_tokens WHERE user_id = $1`,
+	`DELETE FROM sessions WHERE user_id = $1`,
+}
+
+// heldNickname is a nickname of the user and the time span the user had it, nil Until is the current nickname
+type heldNickname struct {
+	Nickname string
+	From     time.Time
+	Until    *time.Time
+}
+
+const EnableAuditScrubQuery = `SELECT set_config('forum.audit_scrub', 'on', true)`
+
+// ScrubUserAuditQuery replaces the nickname in audit entries written while the user had it
+// and drops snapshots of the user entries, which hold email and profile of the user
+const ScrubUserAuditQuery = `UPDATE audit_log SET
+	actor = CASE WHEN lower(actor) = lower($1) THEN $2 ELSE actor END,
+	target_id = CASE WHEN target_type = 'user' AND lower(target_id) = lower($1) THEN $2 ELSE target_id END,
+	before = CASE WHEN target_type = 'user' AND lower(target_id) = lower($1) THEN NULL ELSE before END,
+	after = CASE WHEN target_type = 'user' AND lower(target_id) = lower($1) THEN NULL ELSE after END
+	WHERE (lower(actor) = lower($1) OR (target_type = 'user' AND lower(target_id) = lower($1)))
+	AND created >= $3 AND ($4::timestamptz IS NULL OR created <= $4)`
+
+// getHeldNicknames returns the current and the previous nicknames of the user, a nickname may have
+// belonged to other users before or after, so each one is limited by the time the user had it
+func getHeldNicknames(tx pgx.Tx, userID int, nickname string, created time.Time) ([]heldNickname, error) {
+	rows, err := tx.Query(context.Background(), GetNicknameHistoryQuery, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	held := make([]heldNickname, 0)
+	from := created
+	for rows.Next() {
+		var oldNickname string
+		var changed time.Time
+		err = rows.Scan(&oldNickname, &changed)
+		if err != nil {
+			return nil, err
+		}
+		held = append(held, heldNickname{Nickname: oldNickname, From: from, Until: &changed})
+		from = changed
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+	return append(held, heldNickname{Nickname: nickname, From: from}), nil
+}
+
+// scrubUserAudit is the only change allowed to audit log, its trigger lets it through
+// for the transaction that enabled forum.audit_scrub
+func scrubUserAudit(tx pgx.Tx, held []heldNickname, anonymized string) error {
+	_, err := tx.Exec(context.Background(), EnableAuditScrubQuery)
+	if err != nil {
+		return err
+	}
+	for _, h := range held {
+		_, err = tx.Exec(context.Background(), ScrubUserAuditQuery, h.Nickname, anonymized, h.From, h.Until)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (us *UserRepo) DeleteUser(nickname string) (*entity.User, error) {
+	tx, err := us.db.Begin(context.Background())
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback(context.Background())
+
+	user := &entity.User{}
+	var created time.Time
+	err = tx.QueryRow(context.Background(), LockUserQuery, nickname).Scan(&user.ID, &nickname, &created)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return nil, entity.UserDoesntExistsError
+		}
+		return nil, err
+	}
+
+	held, err := getHeldNicknames(tx, user.ID, nickname, created)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, query := range deleteUserDataQueries {
+		_, err = tx.Exec(context.Background(), query, nickname)
+		if err != nil {
+			return nil, err
+		}
+	}
+	for _, query := range deleteUserIDQueries {
+		_, err = tx.Exec(context.Background(), query, user.ID)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	user.Nickname = fmt.Sprintf("deleted-%d", user.ID)
+	user.Fullname = entity.DeletedUserFullname
+	user.IsDeleted = true
+	for _, query := range renameUnreferencedQueries {
+		_, err = tx.Exec(context.Background(), query, nickname, user.Nickname)
+		if err != nil {
+			return nil, err
+		}
+	}
+	_, err = tx.Exec(context.Background(), AnonymizeUserQuery, user.ID, user.Nickname, user.Fullname)
+	if err != nil {
+		return nil, err
+	}
+
+	err = scrubUserAudit(tx, held, user.Nickname)
+	if err != nil {
+		return nil, err
+	}
+
+	return user, tx.Commit(context.Background())
+}
+
+const ForEachUserVoteQuery = `SELECT nickname, vote, thread_id, 0, created FROM thread_vote WHERE nickname = $1
+	UNION ALL
+	SELECT v.nickname, v.vote, p.thread, v.post_id, v.created FROM post_vote AS v
+	JOIN posts AS p ON p.id = v.post_id WHERE v.nickname = $1`
+
+func (us *UserRepo) ForEachUserVote(nickname string, fn func(vote *entity.VoteRecord) error) error {
+	return forEachRow(us.db, func(rows pgx.Rows) error {
+		vote := &entity.VoteRecord{}
+		err := rows.Scan(&vote.Nickname, &vote.Voice, &vote.Thread, &vote.Post, &vote.Created)
+		if err != nil {
+			return err
+		}
+		return fn(vote)
+	}, ForEachUserVoteQuery, nickname)
+}
+
+const DeleteEmailTokensQuery = `DELETE FROM email_tokens WHERE user_id = $1 AND purpose = $2`
+const SaveEmailTokenQuery = `INSERT INTO email_tokens (token_hash, user_id, email, purpose, expires) VALUES ($1, $2, $3, $4, $5)`
+
+func (us *UserRepo) SaveEmailToken(tokenHash string, token *entity.EmailToken) error {
+	tx, err := us.db.Begin(context.Background())
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(context.Background())
+
+	_, err = tx.Exec(context.Background(), DeleteEmailTokensQuery, token.UserID, token.Purpose)
+	if err != nil {
+		return err
+	}
+	_, err = tx.Exec(context.Background(), SaveEmailTokenQuery,
+		tokenHash, token.UserID, token.Email, token.Purpose, token.Expires)
+	if err != nil {
+		return err
+	}
+	return tx.Commit(context.Background())
+}
+
+const UseEmailTokenQuery = `DELETE FROM email_tokens WHERE token_hash = $1 AND purpose = ANY($2) AND expires > now()
+	RETURNING user_id, email, purpose, expires`
+
+// verification applies only while the user still has the verified address
+const VerifyEmailQuery = `UPDATE users SET email_verified = TRUE WHERE id = $1 AND email = $2 AND NOT is_deleted
+	RETURNING nickname, email`
+const ChangeEmailQuery = `UPDATE users AS u SET email = $2, email_verified = TRUE
+	FROM (SELECT id, email FROM users WHERE id = $1 FOR UPDATE) AS old
+	WHERE u.id = old.id AND NOT u.is_deleted RETURNING u.nickname, old.email`
+
+func (us *UserRepo) ConfirmEmail(tokenHash string) (*entity.EmailToken, string, string, error) {
+	tx, err := us.db.Begin(context.Background())
+	if err != nil {
+		return nil, "", "", err
+	}
+	defer tx.Rollback(context.Background())
+
+	token := &entity.EmailToken{}
+	purposes := []string{entity.VerifyEmailPurpose, entity.ChangeEmailPurpose}
+	err = tx.QueryRow(context.Background(), UseEmailTokenQuery, tokenHash, purposes).Scan(
+		&token.UserID, &token.Email, &token.Purpose, &token.Expires)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return nil, "", "", entity.EmailTokenError
+		}
+		return nil, "", "", err
+	}
+
+	query := VerifyEmailQuery
+	if token.Purpose == entity.ChangeEmailPurpose {
+		query = ChangeEmailQuery
+	}
+	var nickname, previousEmail string
+	err = tx.QueryRow(context.Background(), query, token.UserID, token.Email).Scan(&nickname, &previousEmail)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return nil, "", "", entity.EmailTokenError
+		}
+		if isUniqueViolation(err) {
+			return nil, "", "", entity.EmailTakenError
+		}
+		return nil, "", "", err
+	}
+
+	return token, nickname, previousEmail, tx.Commit(context.Background())
+}
+
+const GetPasswordHashQuery = `SELECT id, nickname, COALESCE(password_hash, '') FROM users
+	WHERE nickname = $1 AND NOT is_deleted`
+
+func (us *UserRepo) GetPasswordHash(nickname string) (int, string, string, error) {
+	var userID int
+	var storedNickname, passwordHash string
+	err := us.db.QueryRow(context.Background(), GetPasswordHashQuery, nickname).
+		Scan(&userID, &storedNickname, &passwordHash)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return 0, "", "", entity.UserDoesntExistsError
+		}
+		return 0, "", "", err
+	}
+	return userID, storedNickname, passwordHash, nil
+}
+
+const UseResetTokenQuery = `DELETE FROM email_tokens WHERE token_hash = $1 AND purpose = $2 AND expires > now()
+	RETURNING user_id`
+const SetPasswordQuery = `UPDATE users SET password_hash = $2 WHERE id = $1 AND NOT is_deleted RETURNING nickname`
+const DeleteUserSessionsQuery = `DELETE FROM sessions WHERE user_id = $1`
+
+func (us *UserRepo) ResetPassword(tokenHash string, passwordHash string) (string, error) {
+	tx, err := us.db.Begin(context.Background())
+	if err != nil {
+		return "", err
+	}
+	defer tx.Rollback(context.Background())
+
+	var userID int
+	err = tx.QueryRow(context.Background(), UseResetTokenQuery, tokenHash, entity.ResetPasswordPurpose).Scan(&userID)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return "", entity.EmailTokenError
+		}
+		return "", err
+	}
+
+	var nickname string
+	err = tx.QueryRow(context.Background(), SetPasswordQuery, userID, passwordHash).Scan(&nickname)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return "", entity.EmailTokenError
+		}
+		return "", err
+	}
+
+	_, err = tx.Exec(context.Background(), DeleteUserSessionsQuery, userID)
+	if err != nil {
+		return "", err
+	}
+	return nickname, tx.Commit(context.Background())
+}
@@ -0,0 +1,73 @@
+package admin
+
+import (
+	"encoding/json"
+	"forum/app"
+	"forum/domain/entity"
+	"go.uber.org/zap"
+	"net/http"
+	"strconv"
+)
+
+type AdminInfo struct {
+	auditApp app.AuditAppInterface
+	logger   *zap.Logger
+}
+
+func NewAdminInfo(auditApp app.AuditAppInterface, logger *zap.Logger) *AdminInfo {
+	return &AdminInfo{
+		auditApp: auditApp,
+		logger:   logger,
+	}
+}
+
+func (adminInfo *AdminInfo) HandleGetAuditLog(w http.ResponseWriter, r *http.Request) {
+	adminInfo.logger.Info("HandleGetAuditLog")
+	queryParams := r.URL.Query()
+
+	filter := &entity.AuditFilter{
+		Actor:      queryParams.Get("actor"),
+		Action:     queryParams.Get("action"),
+		TargetType: queryParams.Get("targetType"),
+		TargetID:   queryParams.Get("targetId"),
+		Since:      queryParams.Get(string(entity.SinceKey)),
+		Desc:       queryParams.Get(string(entity.DescKey)) == "true",
+	}
+
+	if limitParam := queryParams.Get(string(entity.LimitKey)); limitParam != "" {
+		limit, err := strconv.Atoi(limitParam)
+		if err != nil {
+			adminInfo.logger.Info(err.Error(), zap.String("url", r.RequestURI), zap.String("method", r.Method))
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		filter.Limit = int32(limit)
+	}
+
+	if filter.Since != "" {
+		if _, err := strconv.ParseInt(filter.Since, 10, 64); err != nil {
+			adminInfo.logger.Info(err.Error(), zap.String("url", r.RequestURI), zap.String("method", r.Method))
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+	}
+
+	entries, err := adminInfo.auditApp.GetAuditEntries(filter)
+	if err != nil {
+		adminInfo.logger.Info(
+			err.Error(), zap.String("url", r.RequestURI),
+			zap.String("method", r.Method))
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	body, err := json.Marshal(entries)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	w.Write(body)
+}
@@ -0,0 +1,149 @@
+package auth
+
+import (
+	"errors"
+	"forum/app"
+	"forum/domain/entity"
+	"forum/interface/httputil"
+	"go.uber.org/zap"
+	"net/http"
+)
+
+type AuthInfo struct {
+	authApp app.AuthAppInterface
+	// secureCookie marks session cookie as https only
+	secureCookie bool
+	logger       *zap.Logger
+}
+
+func NewAuthInfo(authApp app.AuthAppInterface, secureCookie bool, logger *zap.Logger) *AuthInfo {
+	return &AuthInfo{
+		authApp:      authApp,
+		secureCookie: secureCookie,
+		logger:       logger,
+	}
+}
+
+func (authInfo *AuthInfo) HandleLogin(w http.ResponseWriter, r *http.Request) {
+	authInfo.logger.Info("HandleLogin")
+
+	credentials := &entity.Credentials{}
+	if !httputil.ReadJSON(w, r, credentials, authInfo.logger) {
+		return
+	}
+
+	token, session, err := authInfo.authApp.Login(credentials)
+	if err != nil {
+		if errors.Is(err, entity.WrongCredentialsError) {
+			httputil.WriteJSON(w, http.StatusUnauthorized, entity.Message{Text: err.Error()})
+			return
+		}
+
+		authInfo.logger.Info(
+			err.Error(), zap.String("url", r.RequestURI),
+			zap.String("method", r.Method))
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	http.SetCookie(w, &http.Cookie{
+		Name:     entity.CookieNameKey,
+		Value:    token,
+		Path:     "/",
+		Expires:  session.Expires,
+		HttpOnly: true,
+		Secure:   authInfo.secureCookie,
+		SameSite: http.SameSiteLaxMode,
+	})
+	httputil.WriteJSON(w, http.StatusOK, session)
+}
+
+func (authInfo *AuthInfo) HandleLogout(w http.ResponseWriter, r *http.Request) {
+	authInfo.logger.Info("HandleLogout")
+
+	cookie, err := r.Cookie(entity.CookieNameKey)
+	if err == nil {
+		err = authInfo.authApp.Logout(cookie.Value)
+		if err != nil {
+			authInfo.logger.Info(
+				err.Error(), zap.String("url", r.RequestURI),
+				zap.String("method", r.Method))
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+	}
+
+	http.SetCookie(w, &http.Cookie{
+		Name:     entity.CookieNameKey,
+		Value:    "",
+		Path:     "/",
+		MaxAge:   -1,
+		HttpOnly: true,
+		Secure:   authInfo.secureCookie,
+		SameSite: http.SameSiteLaxMode,
+	})
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (authInfo *AuthInfo) HandleGetSession(w http.ResponseWriter, r *http.Request) {
+	authInfo.logger.Info("HandleGetSession")
+
+	session, ok := r.Context().Value(entity.CookieInfoKey).(*entity.Session)
+	if !ok {
+		httputil.WriteJSON(w, http.StatusUnauthorized, entity.Message{Text: entity.SessionNotFoundError.Error()})
+		return
+	}
+	httputil.WriteJSON(w, http.StatusOK, session)
+}
+
+// HandleRequestPasswordReset answers the same way whether the email is registered or not
+func (authInfo *AuthInfo) HandleRequestPasswordReset(w http.ResponseWriter, r *http.Request) {
+	authInfo.logger.Info("HandleRequestPasswordReset")
+
+	request := &entity.PasswordResetRequest{}
+	if !httputil.ReadJSON(w, r, request, authInfo.logger) {
+		return
+	}
+
+	err := authInfo.authApp.RequestPasswordReset(request.Email)
+	if err != nil {
+		if errors.Is(err, entity.WrongEmailError) {
+			httputil.WriteJSON(w, http.StatusBadRequest, entity.Message{Text: err.Error()})
+			return
+		}
+
+		authInfo.logger.Info(
+			err.Error(), zap.String("url", r.RequestURI),
+			zap.String("method", r.Method))
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	httputil.WriteJSON(w, http.StatusAccepted,
+		entity.Message{Text: "If the email is registered, reset instructions are sent to it"})
+}
+
+func (authInfo *AuthInfo) HandleResetPassword(w http.ResponseWriter, r *http.Request) {
+	authInfo.logger.Info("HandleResetPassword")
+
+	reset := &entity.PasswordReset{}
+	if !httputil.ReadJSON(w, r, reset, authInfo.logger) {
+		return
+	}
+
+	err := authInfo.authApp.ResetPassword(reset)
+	if err != nil {
+		if errors.Is(err, entity.EmailTokenError) || errors.Is(err, entity.WeakPasswordError) {
+			httputil.WriteJSON(w, http.StatusBadRequest, entity.Message{Text: err.Error()})
+			return
+		}
+
+		authInfo.logger.Info(
+			err.Error(), zap.String("url", r.RequestURI),
+			zap.String("method", r.Method))
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	httputil.WriteJSON(w, http.StatusOK, entity.Message{Text: "Password is changed, all sessions are ended"})
+}
@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"forum/app"
 	"forum/domain/entity"
+	"forum/interface/httputil"
 	"github.com/gorilla/mux"
 	"go.uber.org/zap"
 	"io/ioutil"
@@ -13,22 +14,25 @@ import (
 )
 
 type ForumInfo struct {
-	ForumApp  app.ForumAppInterface
-	UserApp   app.UserAppInterface
-	ThreadApp app.ThreadAppInterface
-	logger    *zap.Logger
+	ForumApp      app.ForumAppInterface
+	UserApp       app.UserAppInterface
+	ThreadApp     app.ThreadAppInterface
+	AttachmentApp app.AttachmentAppInterface
+	logger        *zap.Logger
 }
 
 func NewForumInfo(
 	ForumApp app.ForumAppInterface,
 	UserApp app.UserAppInterface,
 	ThreadApp app.ThreadAppInterface,
+	AttachmentApp app.AttachmentAppInterface,
 	logger *zap.Logger) *ForumInfo {
 	return &ForumInfo{
-		ForumApp:  ForumApp,
-		UserApp:   UserApp,
-		ThreadApp: ThreadApp,
-		logger:    logger,
+		ForumApp:      ForumApp,
+		UserApp:       UserApp,
+		ThreadApp:     ThreadApp,
+		AttachmentApp: AttachmentApp,
+		logger:        logger,
 	}
 }
 
@@ -190,6 +194,26 @@ func (forumInfo *ForumInfo) HandleCreateForumThread(w http.ResponseWriter, r *ht
 
 	err = forumInfo.ThreadApp.CreateThread(thread)
 	if err != nil {
+		if err == entity.WrongFormatError || err == entity.UserBannedError {
+			status := http.StatusBadRequest
+			if err == entity.UserBannedError {
+				status = http.StatusForbidden
+			}
+			msg := entity.Message{
+				Text: err.Error(),
+			}
+			body, err := json.Marshal(msg)
+			if err != nil {
+				w.WriteHeader(http.StatusInternalServerError)
+				return
+			}
+
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(status)
+			w.Write(body)
+			return
+		}
+
 		if err == entity.ForumNotExistError {
 			msg := entity.Message{
 				Text: fmt.Sprintf("Can't find thread forum by slug: %v", thread.Forum),
@@ -360,7 +384,9 @@ func (forumInfo *ForumInfo) HandleGetForumThreads(w http.ResponseWriter, r *http
 		since = sinceParam[0]
 	}
 
-	threads, err := forumInfo.ThreadApp.GetThreadsByForumSlug(slug, int32(limit), since, desc)
+	// unread counts are added for signed in users
+	viewer := entity.ViewerNickname(r.Context())
+	threads, err := forumInfo.ThreadApp.GetThreadsByForumSlug(slug, int32(limit), since, desc, viewer)
 	if err != nil {
 		forumInfo.logger.Info(
 			err.Error(), zap.String("url", r.RequestURI),
@@ -382,3 +408,188 @@ func (forumInfo *ForumInfo) HandleGetForumThreads(w http.ResponseWriter, r *http
 	w.WriteHeader(http.StatusOK)
 	w.Write(body)
 }
+
+func (forumInfo *ForumInfo) HandleGetAttachmentLimits(w http.ResponseWriter, r *http.Request) {
+	forumInfo.logger.Info("HandleGetAttachmentLimits")
+	vars := mux.Vars(r)
+	slug := vars[string(entity.SlugKey)]
+
+	limits, err := forumInfo.AttachmentApp.GetForumLimits(slug)
+	if err != nil {
+		msg := entity.Message{
+			Text: fmt.Sprintf("Can't find forum by slug: %v", slug),
+		}
+		body, err := json.Marshal(msg)
+		if err != nil {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusNotFound)
+		w.Write(body)
+		return
+	}
+
+	body, err := json.Marshal(limits)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	w.Write(body)
+}
+
+func (forumInfo *ForumInfo) HandleSetAttachmentLimits(w http.ResponseWriter, r *http.Request) {
+	forumInfo.logger.Info("HandleSetAttachmentLimits")
+	vars := mux.Vars(r)
+	slug := vars[string(entity.SlugKey)]
+
+	// only the forum owner may change limits, the owner is the session user
+	viewer := entity.ViewerNickname(r.Context())
+	if viewer == "" {
+		httputil.WriteMessage(w, http.StatusUnauthorized, entity.Message{Text: entity.NoSessionError.Error()})
+		return
+	}
+
+	limits := &entity.AttachmentLimits{}
+	data, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		forumInfo.logger.Info(
+			err.Error(), zap.String("url", r.RequestURI),
+			zap.String("method", r.Method))
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	err = json.Unmarshal(data, limits)
+	if err != nil {
+		forumInfo.logger.Info(
+			err.Error(), zap.String("url", r.RequestURI),
+			zap.String("method", r.Method))
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	limits.User = viewer
+
+	err = forumInfo.AttachmentApp.SetForumLimits(slug, limits)
+	if err != nil {
+		status := http.StatusInternalServerError
+		switch err {
+		case entity.ForumNotExistError:
+			status = http.StatusNotFound
+		case entity.NotForumOwnerError:
+			status = http.StatusForbidden
+		case entity.DataError:
+			status = http.StatusBadRequest
+		default:
+			forumInfo.logger.Info(
+				err.Error(), zap.String("url", r.RequestURI),
+				zap.String("method", r.Method))
+			w.WriteHeader(status)
+			return
+		}
+
+		msg := entity.Message{
+			Text: err.Error(),
+		}
+		body, err := json.Marshal(msg)
+		if err != nil {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(status)
+		w.Write(body)
+		return
+	}
+
+	effective, err := forumInfo.AttachmentApp.GetForumLimits(slug)
+	if err != nil {
+		forumInfo.logger.Info(
+			err.Error(), zap.String("url", r.RequestURI),
+			zap.String("method", r.Method))
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	httputil.WriteJSON(w, http.StatusOK, effective)
+}
+
+func (forumInfo *ForumInfo) HandleGetWordFilter(w http.ResponseWriter, r *http.Request) {
+	forumInfo.logger.Info("HandleGetWordFilter")
+	vars := mux.Vars(r)
+	slug := vars[string(entity.SlugKey)]
+
+	filter, err := forumInfo.ForumApp.GetWordFilter(slug)
+	if err != nil {
+		msg := entity.Message{
+			Text: fmt.Sprintf("Can't find forum by slug: %v", slug),
+		}
+		body, err := json.Marshal(msg)
+		if err != nil {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusNotFound)
+		w.Write(body)
+		return
+	}
+
+	body, err := json.Marshal(filter)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	w.Write(body)
+}
+
+func (forumInfo *ForumInfo) HandleSetWordFilter(w http.ResponseWriter, r *http.Request) {
+	forumInfo.logger.Info("HandleSetWordFilter")
+	vars := mux.Vars(r)
+	slug := vars[string(entity.SlugKey)]
+
+	// only the forum owner may change the filter, the owner is the session user
+	viewer := entity.ViewerNickname(r.Context())
+	if viewer == "" {
+		httputil.WriteMessage(w, http.StatusUnauthorized, entity.Message{Text: entity.NoSessionError.Error()})
+		return
+	}
+
+	filter := &entity.WordFilter{}
+	if !httputil.ReadJSON(w, r, filter, forumInfo.logger) {
+		return
+	}
+	filter.User = viewer
+
+	err := forumInfo.ForumApp.SetWordFilter(slug, filter)
+	if err != nil {
+		status := http.StatusInternalServerError
+		switch err {
+		case entity.ForumNotExistError:
+			status = http.StatusNotFound
+		case entity.NotForumOwnerError:
+			status = http.StatusForbidden
+		case entity.WrongFilterModeError:
+			status = http.StatusBadRequest
+		default:
+			forumInfo.logger.Info(
+				err.Error(), zap.String("url", r.RequestURI),
+				zap.String("method", r.Method))
+			w.WriteHeader(status)
+			return
+		}
+
+		httputil.WriteMessage(w, status, entity.Message{Text: err.Error()})
+		return
+	}
+
+	httputil.WriteJSON(w, http.StatusOK, filter)
+}
@@ -0,0 +1,113 @@
+// Package httputil holds request parsing and response writing shared by the handlers
+package httputil
+
+import (
+	"encoding/json"
+	"forum/domain/entity"
+	"go.uber.org/zap"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+)
+
+// ReadJSON decodes request body into v. On failure it answers the request and returns false
+func ReadJSON(w http.ResponseWriter, r *http.Request, v interface{}, logger *zap.Logger) bool {
+	data, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		logger.Info(
+			err.Error(), zap.String("url", r.RequestURI),
+			zap.String("method", r.Method))
+		w.WriteHeader(http.StatusInternalServerError)
+		return false
+	}
+
+	err = json.Unmarshal(data, v)
+	if err != nil {
+		logger.Info(
+			err.Error(), zap.String("url", r.RequestURI),
+			zap.String("method", r.Method))
+		w.WriteHeader(http.StatusBadRequest)
+		return false
+	}
+	return true
+}
+
+func WriteJSON(w http.ResponseWriter, status int, v interface{}) {
+	body, err := json.Marshal(v)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	w.Write(body)
+}
+
+func WriteMessage(w http.ResponseWriter, status int, msg entity.Message) {
+	WriteJSON(w, status, msg)
+}
+
+// PageParams parses limit, since and desc query parameters, since is validated by the caller
+func PageParams(queryParams url.Values) (int32, string, bool, error) {
+	limit := 0
+	var err error
+	if limitParam := queryParams.Get(string(entity.LimitKey)); limitParam != "" {
+		limit, err = strconv.Atoi(limitParam)
+		if err != nil {
+			return 0, "", false, err
+		}
+	}
+
+	desc := queryParams.Get(string(entity.DescKey)) == "true"
+	return int32(limit), queryParams.Get(string(entity.SinceKey)), desc, nil
+}
+
+// TimePageParams is PageParams of listings paged by time, since must be RFC 3339 time
+func TimePageParams(queryParams url.Values) (int32, *time.Time, bool, error) {
+	limit, sinceParam, desc, err := PageParams(queryParams)
+	if err != nil || sinceParam == "" {
+		return limit, nil, desc, err
+	}
+	since, err := time.Parse(time.RFC3339Nano, sinceParam)
+	if err != nil {
+		return 0, nil, false, err
+	}
+	return limit, &since, desc, nil
+}
+
+// IDPageParams is PageParams of listings paged by id, since must be a number
+func IDPageParams(queryParams url.Values) (int32, string, bool, error) {
+	limit, since, desc, err := PageParams(queryParams)
+	if err != nil {
+		return 0, "", false, err
+	}
+	if since != "" {
+		_, err = strconv.Atoi(since)
+		if err != nil {
+			return 0, "", false, err
+		}
+	}
+	return limit, since, desc, nil
+}
+
+// ClientAddress is the ip of the request sender
+func ClientAddress(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
+// AuditActor names the sender of the request in audit entries: the session user
+// or the client address of anonymous requests
+func AuditActor(r *http.Request) string {
+	if viewer := entity.ViewerNickname(r.Context()); viewer != "" {
+		return viewer
+	}
+	return "anonymous@" + ClientAddress(r)
+}
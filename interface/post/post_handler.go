@@ -5,20 +5,25 @@ import (
 	"fmt"
 	"forum/app"
 	"forum/domain/entity"
+	"forum/interface/httputil"
 	"github.com/gorilla/mux"
 	"go.uber.org/zap"
+	"io"
 	"io/ioutil"
+	"mime"
 	"net/http"
 	"strconv"
 	"strings"
 )
 
 type PostInfo struct {
-	PostApp   app.PostAppInterface
-	UserApp   app.UserAppInterface
-	ThreadApp app.ThreadAppInterface
-	ForumApp  app.ForumAppInterface
-	logger    *zap.Logger
+	PostApp       app.PostAppInterface
+	UserApp       app.UserAppInterface
+	ThreadApp     app.ThreadAppInterface
+	ForumApp      app.ForumAppInterface
+	ReactionApp   app.ReactionAppInterface
+	AttachmentApp app.AttachmentAppInterface
+	logger        *zap.Logger
 }
 
 func NewPostInfo(
@@ -26,13 +31,17 @@ func NewPostInfo(
 	UserApp app.UserAppInterface,
 	ThreadApp app.ThreadAppInterface,
 	ForumApp app.ForumAppInterface,
+	ReactionApp app.ReactionAppInterface,
+	AttachmentApp app.AttachmentAppInterface,
 	logger *zap.Logger) *PostInfo {
 	return &PostInfo{
-		PostApp:   PostApp,
-		UserApp:   UserApp,
-		ThreadApp: ThreadApp,
-		ForumApp:  ForumApp,
-		logger:    logger,
+		PostApp:       PostApp,
+		UserApp:       UserApp,
+		ThreadApp:     ThreadApp,
+		ForumApp:      ForumApp,
+		ReactionApp:   ReactionApp,
+		AttachmentApp: AttachmentApp,
+		logger:        logger,
 	}
 }
 
@@ -49,7 +58,8 @@ func (postInfo *PostInfo) HandleGetPostDetails(w http.ResponseWriter, r *http.Re
 	}
 
 	post, err := postInfo.PostApp.GetPostDetails(id)
-	if err != nil {
+	// held posts are hidden until a moderator approves them
+	if err != nil || post.IsHeld {
 		msg := entity.Message{
 			Text: fmt.Sprintf("Can't find post with id: %v", id),
 		}
@@ -216,11 +226,20 @@ func (postInfo *PostInfo) HandleChangePost(w http.ResponseWriter, r *http.Reques
 	}
 	post.ID = id
 
-	post, err = postInfo.PostApp.ChangePostMessage(post)
+	post, err = postInfo.PostApp.ChangePostMessage(post, httputil.AuditActor(r))
 	if err != nil {
+		status := http.StatusNotFound
 		msg := entity.Message{
 			Text: fmt.Sprintf("Can't find post with id: %v", id),
 		}
+		if err == entity.WrongFormatError || err == entity.BannedWordsError {
+			status = http.StatusBadRequest
+			msg.Text = err.Error()
+		}
+		if err == entity.DuplicatePostError {
+			status = http.StatusConflict
+			msg.Text = err.Error()
+		}
 		body, err := json.Marshal(msg)
 		if err != nil {
 			w.WriteHeader(http.StatusInternalServerError)
@@ -228,7 +247,7 @@ func (postInfo *PostInfo) HandleChangePost(w http.ResponseWriter, r *http.Reques
 		}
 
 		w.Header().Set("Content-Type", "application/json")
-		w.WriteHeader(http.StatusNotFound)
+		w.WriteHeader(status)
 		w.Write(body)
 		return
 	}
@@ -247,3 +266,348 @@ func (postInfo *PostInfo) HandleChangePost(w http.ResponseWriter, r *http.Reques
 	w.Write(body)
 	return
 }
+
+func (postInfo *PostInfo) HandleVoteForPost(w http.ResponseWriter, r *http.Request) {
+	postInfo.logger.Info("HandleVoteForPost")
+	vars := mux.Vars(r)
+	idStr := vars[string(entity.IDKey)]
+
+	id, err := strconv.Atoi(idStr)
+	if err != nil {
+		postInfo.logger.Info(err.Error(), zap.String("url", r.RequestURI), zap.String("method", r.Method))
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	vote := &entity.Vote{}
+	data, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		postInfo.logger.Info(
+			err.Error(), zap.String("url", r.RequestURI),
+			zap.String("method", r.Method))
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	err = json.Unmarshal(data, vote)
+	if err != nil {
+		postInfo.logger.Info(
+			err.Error(), zap.String("url", r.RequestURI),
+			zap.String("method", r.Method))
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	vote.ID = id
+
+	_, err = postInfo.UserApp.CheckIfUserExists(vote.Nickname)
+	if err != nil {
+		msg := entity.Message{
+			Text: fmt.Sprintf("Can't find user with id #%v\n", vote.Nickname),
+		}
+		body, err := json.Marshal(msg)
+		if err != nil {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusNotFound)
+		w.Write(body)
+		return
+	}
+
+	post, err := postInfo.PostApp.VoteForPost(vote)
+	if err != nil {
+		status := http.StatusNotFound
+		msg := entity.Message{
+			Text: fmt.Sprintf("Can't find post with id: %v", id),
+		}
+		if err == entity.WrongVoiceError {
+			status = http.StatusBadRequest
+			msg.Text = err.Error()
+		}
+		body, err := json.Marshal(msg)
+		if err != nil {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(status)
+		w.Write(body)
+		return
+	}
+
+	body, err := json.Marshal(post)
+	if err != nil {
+		postInfo.logger.Info(
+			err.Error(), zap.String("url", r.RequestURI),
+			zap.String("method", r.Method))
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	w.Write(body)
+}
+
+func (postInfo *PostInfo) HandleAddPostReaction(w http.ResponseWriter, r *http.Request) {
+	postInfo.logger.Info("HandleAddPostReaction")
+	postInfo.handlePostReaction(w, r, postInfo.ReactionApp.AddReaction)
+}
+
+func (postInfo *PostInfo) HandleRemovePostReaction(w http.ResponseWriter, r *http.Request) {
+	postInfo.logger.Info("HandleRemovePostReaction")
+	postInfo.handlePostReaction(w, r, postInfo.ReactionApp.RemoveReaction)
+}
+
+// handlePostReaction applies action to reaction from request body and responds with updated post
+func (postInfo *PostInfo) handlePostReaction(w http.ResponseWriter, r *http.Request, action func(*entity.Reaction) error) {
+	vars := mux.Vars(r)
+	idStr := vars[string(entity.IDKey)]
+
+	id, err := strconv.Atoi(idStr)
+	if err != nil {
+		postInfo.logger.Info(err.Error(), zap.String("url", r.RequestURI), zap.String("method", r.Method))
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	reaction := &entity.Reaction{}
+	data, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		postInfo.logger.Info(
+			err.Error(), zap.String("url", r.RequestURI),
+			zap.String("method", r.Method))
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	err = json.Unmarshal(data, reaction)
+	if err != nil {
+		postInfo.logger.Info(
+			err.Error(), zap.String("url", r.RequestURI),
+			zap.String("method", r.Method))
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	reaction.Post = id
+
+	reaction.Nickname, err = postInfo.UserApp.CheckIfUserExists(reaction.Nickname)
+	if err != nil {
+		msg := entity.Message{
+			Text: fmt.Sprintf("Can't find user with id #%v\n", reaction.Nickname),
+		}
+		body, err := json.Marshal(msg)
+		if err != nil {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusNotFound)
+		w.Write(body)
+		return
+	}
+
+	_, err = postInfo.PostApp.GetPostDetails(id)
+	if err == nil {
+		err = action(reaction)
+	}
+	if err != nil {
+		status := http.StatusNotFound
+		msg := entity.Message{
+			Text: fmt.Sprintf("Can't find post with id: %v", id),
+		}
+		if err == entity.WrongReactionError {
+			status = http.StatusBadRequest
+			msg.Text = err.Error()
+		}
+		body, err := json.Marshal(msg)
+		if err != nil {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(status)
+		w.Write(body)
+		return
+	}
+
+	post, err := postInfo.PostApp.GetPostDetails(id)
+	if err != nil {
+		postInfo.logger.Info(
+			err.Error(), zap.String("url", r.RequestURI),
+			zap.String("method", r.Method))
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	body, err := json.Marshal(post)
+	if err != nil {
+		postInfo.logger.Info(
+			err.Error(), zap.String("url", r.RequestURI),
+			zap.String("method", r.Method))
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	w.Write(body)
+}
+
+// HandleUploadAttachment expects multipart form with a "file" part, other parts are skipped.
+// Only the post author may upload, the uploader is the session user. The file is streamed
+// to the storage without buffering the whole body
+func (postInfo *PostInfo) HandleUploadAttachment(w http.ResponseWriter, r *http.Request) {
+	postInfo.logger.Info("HandleUploadAttachment")
+	vars := mux.Vars(r)
+	idStr := vars[string(entity.IDKey)]
+
+	id, err := strconv.Atoi(idStr)
+	if err != nil {
+		postInfo.logger.Info(err.Error(), zap.String("url", r.RequestURI), zap.String("method", r.Method))
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	// only the post author may attach files, the author is the session user
+	viewer := entity.ViewerNickname(r.Context())
+	if viewer == "" {
+		httputil.WriteMessage(w, http.StatusUnauthorized, entity.Message{Text: entity.NoSessionError.Error()})
+		return
+	}
+
+	post, err := postInfo.PostApp.GetPostDetails(id)
+	if err != nil {
+		msg := entity.Message{
+			Text: fmt.Sprintf("Can't find post with id: %v", id),
+		}
+		body, err := json.Marshal(msg)
+		if err != nil {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusNotFound)
+		w.Write(body)
+		return
+	}
+
+	reader, err := r.MultipartReader()
+	if err != nil {
+		postInfo.logger.Info(err.Error(), zap.String("url", r.RequestURI), zap.String("method", r.Method))
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	var attachment *entity.Attachment
+	for attachment == nil {
+		part, err := reader.NextPart()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			postInfo.logger.Info(err.Error(), zap.String("url", r.RequestURI), zap.String("method", r.Method))
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+
+		switch part.FormName() {
+		case "file":
+			attachment, err = postInfo.AttachmentApp.UploadAttachment(post, viewer, part.FileName(), part)
+			if err != nil {
+				status := http.StatusInternalServerError
+				switch err {
+				case entity.NotPostAuthorError:
+					status = http.StatusForbidden
+				case entity.AttachmentTypeError:
+					status = http.StatusUnsupportedMediaType
+				case entity.AttachmentTooLargeError:
+					status = http.StatusRequestEntityTooLarge
+				default:
+					postInfo.logger.Info(
+						err.Error(), zap.String("url", r.RequestURI),
+						zap.String("method", r.Method))
+					w.WriteHeader(status)
+					return
+				}
+
+				msg := entity.Message{
+					Text: err.Error(),
+				}
+				body, err := json.Marshal(msg)
+				if err != nil {
+					w.WriteHeader(http.StatusInternalServerError)
+					return
+				}
+
+				w.Header().Set("Content-Type", "application/json")
+				w.WriteHeader(status)
+				w.Write(body)
+				return
+			}
+		}
+		part.Close()
+	}
+
+	if attachment == nil {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	body, err := json.Marshal(attachment)
+	if err != nil {
+		postInfo.logger.Info(
+			err.Error(), zap.String("url", r.RequestURI),
+			zap.String("method", r.Method))
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	w.Write(body)
+}
+
+func (postInfo *PostInfo) HandleGetAttachment(w http.ResponseWriter, r *http.Request) {
+	postInfo.logger.Info("HandleGetAttachment")
+	vars := mux.Vars(r)
+	idStr := vars[string(entity.IDKey)]
+
+	id, err := strconv.Atoi(idStr)
+	if err != nil {
+		postInfo.logger.Info(err.Error(), zap.String("url", r.RequestURI), zap.String("method", r.Method))
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	attachment, content, err := postInfo.AttachmentApp.OpenAttachment(id)
+	if err != nil {
+		msg := entity.Message{
+			Text: fmt.Sprintf("Can't find attachment with id: %v", id),
+		}
+		body, err := json.Marshal(msg)
+		if err != nil {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusNotFound)
+		w.Write(body)
+		return
+	}
+	defer content.Close()
+
+	w.Header().Set("Content-Type", attachment.ContentType)
+	w.Header().Set("Content-Length", strconv.FormatInt(attachment.Size, 10))
+	w.Header().Set("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": attachment.Filename}))
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.WriteHeader(http.StatusOK)
+	io.Copy(w, content)
+}
@@ -0,0 +1,377 @@
+package report
+
+import (
+	"encoding/json"
+	"fmt"
+	"forum/app"
+	"forum/domain/entity"
+	"forum/interface/httputil"
+	"github.com/gorilla/mux"
+	"go.uber.org/zap"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+)
+
+type ReportInfo struct {
+	reportApp app.ReportAppInterface
+	userApp   app.UserAppInterface
+	logger    *zap.Logger
+}
+
+func NewReportInfo(
+	reportApp app.ReportAppInterface,
+	userApp app.UserAppInterface,
+	logger *zap.Logger) *ReportInfo {
+	return &ReportInfo{
+		reportApp: reportApp,
+		userApp:   userApp,
+		logger:    logger,
+	}
+}
+
+func (reportInfo *ReportInfo) HandleReportPost(w http.ResponseWriter, r *http.Request) {
+	reportInfo.logger.Info("HandleReportPost")
+	vars := mux.Vars(r)
+	idStr := vars[string(entity.IDKey)]
+
+	id, err := strconv.Atoi(idStr)
+	if err != nil {
+		reportInfo.logger.Info(err.Error(), zap.String("url", r.RequestURI), zap.String("method", r.Method))
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	reportInfo.handleCreateReport(w, r, func(report *entity.Report) error {
+		return reportInfo.reportApp.ReportPost(id, report)
+	})
+}
+
+func (reportInfo *ReportInfo) HandleReportThread(w http.ResponseWriter, r *http.Request) {
+	reportInfo.logger.Info("HandleReportThread")
+	vars := mux.Vars(r)
+	slugOrID := vars[string(entity.SlugOrIDKey)]
+
+	reportInfo.handleCreateReport(w, r, func(report *entity.Report) error {
+		return reportInfo.reportApp.ReportThread(slugOrID, report)
+	})
+}
+
+// handleCreateReport reads report from request body and responds with the created report
+func (reportInfo *ReportInfo) handleCreateReport(w http.ResponseWriter, r *http.Request, create func(*entity.Report) error) {
+	report := &entity.Report{}
+	data, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		reportInfo.logger.Info(
+			err.Error(), zap.String("url", r.RequestURI),
+			zap.String("method", r.Method))
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	err = json.Unmarshal(data, report)
+	if err != nil {
+		reportInfo.logger.Info(
+			err.Error(), zap.String("url", r.RequestURI),
+			zap.String("method", r.Method))
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	nickname, err := reportInfo.userApp.CheckIfUserExists(report.Reporter)
+	if err != nil {
+		msg := entity.Message{
+			Text: fmt.Sprintf("Can't find user by nickname: %v", report.Reporter),
+		}
+		httputil.WriteMessage(w, http.StatusNotFound, msg)
+		return
+	}
+	report.Reporter = nickname
+
+	err = create(report)
+	if err != nil {
+		status := http.StatusNotFound
+		msg := entity.Message{
+			Text: "Can't find reported content",
+		}
+		switch err {
+		case entity.DataError:
+			status = http.StatusBadRequest
+			msg.Text = err.Error()
+		case entity.ReportExistsError:
+			status = http.StatusConflict
+			msg.Text = err.Error()
+		case entity.PostDeletedError:
+			msg.Text = err.Error()
+		}
+
+		httputil.WriteMessage(w, status, msg)
+		return
+	}
+
+	body, err := json.Marshal(report)
+	if err != nil {
+		reportInfo.logger.Info(
+			err.Error(), zap.String("url", r.RequestURI),
+			zap.String("method", r.Method))
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	w.Write(body)
+}
+
+// sessionModerator returns nickname of the session user, who is checked to be a moderator of the forum
+// by the app. Requests without a session are answered with 401
+func sessionModerator(w http.ResponseWriter, r *http.Request) (string, bool) {
+	moderator := entity.ViewerNickname(r.Context())
+	if moderator == "" {
+		httputil.WriteMessage(w, http.StatusUnauthorized, entity.Message{Text: entity.NoSessionError.Error()})
+		return "", false
+	}
+	return moderator, true
+}
+
+func (reportInfo *ReportInfo) HandleGetForumReports(w http.ResponseWriter, r *http.Request) {
+	reportInfo.logger.Info("HandleGetForumReports")
+	vars := mux.Vars(r)
+	slug := vars[string(entity.SlugKey)]
+
+	moderator, ok := sessionModerator(w, r)
+	if !ok {
+		return
+	}
+
+	queryParams := r.URL.Query()
+	limit, since, desc, err := httputil.IDPageParams(queryParams)
+	if err != nil {
+		reportInfo.logger.Info(err.Error(), zap.String("url", r.RequestURI), zap.String("method", r.Method))
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	reports, err := reportInfo.reportApp.GetForumReports(
+		slug, moderator, queryParams.Get(string(entity.StatusKey)), limit, since, desc)
+	if err != nil {
+		status := http.StatusInternalServerError
+		switch err {
+		case entity.ForumNotExistError:
+			status = http.StatusNotFound
+		case entity.NotModeratorError:
+			status = http.StatusForbidden
+		default:
+			reportInfo.logger.Info(
+				err.Error(), zap.String("url", r.RequestURI),
+				zap.String("method", r.Method))
+			w.WriteHeader(status)
+			return
+		}
+
+		httputil.WriteMessage(w, status, entity.Message{Text: err.Error()})
+		return
+	}
+
+	body, err := json.Marshal(reports)
+	if err != nil {
+		reportInfo.logger.Info(
+			err.Error(), zap.String("url", r.RequestURI),
+			zap.String("method", r.Method))
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	w.Write(body)
+}
+
+func (reportInfo *ReportInfo) HandleResolveReport(w http.ResponseWriter, r *http.Request) {
+	reportInfo.logger.Info("HandleResolveReport")
+	vars := mux.Vars(r)
+	idStr := vars[string(entity.IDKey)]
+
+	id, err := strconv.Atoi(idStr)
+	if err != nil {
+		reportInfo.logger.Info(err.Error(), zap.String("url", r.RequestURI), zap.String("method", r.Method))
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	moderator, ok := sessionModerator(w, r)
+	if !ok {
+		return
+	}
+
+	resolution := &entity.ReportResolution{}
+	data, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		reportInfo.logger.Info(
+			err.Error(), zap.String("url", r.RequestURI),
+			zap.String("method", r.Method))
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	err = json.Unmarshal(data, resolution)
+	if err != nil {
+		reportInfo.logger.Info(
+			err.Error(), zap.String("url", r.RequestURI),
+			zap.String("method", r.Method))
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	resolution.Moderator = moderator
+
+	report, err := reportInfo.reportApp.ResolveReport(id, resolution)
+	if err != nil {
+		status := http.StatusInternalServerError
+		switch err {
+		case entity.WrongReportActionError:
+			status = http.StatusBadRequest
+		case entity.ReportNotFoundError, entity.ForumNotExistError:
+			status = http.StatusNotFound
+		case entity.NotModeratorError:
+			status = http.StatusForbidden
+		case entity.ReportResolvedError:
+			status = http.StatusConflict
+		default:
+			reportInfo.logger.Info(
+				err.Error(), zap.String("url", r.RequestURI),
+				zap.String("method", r.Method))
+			w.WriteHeader(status)
+			return
+		}
+
+		httputil.WriteMessage(w, status, entity.Message{Text: err.Error()})
+		return
+	}
+
+	body, err := json.Marshal(report)
+	if err != nil {
+		reportInfo.logger.Info(
+			err.Error(), zap.String("url", r.RequestURI),
+			zap.String("method", r.Method))
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	w.Write(body)
+}
+
+func (reportInfo *ReportInfo) HandleGetForumHeldPosts(w http.ResponseWriter, r *http.Request) {
+	reportInfo.logger.Info("HandleGetForumHeldPosts")
+	vars := mux.Vars(r)
+	slug := vars[string(entity.SlugKey)]
+
+	moderator, ok := sessionModerator(w, r)
+	if !ok {
+		return
+	}
+
+	queryParams := r.URL.Query()
+	limit, since, desc, err := httputil.IDPageParams(queryParams)
+	if err != nil {
+		reportInfo.logger.Info(err.Error(), zap.String("url", r.RequestURI), zap.String("method", r.Method))
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	posts, err := reportInfo.reportApp.GetForumHeldPosts(slug, moderator, limit, since, desc)
+	if err != nil {
+		status := http.StatusInternalServerError
+		switch err {
+		case entity.ForumNotExistError:
+			status = http.StatusNotFound
+		case entity.NotModeratorError:
+			status = http.StatusForbidden
+		default:
+			reportInfo.logger.Info(
+				err.Error(), zap.String("url", r.RequestURI),
+				zap.String("method", r.Method))
+			w.WriteHeader(status)
+			return
+		}
+
+		httputil.WriteMessage(w, status, entity.Message{Text: err.Error()})
+		return
+	}
+
+	body, err := json.Marshal(posts)
+	if err != nil {
+		reportInfo.logger.Info(
+			err.Error(), zap.String("url", r.RequestURI),
+			zap.String("method", r.Method))
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	w.Write(body)
+}
+
+func (reportInfo *ReportInfo) HandleApprovePost(w http.ResponseWriter, r *http.Request) {
+	reportInfo.logger.Info("HandleApprovePost")
+	reportInfo.handleModeratePost(w, r, reportInfo.reportApp.ApprovePost)
+}
+
+func (reportInfo *ReportInfo) HandleRejectPost(w http.ResponseWriter, r *http.Request) {
+	reportInfo.logger.Info("HandleRejectPost")
+	reportInfo.handleModeratePost(w, r, reportInfo.reportApp.RejectPost)
+}
+
+// handleModeratePost applies moderator decision to held post and responds with the post
+func (reportInfo *ReportInfo) handleModeratePost(
+	w http.ResponseWriter, r *http.Request,
+	decide func(int, *entity.Moderation) (*entity.Post, error)) {
+	vars := mux.Vars(r)
+	idStr := vars[string(entity.IDKey)]
+
+	id, err := strconv.Atoi(idStr)
+	if err != nil {
+		reportInfo.logger.Info(err.Error(), zap.String("url", r.RequestURI), zap.String("method", r.Method))
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	moderator, ok := sessionModerator(w, r)
+	if !ok {
+		return
+	}
+
+	post, err := decide(id, &entity.Moderation{Moderator: moderator})
+	if err != nil {
+		status := http.StatusNotFound
+		msg := entity.Message{
+			Text: fmt.Sprintf("Can't find post with id: %v", id),
+		}
+		switch err {
+		case entity.NotModeratorError:
+			status = http.StatusForbidden
+			msg.Text = err.Error()
+		case entity.PostNotHeldError:
+			status = http.StatusConflict
+			msg.Text = err.Error()
+		}
+
+		httputil.WriteMessage(w, status, msg)
+		return
+	}
+
+	body, err := json.Marshal(post)
+	if err != nil {
+		reportInfo.logger.Info(
+			err.Error(), zap.String("url", r.RequestURI),
+			zap.String("method", r.Method))
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	w.Write(body)
+}
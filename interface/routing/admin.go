@@ -0,0 +1,23 @@
+package routing
+
+import (
+	"crypto/subtle"
+	"forum/domain/entity"
+	"forum/interface/httputil"
+	"net/http"
+	"strings"
+)
+
+// adminOnly lets through requests with "Authorization: Bearer <token>" header.
+// Admin endpoints are closed when the token is not configured
+func adminOnly(token string, next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		given := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if token == "" || subtle.ConstantTimeCompare([]byte(given), []byte(token)) != 1 {
+			httputil.WriteMessage(w, http.StatusForbidden, entity.Message{Text: "Admin token required"})
+			return
+		}
+
+		next(w, r)
+	}
+}
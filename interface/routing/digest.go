@@ -0,0 +1,20 @@
+package routing
+
+import (
+	"forum/app"
+	"go.uber.org/zap"
+	"time"
+)
+
+// runDigests mails notification digests every interval for the lifetime of the server
+func runDigests(subscriptionApp app.SubscriptionAppInterface, interval time.Duration, logger *zap.Logger) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		err := subscriptionApp.SendDigests()
+		if err != nil {
+			logger.Error("Could not send digests", zap.String("error", err.Error()))
+		}
+	}
+}
@@ -0,0 +1,264 @@
+package routing
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"forum/domain/entity"
+	"forum/interface/httputil"
+	"go.uber.org/zap"
+	"io/ioutil"
+	"math"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// bucketsCleanupPeriod is how often full buckets are dropped so that the map does not grow forever
+const bucketsCleanupPeriod = time.Minute
+
+type tokenBucket struct {
+	tokens float64
+	last   time.Time
+}
+
+// RateLimiter keeps a token bucket per key. Every bucket holds up to burst tokens
+// and is refilled with rate tokens per second, each request takes its tokens from the bucket
+type RateLimiter struct {
+	mu          sync.Mutex
+	rate        float64
+	burst       float64
+	buckets     map[string]*tokenBucket
+	lastCleanup time.Time
+}
+
+func NewRateLimiter(count int, period time.Duration) *RateLimiter {
+	return &RateLimiter{
+		rate:        float64(count) / period.Seconds(),
+		burst:       float64(count),
+		buckets:     make(map[string]*tokenBucket),
+		lastCleanup: time.Now(),
+	}
+}
+
+// parseRateLimit parses "<count>/<period>" setting like "30/1m", empty setting disables the limit
+func parseRateLimit(setting string) (*RateLimiter, error) {
+	if setting == "" {
+		return nil, nil
+	}
+
+	parts := strings.SplitN(setting, "/", 2)
+	if len(parts) != 2 {
+		return nil, fmt.Errorf("rate limit %q must look like count/period", setting)
+	}
+
+	count, err := strconv.Atoi(strings.TrimSpace(parts[0]))
+	if err != nil || count <= 0 {
+		return nil, fmt.Errorf("rate limit %q has wrong count", setting)
+	}
+	period, err := time.ParseDuration(strings.TrimSpace(parts[1]))
+	if err != nil || period <= 0 {
+		return nil, fmt.Errorf("rate limit %q has wrong period", setting)
+	}
+	return NewRateLimiter(count, period), nil
+}
+
+// bucket returns the refilled bucket of key, l.mu must be held
+func (l *RateLimiter) bucket(key string, now time.Time) *tokenBucket {
+	if now.Sub(l.lastCleanup) > bucketsCleanupPeriod {
+		l.cleanup(now)
+	}
+
+	bucket, ok := l.buckets[key]
+	if !ok {
+		bucket = &tokenBucket{tokens: l.burst, last: now}
+		l.buckets[key] = bucket
+	}
+
+	bucket.tokens = math.Min(l.burst, bucket.tokens+now.Sub(bucket.last).Seconds()*l.rate)
+	bucket.last = now
+	return bucket
+}
+
+// Wait returns the time left until the bucket of key holds tokens, zero when it holds them already.
+// Requests of more than burst tokens wait for a full bucket
+func (l *RateLimiter) Wait(key string, tokens float64) time.Duration {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	tokens = math.Min(tokens, l.burst)
+	bucket := l.bucket(key, time.Now())
+	if bucket.tokens < tokens {
+		return time.Duration((tokens - bucket.tokens) / l.rate * float64(time.Second))
+	}
+	return 0
+}
+
+// Take takes tokens from the bucket of key, it is called after Wait allowed the request
+func (l *RateLimiter) Take(key string, tokens float64) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	bucket := l.bucket(key, time.Now())
+	bucket.tokens = math.Max(0, bucket.tokens-tokens)
+}
+
+func (l *RateLimiter) cleanup(now time.Time) {
+	for key, bucket := range l.buckets {
+		if bucket.tokens+now.Sub(bucket.last).Seconds()*l.rate >= l.burst {
+			delete(l.buckets, key)
+		}
+	}
+	l.lastCleanup = now
+}
+
+// rateLimitKeys returns user keys of the request with the number of tokens each key takes
+type rateLimitKeys func(r *http.Request) map[string]int
+
+// rateLimitRule limits requests of one kind by user key and by client ip. Rules without keys
+// limit by client ip only. Nil limiters are disabled
+type rateLimitRule struct {
+	name   string
+	user   *RateLimiter
+	ip     *RateLimiter
+	keys   rateLimitKeys
+	logger *zap.Logger
+	// mu makes checking and taking tokens of all buckets of a request atomic
+	mu sync.Mutex
+}
+
+// rateLimitCharge is tokens a request takes from a bucket
+type rateLimitCharge struct {
+	limiter *RateLimiter
+	key     string
+	tokens  float64
+}
+
+// newRateLimitRule reads RATE_LIMIT_<NAME>_USER and RATE_LIMIT_<NAME>_IP settings
+func newRateLimitRule(name string, keys rateLimitKeys, logger *zap.Logger) *rateLimitRule {
+	rule := &rateLimitRule{name: name, keys: keys, logger: logger}
+
+	var err error
+	prefix := "RATE_LIMIT_" + strings.ToUpper(name)
+	if keys != nil {
+		rule.user, err = parseRateLimit(os.Getenv(prefix + "_USER"))
+		if err != nil {
+			logger.Fatal("Wrong rate limit setting", zap.String("error", err.Error()))
+		}
+	}
+	rule.ip, err = parseRateLimit(os.Getenv(prefix + "_IP"))
+	if err != nil {
+		logger.Fatal("Wrong rate limit setting", zap.String("error", err.Error()))
+	}
+	return rule
+}
+
+// middleware responds with 429 and Retry-After when any of request buckets lacks tokens.
+// Rejected requests take no tokens from any bucket
+func (rule *rateLimitRule) middleware(next http.HandlerFunc) http.HandlerFunc {
+	if rule.user == nil && rule.ip == nil {
+		return next
+	}
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		charges := rule.charges(r)
+
+		rule.mu.Lock()
+		for _, charge := range charges {
+			if wait := charge.limiter.Wait(charge.key, charge.tokens); wait > 0 {
+				rule.mu.Unlock()
+				rule.reject(w, r, wait)
+				return
+			}
+		}
+		for _, charge := range charges {
+			charge.limiter.Take(charge.key, charge.tokens)
+		}
+		rule.mu.Unlock()
+
+		next(w, r)
+	}
+}
+
+// charges lists tokens the request takes. Bucket of every user key takes tokens of the key,
+// the ip bucket takes tokens of all keys, so that a batch of posts counts as that many requests
+func (rule *rateLimitRule) charges(r *http.Request) []rateLimitCharge {
+	charges := make([]rateLimitCharge, 0)
+	total := 0
+	if rule.keys != nil {
+		for key, tokens := range rule.keys(r) {
+			total += tokens
+			if rule.user != nil {
+				charges = append(charges, rateLimitCharge{limiter: rule.user, key: key, tokens: float64(tokens)})
+			}
+		}
+	}
+	if total == 0 {
+		total = 1
+	}
+
+	if rule.ip != nil {
+		charges = append(charges, rateLimitCharge{limiter: rule.ip, key: httputil.ClientAddress(r), tokens: float64(total)})
+	}
+	return charges
+}
+
+func (rule *rateLimitRule) reject(w http.ResponseWriter, r *http.Request, wait time.Duration) {
+	rule.logger.Info("Rate limit exceeded", zap.String("limit", rule.name),
+		zap.String("url", r.RequestURI), zap.String("method", r.Method))
+
+	w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(wait.Seconds()))))
+	httputil.WriteMessage(w, http.StatusTooManyRequests, entity.Message{Text: "Too many requests"})
+}
+
+// peekBody reads request body leaving it in place for the handler
+func peekBody(r *http.Request) []byte {
+	data, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		return nil
+	}
+	r.Body = ioutil.NopCloser(bytes.NewReader(data))
+	return data
+}
+
+// postsAuthors returns authors of posts in request body, every post takes a token of its author
+func postsAuthors(r *http.Request) map[string]int {
+	posts := make([]entity.Post, 0)
+	if json.Unmarshal(peekBody(r), &posts) != nil {
+		return nil
+	}
+
+	authors := make(map[string]int, len(posts))
+	for _, post := range posts {
+		authors[strings.ToLower(post.Author)]++
+	}
+	return authors
+}
+
+func threadAuthor(r *http.Request) map[string]int {
+	thread := &entity.Thread{}
+	if json.Unmarshal(peekBody(r), thread) != nil {
+		return nil
+	}
+	return map[string]int{strings.ToLower(thread.Author): 1}
+}
+
+func voteNickname(r *http.Request) map[string]int {
+	vote := &entity.Vote{}
+	if json.Unmarshal(peekBody(r), vote) != nil {
+		return nil
+	}
+	return map[string]int{strings.ToLower(vote.Nickname): 1}
+}
+
+// resetEmail returns email of password reset request, buckets of the rule are kept per email
+func resetEmail(r *http.Request) map[string]int {
+	request := &entity.PasswordResetRequest{}
+	if json.Unmarshal(peekBody(r), request) != nil {
+		return nil
+	}
+	return map[string]int{strings.ToLower(request.Email): 1}
+}
@@ -0,0 +1,81 @@
+package routing
+
+import (
+	"go.uber.org/zap"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func postRequest(body string) *http.Request {
+	request := httptest.NewRequest(http.MethodPost, "/api/thread/1/create", strings.NewReader(body))
+	request.RemoteAddr = "192.0.2.1:1234"
+	return request
+}
+
+func serve(handler http.HandlerFunc, request *http.Request) int {
+	recorder := httptest.NewRecorder()
+	handler(recorder, request)
+	return recorder.Code
+}
+
+func okHandler(w http.ResponseWriter, r *http.Request) {
+	w.WriteHeader(http.StatusOK)
+}
+
+func TestPostsLimitTakesTokenPerPost(t *testing.T) {
+	rule := &rateLimitRule{name: "posts", user: NewRateLimiter(3, time.Hour), keys: postsAuthors, logger: zap.NewNop()}
+	handler := rule.middleware(okHandler)
+
+	if code := serve(handler, postRequest(`[{"author":"alice"},{"author":"Alice"}]`)); code != http.StatusOK {
+		t.Fatalf("first batch got %d, want %d", code, http.StatusOK)
+	}
+	if code := serve(handler, postRequest(`[{"author":"alice"},{"author":"alice"}]`)); code != http.StatusTooManyRequests {
+		t.Fatalf("batch over the limit got %d, want %d", code, http.StatusTooManyRequests)
+	}
+	if code := serve(handler, postRequest(`[{"author":"alice"}]`)); code != http.StatusOK {
+		t.Fatalf("post within the limit got %d, want %d", code, http.StatusOK)
+	}
+}
+
+func TestRejectedRequestTakesNoTokens(t *testing.T) {
+	rule := &rateLimitRule{
+		name:   "posts",
+		user:   NewRateLimiter(1, time.Hour),
+		ip:     NewRateLimiter(2, time.Hour),
+		keys:   postsAuthors,
+		logger: zap.NewNop(),
+	}
+	handler := rule.middleware(okHandler)
+
+	if code := serve(handler, postRequest(`[{"author":"alice"}]`)); code != http.StatusOK {
+		t.Fatalf("first post got %d, want %d", code, http.StatusOK)
+	}
+	// alice is out of tokens, the ip bucket must keep its last token for bob
+	if code := serve(handler, postRequest(`[{"author":"alice"}]`)); code != http.StatusTooManyRequests {
+		t.Fatalf("second post of alice got %d, want %d", code, http.StatusTooManyRequests)
+	}
+	if code := serve(handler, postRequest(`[{"author":"bob"}]`)); code != http.StatusOK {
+		t.Fatalf("post of bob got %d, want %d", code, http.StatusOK)
+	}
+}
+
+func TestRuleWithoutKeysLimitsByIP(t *testing.T) {
+	rule := &rateLimitRule{name: "users", ip: NewRateLimiter(1, time.Hour), logger: zap.NewNop()}
+	handler := rule.middleware(okHandler)
+
+	if code := serve(handler, postRequest(`{}`)); code != http.StatusOK {
+		t.Fatalf("first registration got %d, want %d", code, http.StatusOK)
+	}
+	if code := serve(handler, postRequest(`{}`)); code != http.StatusTooManyRequests {
+		t.Fatalf("second registration from the same ip got %d, want %d", code, http.StatusTooManyRequests)
+	}
+
+	other := postRequest(`{}`)
+	other.RemoteAddr = "192.0.2.2:1234"
+	if code := serve(handler, other); code != http.StatusOK {
+		t.Fatalf("registration from another ip got %d, want %d", code, http.StatusOK)
+	}
+}
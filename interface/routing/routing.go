@@ -2,13 +2,23 @@ package routing
 
 import (
 	"forum/app"
+	"forum/domain/entity"
+	"forum/domain/repository"
 	"forum/infrastructure"
+	"forum/interface/admin"
+	"forum/interface/auth"
 	"forum/interface/forum"
 	"forum/interface/post"
+	"forum/interface/report"
 	"forum/interface/service"
+	"forum/interface/subscription"
 	"forum/interface/thread"
 	"forum/interface/user"
 	"go.uber.org/zap"
+	"os"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/gorilla/mux"
 	"github.com/jackc/pgx/v4/pgxpool"
@@ -22,40 +32,210 @@ func CreateRouter(conn *pgxpool.Pool, logger *zap.Logger) *mux.Router {
 	repoPosts := infrastructure.NewPostRepository(conn)
 	repoService := infrastructure.NewServiceRepository(conn)
 	repoThreads := infrastructure.NewThreadRepository(conn)
+	repoReactions := infrastructure.NewReactionRepository(conn)
+	repoAttachments := infrastructure.NewAttachmentRepository(conn)
+	repoReports := infrastructure.NewReportRepository(conn)
+	repoAudit := infrastructure.NewAuditRepository(conn)
+	repoSessions := infrastructure.NewSessionRepository(conn)
+	repoFollows := infrastructure.NewFollowRepository(conn)
+	repoBlocks := infrastructure.NewBlockRepository(conn)
+	repoSubscriptions := infrastructure.NewSubscriptionRepository(conn)
 
-	postsApp := app.NewPostApp(repoPosts)
-	userApp := app.NewUserApp(repoUser)
-	serviceApp := app.NewServiceApp(repoService)
-	forumApp := app.NewForumApp(repoForum)
-	threadsApp := app.NewThreadApp(repoThreads, forumApp)
+	reactionTypes := entity.DefaultReactions
+	if reactionsSetting := os.Getenv("REACTIONS"); reactionsSetting != "" {
+		reactionTypes = splitSetting(reactionsSetting)
+	}
 
-	forumInfo := forum.NewForumInfo(forumApp, userApp, threadsApp, logger)
-	userInfo := user.NewUserInfo(userApp, logger)
+	attachmentsDir := os.Getenv("ATTACHMENTS_DIR")
+	if attachmentsDir == "" {
+		attachmentsDir = "attachments"
+	}
+	attachmentStorage, err := infrastructure.NewLocalBlobStorage(attachmentsDir)
+	if err != nil {
+		logger.Fatal("Could not create attachments storage", zap.String("error", err.Error()))
+	}
+
+	attachmentLimits := entity.AttachmentLimits{
+		MaxSize: entity.DefaultAttachmentMaxSize,
+		Types:   entity.DefaultAttachmentTypes,
+	}
+	if maxSize, err := strconv.ParseInt(os.Getenv("ATTACHMENT_MAX_SIZE"), 10, 64); err == nil && maxSize > 0 {
+		attachmentLimits.MaxSize = maxSize
+	}
+	if typesSetting := os.Getenv("ATTACHMENT_TYPES"); typesSetting != "" {
+		attachmentLimits.Types = splitSetting(typesSetting)
+	}
+
+	contentFilter := app.ContentFilterPipeline{app.NewWordFilter(repoForum)}
+	if window, err := time.ParseDuration(os.Getenv("DUPLICATE_POST_WINDOW")); err == nil && window > 0 {
+		contentFilter = append(contentFilter, app.NewDuplicateFilter(repoPosts, window))
+	}
+	newUserPeriod, periodErr := time.ParseDuration(os.Getenv("NEW_USER_PERIOD"))
+	maxLinks, linksErr := strconv.Atoi(os.Getenv("NEW_USER_MAX_LINKS"))
+	if periodErr == nil && linksErr == nil && newUserPeriod > 0 && maxLinks >= 0 {
+		contentFilter = append(contentFilter, app.NewLinkFilter(repoUser, newUserPeriod, maxLinks))
+	}
+
+	var mailer repository.Mailer
+	switch os.Getenv("MAIL_SENDER") {
+	case "smtp":
+		mailer = infrastructure.NewSMTPMailer(os.Getenv("SMTP_HOST"), os.Getenv("SMTP_PORT"),
+			os.Getenv("SMTP_USER"), os.Getenv("SMTP_PASSWORD"), os.Getenv("MAIL_FROM"))
+	case "", "log":
+		mailer = infrastructure.NewLogMailer(os.Getenv("MAIL_LOG_FILE"), os.Getenv("MAIL_FROM"), logger)
+	default:
+		logger.Fatal("Unknown mail sender", zap.String("sender", os.Getenv("MAIL_SENDER")))
+	}
+
+	auditApp := app.NewAuditApp(repoAudit)
+	reactionApp := app.NewReactionApp(repoReactions, reactionTypes)
+	attachmentApp := app.NewAttachmentApp(repoAttachments, repoForum, attachmentStorage, attachmentLimits, auditApp)
+	subscriptionApp := app.NewSubscriptionApp(repoSubscriptions, repoUser, mailer)
+	postsApp := app.NewPostApp(repoPosts, reactionApp, attachmentApp, auditApp, contentFilter)
+	// parse error leaves zero cooldown, old nicknames are free to take right away
+	nicknameCooldown, _ := time.ParseDuration(os.Getenv("NICKNAME_REUSE_COOLDOWN"))
+	emailTokenTTL, _ := time.ParseDuration(os.Getenv("EMAIL_TOKEN_TTL"))
+	emailApp := app.NewEmailApp(repoUser, mailer, auditApp, emailTokenTTL,
+		os.Getenv("EMAIL_CONFIRM_URL"), os.Getenv("PASSWORD_RESET_URL"))
+	userApp := app.NewUserApp(repoUser, auditApp, emailApp, nicknameCooldown)
+	sessionTTL, _ := time.ParseDuration(os.Getenv("SESSION_TTL"))
+	authApp := app.NewAuthApp(repoUser, repoSessions, emailApp, auditApp, sessionTTL, func(err error) {
+		logger.Error("Could not send password reset", zap.String("error", err.Error()))
+	})
+	serviceApp := app.NewServiceApp(repoService, attachmentStorage, auditApp)
+	forumApp := app.NewForumApp(repoForum, auditApp)
+	blockApp := app.NewBlockApp(repoBlocks, repoUser)
+	threadsApp := app.NewThreadApp(repoThreads, forumApp, postsApp, reactionApp, contentFilter, auditApp, blockApp)
+	followApp := app.NewFollowApp(repoFollows, repoUser)
+	exportApp := app.NewExportApp(repoUser, repoThreads, repoPosts, repoAudit, repoSubscriptions, auditApp)
+	reportApp := app.NewReportApp(repoReports, attachmentStorage, forumApp, postsApp, threadsApp, auditApp)
+
+	forumInfo := forum.NewForumInfo(forumApp, userApp, threadsApp, attachmentApp, logger)
+	userInfo := user.NewUserInfo(userApp, postsApp, threadsApp, exportApp, followApp, blockApp, logger)
 	serviceInfo := service.NewServiceInfo(serviceApp, logger)
-	postsInfo := post.NewPostInfo(postsApp, userApp, threadsApp, forumApp, logger)
-	threadsInfo := thread.NewThreadInfo(threadsApp, userApp, logger)
+	postsInfo := post.NewPostInfo(postsApp, userApp, threadsApp, forumApp, reactionApp, attachmentApp, logger)
+	threadsInfo := thread.NewThreadInfo(threadsApp, userApp, reactionApp, logger)
+	reportInfo := report.NewReportInfo(reportApp, userApp, logger)
+	adminInfo := admin.NewAdminInfo(auditApp, logger)
+	subscriptionInfo := subscription.NewSubscriptionInfo(subscriptionApp, threadsApp, forumApp, logger)
+	authInfo := auth.NewAuthInfo(authApp, os.Getenv("HTTPS_ON") == "true", logger)
+	adminToken := os.Getenv("ADMIN_TOKEN")
+
+	postsLimit := newRateLimitRule("posts", postsAuthors, logger)
+	threadsLimit := newRateLimitRule("threads", threadAuthor, logger)
+	votesLimit := newRateLimitRule("votes", voteNickname, logger)
+	// registrations are limited by client ip, nickname of a new user says nothing about who registers it
+	usersLimit := newRateLimitRule("users", nil, logger)
+	resetLimit := newRateLimitRule("password_reset", resetEmail, logger)
+
+	r.Use(sessionMiddleware(authApp, logger))
 
 	r.HandleFunc("/api/forum/create", forumInfo.HandleCreateForum).Methods("POST")
-	r.HandleFunc("/api/forum/{slug}/create", forumInfo.HandleCreateForumThread).Methods("POST")
+	r.HandleFunc("/api/forum/{slug}/create", threadsLimit.middleware(forumInfo.HandleCreateForumThread)).Methods("POST")
 	r.HandleFunc("/api/forum/{slug}/details", forumInfo.HandleGetForumDetails).Methods("GET")
 	r.HandleFunc("/api/forum/{slug}/users", forumInfo.HandleGetForumUsers).Methods("GET")
 	r.HandleFunc("/api/forum/{slug}/threads", forumInfo.HandleGetForumThreads).Methods("GET")
+	r.HandleFunc("/api/forum/{slug}/attachments/limits", forumInfo.HandleGetAttachmentLimits).Methods("GET")
+	r.HandleFunc("/api/forum/{slug}/attachments/limits", forumInfo.HandleSetAttachmentLimits).Methods("POST")
+	r.HandleFunc("/api/forum/{slug}/reports", reportInfo.HandleGetForumReports).Methods("GET")
+	r.HandleFunc("/api/forum/{slug}/held", reportInfo.HandleGetForumHeldPosts).Methods("GET")
+	r.HandleFunc("/api/forum/{slug}/filter", forumInfo.HandleGetWordFilter).Methods("GET")
+	r.HandleFunc("/api/forum/{slug}/filter", forumInfo.HandleSetWordFilter).Methods("POST")
 
 	r.HandleFunc("/api/post/{id}/details", postsInfo.HandleChangePost).Methods("POST")
 	r.HandleFunc("/api/post/{id}/details", postsInfo.HandleGetPostDetails).Methods("GET")
+	r.HandleFunc("/api/post/{id}/vote", votesLimit.middleware(postsInfo.HandleVoteForPost)).Methods("POST")
+	r.HandleFunc("/api/post/{id}/reactions", postsInfo.HandleAddPostReaction).Methods("POST")
+	r.HandleFunc("/api/post/{id}/reactions", postsInfo.HandleRemovePostReaction).Methods("DELETE")
+	r.HandleFunc("/api/post/{id}/attachments", postsInfo.HandleUploadAttachment).Methods("POST")
+	r.HandleFunc("/api/post/{id}/report", reportInfo.HandleReportPost).Methods("POST")
+	r.HandleFunc("/api/post/{id}/approve", reportInfo.HandleApprovePost).Methods("POST")
+	r.HandleFunc("/api/post/{id}/reject", reportInfo.HandleRejectPost).Methods("POST")
 
-	r.HandleFunc("/api/service/clear", serviceInfo.HandleClearData).Methods("POST")
+	r.HandleFunc("/api/attachment/{id}", postsInfo.HandleGetAttachment).Methods("GET")
+
+	r.HandleFunc("/api/report/{id}/resolve", reportInfo.HandleResolveReport).Methods("POST")
+
+	// clear is not even routed unless enabled explicitly
+	if os.Getenv("SERVICE_CLEAR_ENABLED") == "true" {
+		r.HandleFunc("/api/service/clear", adminOnly(adminToken, serviceInfo.HandleClearData)).Methods("POST")
+	}
 	r.HandleFunc("/api/service/status", serviceInfo.HandleGetDBStatus).Methods("GET")
+	r.HandleFunc("/api/service/status/extended", adminOnly(adminToken, serviceInfo.HandleGetExtendedStatus)).Methods("GET")
 
-	r.HandleFunc("/api/thread/{slug_or_id}/create", threadsInfo.HandleCreateThread).Methods("POST")
+	r.HandleFunc("/api/admin/audit", adminOnly(adminToken, adminInfo.HandleGetAuditLog)).Methods("GET")
+
+	r.HandleFunc("/api/thread/{slug_or_id}/create", postsLimit.middleware(threadsInfo.HandleCreateThread)).Methods("POST")
 	r.HandleFunc("/api/thread/{slug_or_id}/details", threadsInfo.HandleUpdateThread).Methods("POST")
-	r.HandleFunc("/api/thread/{slug_or_id}/vote", threadsInfo.HandleVoteForThread).Methods("POST")
+	r.HandleFunc("/api/thread/{slug_or_id}/vote", votesLimit.middleware(threadsInfo.HandleVoteForThread)).Methods("POST")
 	r.HandleFunc("/api/thread/{slug_or_id}/details", threadsInfo.HandleGetThreadDetails).Methods("GET")
 	r.HandleFunc("/api/thread/{slug_or_id}/posts", threadsInfo.HandleGetThreadPosts).Methods("GET")
+	r.HandleFunc("/api/thread/{slug_or_id}/read", threadsInfo.HandleMarkThreadRead).Methods("POST")
+	r.HandleFunc("/api/thread/{slug_or_id}/unread", threadsInfo.HandleGetFirstUnread).Methods("GET")
+	r.HandleFunc("/api/thread/{slug_or_id}/votes", threadsInfo.HandleGetThreadVotes).Methods("GET")
+	r.HandleFunc("/api/thread/{slug_or_id}/reactions", threadsInfo.HandleAddThreadReaction).Methods("POST")
+	r.HandleFunc("/api/thread/{slug_or_id}/reactions", threadsInfo.HandleRemoveThreadReaction).Methods("DELETE")
+	r.HandleFunc("/api/thread/{slug_or_id}/report", reportInfo.HandleReportThread).Methods("POST")
 
-	r.HandleFunc("/api/user/{nickname}/create", userInfo.HandleCreateUser).Methods("POST")
-	r.HandleFunc("/api/user/{nickname}/profile", userInfo.HandleUpdateUser).Methods("POST")
+	r.HandleFunc("/api/user/{nickname}/create", usersLimit.middleware(userInfo.HandleCreateUser)).Methods("POST")
+	r.HandleFunc("/api/user/{nickname}/profile", requireSelf(userInfo.HandleUpdateUser)).Methods("POST")
 	r.HandleFunc("/api/user/{nickname}/profile", userInfo.HandleGetUser).Methods("GET")
+	r.HandleFunc("/api/user/{nickname}/profile", requireSelf(userInfo.HandleDeleteUser)).Methods("DELETE")
+	r.HandleFunc("/api/user/{nickname}/votes", userInfo.HandleGetUserVotes).Methods("GET")
+	r.HandleFunc("/api/user/{nickname}/mentions", userInfo.HandleGetUserMentions).Methods("GET")
+	r.HandleFunc("/api/user/{nickname}/posts", userInfo.HandleGetUserPosts).Methods("GET")
+	r.HandleFunc("/api/user/{nickname}/threads", userInfo.HandleGetUserThreads).Methods("GET")
+	r.HandleFunc("/api/user/{nickname}/follow/{followee}", requireSelf(userInfo.HandleFollowUser)).Methods("POST")
+	r.HandleFunc("/api/user/{nickname}/follow/{followee}", requireSelf(userInfo.HandleUnfollowUser)).Methods("DELETE")
+	r.HandleFunc("/api/user/{nickname}/following", userInfo.HandleGetFollowing).Methods("GET")
+	r.HandleFunc("/api/user/{nickname}/followers", userInfo.HandleGetFollowers).Methods("GET")
+	r.HandleFunc("/api/user/{nickname}/block/{blocked}", requireSelf(userInfo.HandleBlockUser)).Methods("POST")
+	r.HandleFunc("/api/user/{nickname}/block/{blocked}", requireSelf(userInfo.HandleUnblockUser)).Methods("DELETE")
+	r.HandleFunc("/api/user/{nickname}/blocked", requireSelf(userInfo.HandleGetBlocked)).Methods("GET")
+	r.HandleFunc("/api/user/{nickname}/subscriptions", requireSelf(subscriptionInfo.HandleGetSubscriptions)).Methods("GET")
+	r.HandleFunc("/api/user/{nickname}/subscriptions/thread/{slug_or_id}",
+		requireSelf(subscriptionInfo.HandleSubscribeThread)).Methods("POST")
+	r.HandleFunc("/api/user/{nickname}/subscriptions/thread/{slug_or_id}",
+		requireSelf(subscriptionInfo.HandleUnsubscribeThread)).Methods("DELETE")
+	r.HandleFunc("/api/user/{nickname}/subscriptions/forum/{slug}",
+		requireSelf(subscriptionInfo.HandleSubscribeForum)).Methods("POST")
+	r.HandleFunc("/api/user/{nickname}/subscriptions/forum/{slug}",
+		requireSelf(subscriptionInfo.HandleUnsubscribeForum)).Methods("DELETE")
+	r.HandleFunc("/api/user/{nickname}/notifications", requireSelf(subscriptionInfo.HandleGetNotifications)).Methods("GET")
+	r.HandleFunc("/api/user/{nickname}/notifications/read",
+		requireSelf(subscriptionInfo.HandleMarkNotificationsRead)).Methods("POST")
+	r.HandleFunc("/api/user/{nickname}/notifications/settings",
+		requireSelf(subscriptionInfo.HandleGetNotificationSettings)).Methods("GET")
+	r.HandleFunc("/api/user/{nickname}/notifications/settings",
+		requireSelf(subscriptionInfo.HandleSetNotificationSettings)).Methods("POST")
+	r.HandleFunc("/api/user/{nickname}/feed", requireSelf(userInfo.HandleGetUserFeed)).Methods("GET")
+	r.HandleFunc("/api/user/{nickname}/rename", requireSelf(userInfo.HandleRenameUser)).Methods("POST")
+	r.HandleFunc("/api/user/{nickname}/export", requireSelf(userInfo.HandleExportUser)).Methods("GET")
+	r.HandleFunc("/api/user/{nickname}/privacy", requireSelf(userInfo.HandleGetUserPrivacy)).Methods("GET")
+	r.HandleFunc("/api/user/{nickname}/privacy", requireSelf(userInfo.HandleSetUserPrivacy)).Methods("POST")
+	r.HandleFunc("/api/user/{nickname}/email/verify", requireSelf(userInfo.HandleSendEmailVerification)).Methods("POST")
+	r.HandleFunc("/api/email/confirm", userInfo.HandleConfirmEmail).Methods("POST")
+	r.HandleFunc("/api/users", userInfo.HandleSearchUsers).Methods("GET")
+
+	r.HandleFunc("/api/session", authInfo.HandleLogin).Methods("POST")
+	r.HandleFunc("/api/session", authInfo.HandleGetSession).Methods("GET")
+	r.HandleFunc("/api/session", authInfo.HandleLogout).Methods("DELETE")
+	r.HandleFunc("/api/password/reset", resetLimit.middleware(authInfo.HandleRequestPasswordReset)).Methods("POST")
+	r.HandleFunc("/api/password/reset/confirm", authInfo.HandleResetPassword).Methods("POST")
+
+	// digests are off unless the interval is set
+	if digestInterval, err := time.ParseDuration(os.Getenv("DIGEST_INTERVAL")); err == nil && digestInterval > 0 {
+		go runDigests(subscriptionApp, digestInterval, logger)
+	}
 
 	return r
 }
+
+// splitSetting splits comma separated setting value
+func splitSetting(setting string) []string {
+	values := strings.Split(setting, ",")
+	for i := range values {
+		values[i] = strings.TrimSpace(values[i])
+	}
+	return values
+}
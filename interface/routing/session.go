@@ -0,0 +1,54 @@
+package routing
+
+import (
+	"context"
+	"errors"
+	"forum/app"
+	"forum/domain/entity"
+	"forum/interface/httputil"
+	"github.com/gorilla/mux"
+	"go.uber.org/zap"
+	"net/http"
+	"strings"
+)
+
+// sessionMiddleware puts session of the cookie into request context under entity.CookieInfoKey.
+// Requests without a valid session pass through as anonymous
+func sessionMiddleware(authApp app.AuthAppInterface, logger *zap.Logger) mux.MiddlewareFunc {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			cookie, err := r.Cookie(entity.CookieNameKey)
+			if err != nil {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			session, err := authApp.GetSession(cookie.Value)
+			if err != nil {
+				if !errors.Is(err, entity.SessionNotFoundError) {
+					logger.Info(err.Error(), zap.String("url", r.RequestURI), zap.String("method", r.Method))
+				}
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), entity.CookieInfoKey, session)))
+		})
+	}
+}
+
+// requireSelf lets through only requests whose session user is the {nickname} of the path,
+// anonymous requests get 401 and sessions of other users get 403
+func requireSelf(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		viewer := entity.ViewerNickname(r.Context())
+		switch {
+		case viewer == "":
+			httputil.WriteMessage(w, http.StatusUnauthorized, entity.Message{Text: entity.NoSessionError.Error()})
+		case !strings.EqualFold(viewer, mux.Vars(r)["nickname"]):
+			httputil.WriteMessage(w, http.StatusForbidden, entity.Message{Text: entity.NotSelfError.Error()})
+		default:
+			next(w, r)
+		}
+	}
+}
@@ -0,0 +1,45 @@
+package routing
+
+import (
+	"context"
+	"forum/domain/entity"
+	"github.com/gorilla/mux"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestRequireSelf(t *testing.T) {
+	cases := []struct {
+		name   string
+		viewer string
+		path   string
+		status int
+	}{
+		{name: "anonymous", viewer: "", path: "/api/user/alice/profile", status: http.StatusUnauthorized},
+		{name: "other user", viewer: "bob", path: "/api/user/alice/profile", status: http.StatusForbidden},
+		{name: "self", viewer: "alice", path: "/api/user/alice/profile", status: http.StatusOK},
+		{name: "self in other case", viewer: "Alice", path: "/api/user/ALICE/profile", status: http.StatusOK},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			r := mux.NewRouter()
+			r.HandleFunc("/api/user/{nickname}/profile", requireSelf(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusOK)
+			}))
+
+			request := httptest.NewRequest(http.MethodPost, c.path, nil)
+			if c.viewer != "" {
+				session := &entity.Session{Nickname: c.viewer}
+				request = request.WithContext(context.WithValue(request.Context(), entity.CookieInfoKey, session))
+			}
+			recorder := httptest.NewRecorder()
+			r.ServeHTTP(recorder, request)
+
+			if recorder.Code != c.status {
+				t.Errorf("status is %d, want %d", recorder.Code, c.status)
+			}
+		})
+	}
+}
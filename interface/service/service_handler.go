@@ -1,12 +1,16 @@
 package service
 
 import (
+	"bytes"
 	"encoding/json"
 	"fmt"
 	"forum/app"
 	"forum/domain/entity"
 	"go.uber.org/zap"
+	"io/ioutil"
+	"net"
 	"net/http"
+	"strconv"
 )
 
 type ServiceInfo struct {
@@ -23,8 +27,45 @@ func NewServiceInfo(ServiceApp app.ServiceAppInterface, logger *zap.Logger) *Ser
 
 func (serviceInfo *ServiceInfo) HandleClearData(w http.ResponseWriter, r *http.Request) {
 	serviceInfo.logger.Info("HandleClearData")
-	err := serviceInfo.ServiceApp.ClearAllDate()
+
+	// empty body clears everything
+	request := &entity.ClearRequest{}
+	data, err := ioutil.ReadAll(r.Body)
 	if err != nil {
+		serviceInfo.logger.Info(
+			err.Error(), zap.String("url", r.RequestURI),
+			zap.String("method", r.Method))
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	if len(bytes.TrimSpace(data)) != 0 {
+		err = json.Unmarshal(data, request)
+		if err != nil {
+			serviceInfo.logger.Info(
+				err.Error(), zap.String("url", r.RequestURI),
+				zap.String("method", r.Method))
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+	}
+
+	// the endpoint is available to admins only, actor is the admin address
+	address, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		address = r.RemoteAddr
+	}
+
+	err = serviceInfo.ServiceApp.ClearData("admin@"+address, request)
+	if err != nil {
+		status := http.StatusInternalServerError
+		switch err {
+		case entity.WrongClearScopeError:
+			status = http.StatusBadRequest
+		case entity.ForumNotExistError:
+			status = http.StatusNotFound
+		}
+
 		msg := entity.Message{
 			Text: fmt.Sprintf(`{"messege": "%s"}`, err.Error()),
 		}
@@ -35,7 +76,7 @@ func (serviceInfo *ServiceInfo) HandleClearData(w http.ResponseWriter, r *http.R
 		}
 
 		w.Header().Set("Content-Type", "application/json")
-		w.WriteHeader(http.StatusInternalServerError)
+		w.WriteHeader(status)
 		w.Write(body)
 		return
 	}
@@ -87,3 +128,37 @@ func (serviceInfo *ServiceInfo) HandleGetDBStatus(w http.ResponseWriter, r *http
 	w.Write(body)
 	return
 }
+
+func (serviceInfo *ServiceInfo) HandleGetExtendedStatus(w http.ResponseWriter, r *http.Request) {
+	serviceInfo.logger.Info("HandleGetExtendedStatus")
+
+	limit := 0
+	if limitParam := r.URL.Query().Get(string(entity.LimitKey)); limitParam != "" {
+		var err error
+		limit, err = strconv.Atoi(limitParam)
+		if err != nil {
+			serviceInfo.logger.Info(err.Error(), zap.String("url", r.RequestURI), zap.String("method", r.Method))
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+	}
+
+	status, err := serviceInfo.ServiceApp.GetExtendedStatus(int32(limit))
+	if err != nil {
+		serviceInfo.logger.Info(
+			err.Error(), zap.String("url", r.RequestURI),
+			zap.String("method", r.Method))
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	body, err := json.Marshal(status)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	w.Write(body)
+}
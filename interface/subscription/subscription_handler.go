@@ -0,0 +1,211 @@
+package subscription
+
+import (
+	"errors"
+	"fmt"
+	"forum/app"
+	"forum/domain/entity"
+	"forum/interface/httputil"
+	"github.com/gorilla/mux"
+	"go.uber.org/zap"
+	"net/http"
+	"strconv"
+)
+
+type SubscriptionInfo struct {
+	subscriptionApp app.SubscriptionAppInterface
+	threadApp       app.ThreadAppInterface
+	forumApp        app.ForumAppInterface
+	logger          *zap.Logger
+}
+
+func NewSubscriptionInfo(subscriptionApp app.SubscriptionAppInterface,
+	threadApp app.ThreadAppInterface,
+	forumApp app.ForumAppInterface,
+	logger *zap.Logger) *SubscriptionInfo {
+	return &SubscriptionInfo{
+		subscriptionApp: subscriptionApp,
+		threadApp:       threadApp,
+		forumApp:        forumApp,
+		logger:          logger,
+	}
+}
+
+func (subscriptionInfo *SubscriptionInfo) HandleSubscribeThread(w http.ResponseWriter, r *http.Request) {
+	subscriptionInfo.logger.Info("HandleSubscribeThread")
+	subscriptionInfo.handleThreadSubscription(w, r, subscriptionInfo.subscriptionApp.SubscribeThread)
+}
+
+func (subscriptionInfo *SubscriptionInfo) HandleUnsubscribeThread(w http.ResponseWriter, r *http.Request) {
+	subscriptionInfo.logger.Info("HandleUnsubscribeThread")
+	subscriptionInfo.handleThreadSubscription(w, r, subscriptionInfo.subscriptionApp.UnsubscribeThread)
+}
+
+func (subscriptionInfo *SubscriptionInfo) handleThreadSubscription(w http.ResponseWriter,
+	r *http.Request,
+	change func(nickname string, threadID int) error) {
+	vars := mux.Vars(r)
+	slugOrID := vars[string(entity.SlugOrIDKey)]
+
+	thread, err := subscriptionInfo.threadApp.GetThreadForumAndID(slugOrID)
+	if err != nil {
+		httputil.WriteMessage(w, http.StatusNotFound, entity.Message{
+			Text: fmt.Sprintf("Can't find thread by slug: %v", slugOrID),
+		})
+		return
+	}
+
+	err = change(vars[string(entity.NicknameKey)], thread.ID)
+	subscriptionInfo.writeChangeResult(w, r, err)
+}
+
+func (subscriptionInfo *SubscriptionInfo) HandleSubscribeForum(w http.ResponseWriter, r *http.Request) {
+	subscriptionInfo.logger.Info("HandleSubscribeForum")
+	subscriptionInfo.handleForumSubscription(w, r, subscriptionInfo.subscriptionApp.SubscribeForum)
+}
+
+func (subscriptionInfo *SubscriptionInfo) HandleUnsubscribeForum(w http.ResponseWriter, r *http.Request) {
+	subscriptionInfo.logger.Info("HandleUnsubscribeForum")
+	subscriptionInfo.handleForumSubscription(w, r, subscriptionInfo.subscriptionApp.UnsubscribeForum)
+}
+
+func (subscriptionInfo *SubscriptionInfo) handleForumSubscription(w http.ResponseWriter,
+	r *http.Request,
+	change func(nickname string, slug string) error) {
+	vars := mux.Vars(r)
+
+	slug, err := subscriptionInfo.forumApp.CheckForumCase(vars[string(entity.SlugKey)])
+	if err != nil {
+		httputil.WriteMessage(w, http.StatusNotFound, entity.Message{
+			Text: fmt.Sprintf("Can't find forum with slug: %v", vars[string(entity.SlugKey)]),
+		})
+		return
+	}
+
+	err = change(vars[string(entity.NicknameKey)], slug)
+	subscriptionInfo.writeChangeResult(w, r, err)
+}
+
+func (subscriptionInfo *SubscriptionInfo) writeChangeResult(w http.ResponseWriter, r *http.Request, err error) {
+	if errors.Is(err, entity.UserDoesntExistsError) {
+		httputil.WriteMessage(w, http.StatusNotFound, entity.Message{Text: err.Error()})
+		return
+	}
+	if err != nil {
+		subscriptionInfo.logger.Info(
+			err.Error(), zap.String("url", r.RequestURI),
+			zap.String("method", r.Method))
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (subscriptionInfo *SubscriptionInfo) HandleGetSubscriptions(w http.ResponseWriter, r *http.Request) {
+	subscriptionInfo.logger.Info("HandleGetSubscriptions")
+	nickname := mux.Vars(r)[string(entity.NicknameKey)]
+
+	subscriptions, err := subscriptionInfo.subscriptionApp.GetSubscriptions(nickname)
+	if err != nil {
+		subscriptionInfo.writeError(w, r, err)
+		return
+	}
+
+	httputil.WriteJSON(w, http.StatusOK, subscriptions)
+}
+
+// HandleGetNotifications lists notifications paginated by id, unread=true leaves out read ones
+func (subscriptionInfo *SubscriptionInfo) HandleGetNotifications(w http.ResponseWriter, r *http.Request) {
+	subscriptionInfo.logger.Info("HandleGetNotifications")
+	nickname := mux.Vars(r)[string(entity.NicknameKey)]
+	queryParams := r.URL.Query()
+
+	limit := 0
+	var err error
+	if limitParam := queryParams.Get(string(entity.LimitKey)); limitParam != "" {
+		limit, err = strconv.Atoi(limitParam)
+	}
+	since := queryParams.Get(string(entity.SinceKey))
+	if err == nil && since != "" {
+		_, err = strconv.Atoi(since)
+	}
+	if err != nil {
+		subscriptionInfo.logger.Info(err.Error(), zap.String("url", r.RequestURI), zap.String("method", r.Method))
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	desc := queryParams.Get(string(entity.DescKey)) == "true"
+	unread := queryParams.Get(string(entity.UnreadKey)) == "true"
+
+	notifications, err := subscriptionInfo.subscriptionApp.GetNotifications(nickname, int32(limit), since, desc, unread)
+	if err != nil {
+		subscriptionInfo.writeError(w, r, err)
+		return
+	}
+
+	httputil.WriteJSON(w, http.StatusOK, notifications)
+}
+
+func (subscriptionInfo *SubscriptionInfo) HandleMarkNotificationsRead(w http.ResponseWriter, r *http.Request) {
+	subscriptionInfo.logger.Info("HandleMarkNotificationsRead")
+	nickname := mux.Vars(r)[string(entity.NicknameKey)]
+
+	read := &entity.NotificationsRead{}
+	if !httputil.ReadJSON(w, r, read, subscriptionInfo.logger) {
+		return
+	}
+
+	err := subscriptionInfo.subscriptionApp.MarkNotificationsRead(nickname, read.IDs)
+	if err != nil {
+		subscriptionInfo.writeError(w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (subscriptionInfo *SubscriptionInfo) HandleGetNotificationSettings(w http.ResponseWriter, r *http.Request) {
+	subscriptionInfo.logger.Info("HandleGetNotificationSettings")
+	nickname := mux.Vars(r)[string(entity.NicknameKey)]
+
+	settings, err := subscriptionInfo.subscriptionApp.GetNotificationSettings(nickname)
+	if err != nil {
+		subscriptionInfo.writeError(w, r, err)
+		return
+	}
+
+	httputil.WriteJSON(w, http.StatusOK, settings)
+}
+
+func (subscriptionInfo *SubscriptionInfo) HandleSetNotificationSettings(w http.ResponseWriter, r *http.Request) {
+	subscriptionInfo.logger.Info("HandleSetNotificationSettings")
+	nickname := mux.Vars(r)[string(entity.NicknameKey)]
+
+	settings := &entity.NotificationSettings{}
+	if !httputil.ReadJSON(w, r, settings, subscriptionInfo.logger) {
+		return
+	}
+
+	err := subscriptionInfo.subscriptionApp.SetNotificationSettings(nickname, settings)
+	if err != nil {
+		subscriptionInfo.writeError(w, r, err)
+		return
+	}
+
+	httputil.WriteJSON(w, http.StatusOK, settings)
+}
+
+func (subscriptionInfo *SubscriptionInfo) writeError(w http.ResponseWriter, r *http.Request, err error) {
+	if errors.Is(err, entity.UserDoesntExistsError) {
+		httputil.WriteMessage(w, http.StatusNotFound, entity.Message{
+			Text: fmt.Sprintf("Can't find user with id #%v\n", mux.Vars(r)[string(entity.NicknameKey)]),
+		})
+		return
+	}
+
+	subscriptionInfo.logger.Info(
+		err.Error(), zap.String("url", r.RequestURI),
+		zap.String("method", r.Method))
+	w.WriteHeader(http.StatusInternalServerError)
+}
@@ -2,9 +2,11 @@ package thread
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"forum/app"
 	"forum/domain/entity"
+	"forum/interface/httputil"
 	"github.com/gorilla/mux"
 	"go.uber.org/zap"
 	"io/ioutil"
@@ -13,19 +15,22 @@ import (
 )
 
 type ThreadInfo struct {
-	ThreadApp app.ThreadAppInterface
-	userApp   app.UserAppInterface
-	logger    *zap.Logger
+	ThreadApp   app.ThreadAppInterface
+	userApp     app.UserAppInterface
+	reactionApp app.ReactionAppInterface
+	logger      *zap.Logger
 }
 
 func NewThreadInfo(
 	ThreadApp app.ThreadAppInterface,
 	userApp app.UserAppInterface,
+	reactionApp app.ReactionAppInterface,
 	logger *zap.Logger) *ThreadInfo {
 	return &ThreadInfo{
-		ThreadApp: ThreadApp,
-		userApp:   userApp,
-		logger:    logger,
+		ThreadApp:   ThreadApp,
+		userApp:     userApp,
+		reactionApp: reactionApp,
+		logger:      logger,
 	}
 }
 
@@ -107,9 +112,29 @@ func (threadInfo *ThreadInfo) HandleCreateThread(w http.ResponseWriter, r *http.
 
 	err = threadInfo.ThreadApp.CreatePosts(thread, posts)
 	if err != nil {
+		status := http.StatusConflict
 		msg := entity.Message{
 			Text: fmt.Sprintf("Parent post was created in another thread"),
 		}
+		if err == entity.QuotedPostNotFoundError {
+			status = http.StatusNotFound
+			msg.Text = err.Error()
+		}
+		if err == entity.WrongFormatError {
+			status = http.StatusBadRequest
+			msg.Text = err.Error()
+		}
+		if err == entity.UserBannedError {
+			status = http.StatusForbidden
+			msg.Text = err.Error()
+		}
+		if err == entity.BannedWordsError {
+			status = http.StatusBadRequest
+			msg.Text = err.Error()
+		}
+		if err == entity.DuplicatePostError {
+			msg.Text = err.Error()
+		}
 		body, err := json.Marshal(msg)
 		if err != nil {
 			w.WriteHeader(http.StatusInternalServerError)
@@ -117,7 +142,7 @@ func (threadInfo *ThreadInfo) HandleCreateThread(w http.ResponseWriter, r *http.
 		}
 
 		w.Header().Set("Content-Type", "application/json")
-		w.WriteHeader(http.StatusConflict)
+		w.WriteHeader(status)
 		w.Write(body)
 		return
 	}
@@ -225,7 +250,22 @@ func (threadInfo *ThreadInfo) HandleUpdateThread(w http.ResponseWriter, r *http.
 			return
 		}
 	} else {
-		err = threadInfo.ThreadApp.UpdateThread(slugOrID, thread)
+		err = threadInfo.ThreadApp.UpdateThread(slugOrID, thread, httputil.AuditActor(r))
+		if err == entity.WrongFormatError {
+			msg := entity.Message{
+				Text: err.Error(),
+			}
+			body, err := json.Marshal(msg)
+			if err != nil {
+				w.WriteHeader(http.StatusInternalServerError)
+				return
+			}
+
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write(body)
+			return
+		}
 		if err != nil {
 			threadInfo.logger.Info(
 				err.Error(), zap.String("url", r.RequestURI),
@@ -293,7 +333,8 @@ func (threadInfo *ThreadInfo) HandleGetThreadPosts(w http.ResponseWriter, r *htt
 	descParam, _ := queryParams[string(entity.DescKey)]
 	desc := false
 	if descParam == nil {
-		desc = false
+		// top posts go first unless desc=false is given explicitly
+		desc = sort == "top"
 	} else {
 		if descParam[0] == "true" {
 			desc = true
@@ -308,7 +349,9 @@ func (threadInfo *ThreadInfo) HandleGetThreadPosts(w http.ResponseWriter, r *htt
 		since = sinceParam[0]
 	}
 
-	posts, err := threadInfo.ThreadApp.GetThreadPosts(slugOrID, int32(limit), since, sort, desc)
+	// anonymous requests see posts of all users
+	viewer := entity.ViewerNickname(r.Context())
+	posts, err := threadInfo.ThreadApp.GetThreadPosts(slugOrID, int32(limit), since, sort, desc, viewer)
 	if err != nil {
 		threadInfo.logger.Info(
 			err.Error(), zap.String("url", r.RequestURI),
@@ -365,9 +408,14 @@ func (threadInfo *ThreadInfo) HandleVoteForThread(w http.ResponseWriter, r *http
 
 	thread, err := threadInfo.ThreadApp.VoteForThread(vote)
 	if err != nil {
+		status := http.StatusNotFound
 		msg := entity.Message{
 			Text: fmt.Sprintf("Can't find thread by slug: %v", slugOrID),
 		}
+		if err == entity.WrongVoiceError {
+			status = http.StatusBadRequest
+			msg.Text = err.Error()
+		}
 		body, err := json.Marshal(msg)
 		if err != nil {
 			w.WriteHeader(http.StatusInternalServerError)
@@ -375,7 +423,7 @@ func (threadInfo *ThreadInfo) HandleVoteForThread(w http.ResponseWriter, r *http
 		}
 
 		w.Header().Set("Content-Type", "application/json")
-		w.WriteHeader(http.StatusNotFound)
+		w.WriteHeader(status)
 		w.Write(body)
 		return
 	}
@@ -394,3 +442,250 @@ func (threadInfo *ThreadInfo) HandleVoteForThread(w http.ResponseWriter, r *http
 	w.Write(body)
 	return
 }
+
+func (threadInfo *ThreadInfo) HandleGetThreadVotes(w http.ResponseWriter, r *http.Request) {
+	threadInfo.logger.Info("HandleGetThreadVotes")
+	vars := mux.Vars(r)
+	slugOrID := vars[string(entity.SlugOrIDKey)]
+
+	queryParams := r.URL.Query()
+	limit, since, desc, err := httputil.TimePageParams(queryParams)
+	if err != nil {
+		threadInfo.logger.Info(err.Error(), zap.String("url", r.RequestURI), zap.String("method", r.Method))
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	page, err := threadInfo.ThreadApp.GetThreadVotes(slugOrID, limit, since, queryParams.Get(string(entity.CursorKey)), desc)
+	if err != nil {
+		if errors.Is(err, entity.WrongCursorError) {
+			httputil.WriteMessage(w, http.StatusBadRequest, entity.Message{Text: err.Error()})
+			return
+		}
+		httputil.WriteMessage(w, http.StatusNotFound, entity.Message{
+			Text: fmt.Sprintf("Can't find thread by slug: %v", slugOrID),
+		})
+		return
+	}
+
+	httputil.WriteJSON(w, http.StatusOK, page)
+}
+
+func (threadInfo *ThreadInfo) HandleAddThreadReaction(w http.ResponseWriter, r *http.Request) {
+	threadInfo.logger.Info("HandleAddThreadReaction")
+	threadInfo.handleThreadReaction(w, r, threadInfo.reactionApp.AddReaction)
+}
+
+func (threadInfo *ThreadInfo) HandleRemoveThreadReaction(w http.ResponseWriter, r *http.Request) {
+	threadInfo.logger.Info("HandleRemoveThreadReaction")
+	threadInfo.handleThreadReaction(w, r, threadInfo.reactionApp.RemoveReaction)
+}
+
+// handleThreadReaction applies action to reaction from request body and responds with updated thread
+func (threadInfo *ThreadInfo) handleThreadReaction(w http.ResponseWriter, r *http.Request, action func(*entity.Reaction) error) {
+	vars := mux.Vars(r)
+	slugOrID := vars[string(entity.SlugOrIDKey)]
+
+	thread, err := threadInfo.ThreadApp.GetThreadForumAndID(slugOrID)
+	if err != nil {
+		msg := entity.Message{
+			Text: fmt.Sprintf("Can't find thread by slug: %v", slugOrID),
+		}
+		body, err := json.Marshal(msg)
+		if err != nil {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusNotFound)
+		w.Write(body)
+		return
+	}
+
+	reaction := &entity.Reaction{}
+	data, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		threadInfo.logger.Info(
+			err.Error(), zap.String("url", r.RequestURI),
+			zap.String("method", r.Method))
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	err = json.Unmarshal(data, reaction)
+	if err != nil {
+		threadInfo.logger.Info(
+			err.Error(), zap.String("url", r.RequestURI),
+			zap.String("method", r.Method))
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	reaction.Thread = thread.ID
+
+	reaction.Nickname, err = threadInfo.userApp.CheckIfUserExists(reaction.Nickname)
+	if err != nil {
+		msg := entity.Message{
+			Text: fmt.Sprintf("Can't find user with id #%v\n", reaction.Nickname),
+		}
+		body, err := json.Marshal(msg)
+		if err != nil {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusNotFound)
+		w.Write(body)
+		return
+	}
+
+	err = action(reaction)
+	if err != nil {
+		if err == entity.WrongReactionError {
+			msg := entity.Message{
+				Text: err.Error(),
+			}
+			body, err := json.Marshal(msg)
+			if err != nil {
+				w.WriteHeader(http.StatusInternalServerError)
+				return
+			}
+
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write(body)
+			return
+		}
+
+		threadInfo.logger.Info(
+			err.Error(), zap.String("url", r.RequestURI),
+			zap.String("method", r.Method))
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	updatedThread, err := threadInfo.ThreadApp.GetThread(strconv.Itoa(thread.ID))
+	if err != nil {
+		threadInfo.logger.Info(
+			err.Error(), zap.String("url", r.RequestURI),
+			zap.String("method", r.Method))
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	body, err := json.Marshal(updatedThread)
+	if err != nil {
+		threadInfo.logger.Info(
+			err.Error(), zap.String("url", r.RequestURI),
+			zap.String("method", r.Method))
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	w.Write(body)
+}
+
+// HandleMarkThreadRead saves the last read post of the session user, empty body or zero post marks the whole thread
+func (threadInfo *ThreadInfo) HandleMarkThreadRead(w http.ResponseWriter, r *http.Request) {
+	threadInfo.logger.Info("HandleMarkThreadRead")
+	slugOrID := mux.Vars(r)[string(entity.SlugOrIDKey)]
+
+	viewer := entity.ViewerNickname(r.Context())
+	if viewer == "" {
+		httputil.WriteMessage(w, http.StatusUnauthorized, entity.Message{Text: entity.NoSessionError.Error()})
+		return
+	}
+
+	err := threadInfo.ThreadApp.CheckThread(slugOrID)
+	if err != nil {
+		httputil.WriteMessage(w, http.StatusNotFound, entity.Message{
+			Text: fmt.Sprintf("Can't find thread by slug: %v", slugOrID),
+		})
+		return
+	}
+
+	read := &entity.ThreadRead{}
+	data, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		threadInfo.logger.Info(
+			err.Error(), zap.String("url", r.RequestURI),
+			zap.String("method", r.Method))
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	if len(data) != 0 {
+		err = json.Unmarshal(data, read)
+		if err != nil {
+			threadInfo.logger.Info(
+				err.Error(), zap.String("url", r.RequestURI),
+				zap.String("method", r.Method))
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+	}
+
+	err = threadInfo.ThreadApp.MarkThreadRead(slugOrID, viewer, read.Post)
+	if err != nil {
+		if err == entity.PostNotInThreadError {
+			httputil.WriteMessage(w, http.StatusBadRequest, entity.Message{Text: err.Error()})
+			return
+		}
+
+		threadInfo.logger.Info(
+			err.Error(), zap.String("url", r.RequestURI),
+			zap.String("method", r.Method))
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// HandleGetFirstUnread returns the first unread post of the session user and its position for sort flat or tree
+func (threadInfo *ThreadInfo) HandleGetFirstUnread(w http.ResponseWriter, r *http.Request) {
+	threadInfo.logger.Info("HandleGetFirstUnread")
+	slugOrID := mux.Vars(r)[string(entity.SlugOrIDKey)]
+
+	viewer := entity.ViewerNickname(r.Context())
+	if viewer == "" {
+		httputil.WriteMessage(w, http.StatusUnauthorized, entity.Message{Text: entity.NoSessionError.Error()})
+		return
+	}
+
+	err := threadInfo.ThreadApp.CheckThread(slugOrID)
+	if err != nil {
+		httputil.WriteMessage(w, http.StatusNotFound, entity.Message{
+			Text: fmt.Sprintf("Can't find thread by slug: %v", slugOrID),
+		})
+		return
+	}
+
+	unread, err := threadInfo.ThreadApp.GetFirstUnread(slugOrID, viewer, r.URL.Query().Get(string(entity.SortKey)))
+	if err != nil {
+		switch err {
+		case entity.WrongUnreadSortError:
+			httputil.WriteMessage(w, http.StatusBadRequest, entity.Message{Text: err.Error()})
+		case entity.NoUnreadPostsError:
+			httputil.WriteMessage(w, http.StatusNotFound, entity.Message{Text: err.Error()})
+		default:
+			threadInfo.logger.Info(
+				err.Error(), zap.String("url", r.RequestURI),
+				zap.String("method", r.Method))
+			w.WriteHeader(http.StatusInternalServerError)
+		}
+		return
+	}
+
+	body, err := json.Marshal(unread)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	w.Write(body)
+}
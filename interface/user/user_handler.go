@@ -6,22 +6,43 @@ import (
 	"fmt"
 	"forum/app"
 	"forum/domain/entity"
+	"forum/interface/httputil"
 	"github.com/gorilla/mux"
 	"go.uber.org/zap"
 	"io/ioutil"
+	"mime"
 	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
 )
 
 type UserInfo struct {
-	userApp app.UserAppInterface
-	logger  *zap.Logger
+	userApp   app.UserAppInterface
+	postApp   app.PostAppInterface
+	threadApp app.ThreadAppInterface
+	exportApp app.ExportAppInterface
+	followApp app.FollowAppInterface
+	blockApp  app.BlockAppInterface
+	logger    *zap.Logger
 }
 
 func NewUserInfo(userApp app.UserAppInterface,
+	postApp app.PostAppInterface,
+	threadApp app.ThreadAppInterface,
+	exportApp app.ExportAppInterface,
+	followApp app.FollowAppInterface,
+	blockApp app.BlockAppInterface,
 	logger *zap.Logger) *UserInfo {
 	return &UserInfo{
-		userApp: userApp,
-		logger:  logger,
+		userApp:   userApp,
+		postApp:   postApp,
+		threadApp: threadApp,
+		exportApp: exportApp,
+		followApp: followApp,
+		blockApp:  blockApp,
+		logger:    logger,
 	}
 }
 
@@ -52,6 +73,15 @@ func (userInfo *UserInfo) HandleCreateUser(w http.ResponseWriter, r *http.Reques
 	user.Nickname = nickname
 
 	err = userInfo.userApp.CreateUser(user)
+	if errors.Is(err, entity.WrongEmailError) || errors.Is(err, entity.WeakPasswordError) ||
+		errors.Is(err, entity.DeletedNicknameError) {
+		httputil.WriteMessage(w, http.StatusBadRequest, entity.Message{Text: err.Error()})
+		return
+	}
+	if errors.Is(err, entity.NicknameReservedError) {
+		httputil.WriteMessage(w, http.StatusConflict, entity.Message{Text: err.Error()})
+		return
+	}
 	if err != nil {
 		users, err := userInfo.userApp.GetUsersWithNicknameAndEmail(nickname, user.Email)
 		if err != nil {
@@ -77,6 +107,14 @@ func (userInfo *UserInfo) HandleCreateUser(w http.ResponseWriter, r *http.Reques
 		return
 	}
 
+	// the user is created already, failed verification mail can be requested again
+	err = userInfo.userApp.SendEmailVerification(nickname)
+	if err != nil {
+		userInfo.logger.Info(
+			err.Error(), zap.String("url", r.RequestURI),
+			zap.String("method", r.Method))
+	}
+
 	body, err := json.Marshal(user)
 	if err != nil {
 		userInfo.logger.Info(
@@ -99,6 +137,9 @@ func (userInfo *UserInfo) HandleGetUser(w http.ResponseWriter, r *http.Request)
 
 	profile, err := userInfo.userApp.GetUserByNickname(nickname)
 	if err != nil {
+		if userInfo.redirectRenamed(w, r, nickname) {
+			return
+		}
 		msg := entity.Message{
 			Text: fmt.Sprintf("Can't find user with id #%v\n", nickname),
 		}
@@ -114,6 +155,17 @@ func (userInfo *UserInfo) HandleGetUser(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
+	if r.URL.Query().Get(string(entity.StatsKey)) == "true" {
+		profile.Stats, err = userInfo.userApp.GetUserStats(profile.Nickname)
+		if err != nil {
+			userInfo.logger.Info(
+				err.Error(), zap.String("url", r.RequestURI),
+				zap.String("method", r.Method))
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+	}
+
 	body, err := json.Marshal(profile)
 	if err != nil {
 		userInfo.logger.Info(
@@ -194,7 +246,16 @@ func (userInfo *UserInfo) HandleUpdateUser(w http.ResponseWriter, r *http.Reques
 			w.WriteHeader(http.StatusConflict)
 			w.Write(body)
 			return
+		} else if errors.Is(err, entity.WrongEmailError) {
+			httputil.WriteMessage(w, http.StatusBadRequest, entity.Message{Text: err.Error()})
+			return
 		}
+
+		userInfo.logger.Info(
+			err.Error(), zap.String("url", r.RequestURI),
+			zap.String("method", r.Method))
+		w.WriteHeader(http.StatusInternalServerError)
+		return
 	}
 
 	body, err := json.Marshal(profileData)
@@ -210,3 +271,631 @@ func (userInfo *UserInfo) HandleUpdateUser(w http.ResponseWriter, r *http.Reques
 	w.WriteHeader(http.StatusOK)
 	w.Write(body)
 }
+
+func (userInfo *UserInfo) HandleGetUserVotes(w http.ResponseWriter, r *http.Request) {
+	userInfo.logger.Info("HandleGetUserVotes")
+	vars := mux.Vars(r)
+	nickname := vars[string(entity.NicknameKey)]
+
+	queryParams := r.URL.Query()
+	limit, since, desc, err := httputil.TimePageParams(queryParams)
+	if err != nil {
+		userInfo.logger.Info(err.Error(), zap.String("url", r.RequestURI), zap.String("method", r.Method))
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	page, err := userInfo.userApp.GetUserVotes(nickname, limit, since, queryParams.Get(string(entity.CursorKey)), desc)
+	if err != nil {
+		if errors.Is(err, entity.UserDoesntExistsError) {
+			httputil.WriteMessage(w, http.StatusNotFound, entity.Message{
+				Text: fmt.Sprintf("Can't find user with id #%v\n", nickname),
+			})
+			return
+		}
+		if errors.Is(err, entity.WrongCursorError) {
+			httputil.WriteMessage(w, http.StatusBadRequest, entity.Message{Text: err.Error()})
+			return
+		}
+
+		userInfo.logger.Info(
+			err.Error(), zap.String("url", r.RequestURI),
+			zap.String("method", r.Method))
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	httputil.WriteJSON(w, http.StatusOK, page)
+}
+
+func (userInfo *UserInfo) HandleGetUserMentions(w http.ResponseWriter, r *http.Request) {
+	userInfo.logger.Info("HandleGetUserMentions")
+	vars := mux.Vars(r)
+	nickname := vars[string(entity.NicknameKey)]
+
+	nickname, err := userInfo.userApp.CheckIfUserExists(nickname)
+	if err != nil {
+		msg := entity.Message{
+			Text: fmt.Sprintf("Can't find user with id #%v\n", vars[string(entity.NicknameKey)]),
+		}
+		body, err := json.Marshal(msg)
+		if err != nil {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusNotFound)
+		w.Write(body)
+		return
+	}
+
+	queryParams := r.URL.Query()
+
+	limitParam, _ := queryParams[string(entity.LimitKey)]
+	limit := 0
+	if limitParam != nil {
+		limit, err = strconv.Atoi(limitParam[0])
+		if err != nil {
+			userInfo.logger.Info(err.Error(), zap.String("url", r.RequestURI), zap.String("method", r.Method))
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+	}
+
+	descParam, _ := queryParams[string(entity.DescKey)]
+	desc := false
+	if descParam != nil && descParam[0] == "true" {
+		desc = true
+	}
+
+	sinceParam, _ := queryParams[string(entity.SinceKey)]
+	since := ""
+	if sinceParam != nil {
+		since = sinceParam[0]
+	}
+
+	posts, err := userInfo.postApp.GetUserMentions(nickname, int32(limit), since, desc)
+	if err != nil {
+		userInfo.logger.Info(
+			err.Error(), zap.String("url", r.RequestURI),
+			zap.String("method", r.Method))
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	body, err := json.Marshal(posts)
+	if err != nil {
+		userInfo.logger.Info(
+			err.Error(), zap.String("url", r.RequestURI),
+			zap.String("method", r.Method))
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	w.Write(body)
+}
+
+func (userInfo *UserInfo) HandleGetUserPosts(w http.ResponseWriter, r *http.Request) {
+	userInfo.logger.Info("HandleGetUserPosts")
+	vars := mux.Vars(r)
+
+	nickname, err := userInfo.userApp.CheckIfUserExists(vars[string(entity.NicknameKey)])
+	if err != nil {
+		if userInfo.redirectRenamed(w, r, vars[string(entity.NicknameKey)]) {
+			return
+		}
+		userInfo.writeUserNotFound(w, vars[string(entity.NicknameKey)])
+		return
+	}
+
+	limit, since, desc, err := httputil.PageParams(r.URL.Query())
+	if err == nil && since != "" {
+		_, err = strconv.Atoi(since)
+	}
+	if err != nil {
+		userInfo.logger.Info(err.Error(), zap.String("url", r.RequestURI), zap.String("method", r.Method))
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	posts, err := userInfo.postApp.GetUserPosts(nickname, limit, since, desc)
+	if err != nil {
+		userInfo.logger.Info(
+			err.Error(), zap.String("url", r.RequestURI),
+			zap.String("method", r.Method))
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	httputil.WriteJSON(w, http.StatusOK, posts)
+}
+
+func (userInfo *UserInfo) HandleGetUserThreads(w http.ResponseWriter, r *http.Request) {
+	userInfo.logger.Info("HandleGetUserThreads")
+	vars := mux.Vars(r)
+
+	nickname, err := userInfo.userApp.CheckIfUserExists(vars[string(entity.NicknameKey)])
+	if err != nil {
+		if userInfo.redirectRenamed(w, r, vars[string(entity.NicknameKey)]) {
+			return
+		}
+		userInfo.writeUserNotFound(w, vars[string(entity.NicknameKey)])
+		return
+	}
+
+	limit, since, desc, err := httputil.PageParams(r.URL.Query())
+	if err == nil && since != "" {
+		_, err = time.Parse(time.RFC3339Nano, since)
+	}
+	if err != nil {
+		userInfo.logger.Info(err.Error(), zap.String("url", r.RequestURI), zap.String("method", r.Method))
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	threads, err := userInfo.threadApp.GetUserThreads(nickname, limit, since, desc)
+	if err != nil {
+		userInfo.logger.Info(
+			err.Error(), zap.String("url", r.RequestURI),
+			zap.String("method", r.Method))
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	httputil.WriteJSON(w, http.StatusOK, threads)
+}
+
+func (userInfo *UserInfo) HandleSearchUsers(w http.ResponseWriter, r *http.Request) {
+	userInfo.logger.Info("HandleSearchUsers")
+	queryParams := r.URL.Query()
+
+	limit, _, desc, err := httputil.PageParams(queryParams)
+	if err != nil {
+		userInfo.logger.Info(err.Error(), zap.String("url", r.RequestURI), zap.String("method", r.Method))
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	search := &entity.UserSearch{
+		Query: strings.TrimSpace(queryParams.Get(string(entity.QueryKey))),
+		Sort:  queryParams.Get(string(entity.SortKey)),
+		Desc:  desc,
+		Limit: limit,
+	}
+	page, err := userInfo.userApp.SearchUsers(search, queryParams.Get(string(entity.CursorKey)))
+	if err != nil {
+		if errors.Is(err, entity.WrongUserSortError) || errors.Is(err, entity.WrongCursorError) {
+			msg := entity.Message{Text: err.Error()}
+			body, err := json.Marshal(msg)
+			if err != nil {
+				w.WriteHeader(http.StatusInternalServerError)
+				return
+			}
+
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write(body)
+			return
+		}
+
+		userInfo.logger.Info(
+			err.Error(), zap.String("url", r.RequestURI),
+			zap.String("method", r.Method))
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	httputil.WriteJSON(w, http.StatusOK, page)
+}
+
+func (userInfo *UserInfo) HandleGetUserPrivacy(w http.ResponseWriter, r *http.Request) {
+	userInfo.logger.Info("HandleGetUserPrivacy")
+	vars := mux.Vars(r)
+	nickname := vars[string(entity.NicknameKey)]
+
+	privacy, err := userInfo.userApp.GetUserPrivacy(nickname)
+	if err != nil {
+		if errors.Is(err, entity.UserDoesntExistsError) {
+			userInfo.writeUserNotFound(w, nickname)
+			return
+		}
+
+		userInfo.logger.Info(
+			err.Error(), zap.String("url", r.RequestURI),
+			zap.String("method", r.Method))
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	httputil.WriteJSON(w, http.StatusOK, privacy)
+}
+
+func (userInfo *UserInfo) HandleSetUserPrivacy(w http.ResponseWriter, r *http.Request) {
+	userInfo.logger.Info("HandleSetUserPrivacy")
+	vars := mux.Vars(r)
+	nickname := vars[string(entity.NicknameKey)]
+
+	privacy := &entity.UserPrivacy{}
+	data, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		userInfo.logger.Info(
+			err.Error(), zap.String("url", r.RequestURI),
+			zap.String("method", r.Method))
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	err = json.Unmarshal(data, privacy)
+	if err != nil {
+		userInfo.logger.Info(
+			err.Error(), zap.String("url", r.RequestURI),
+			zap.String("method", r.Method))
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	err = userInfo.userApp.SetUserPrivacy(nickname, privacy)
+	if err != nil {
+		if errors.Is(err, entity.UserDoesntExistsError) {
+			userInfo.writeUserNotFound(w, nickname)
+			return
+		}
+
+		userInfo.logger.Info(
+			err.Error(), zap.String("url", r.RequestURI),
+			zap.String("method", r.Method))
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	httputil.WriteJSON(w, http.StatusOK, privacy)
+}
+
+func (userInfo *UserInfo) HandleRenameUser(w http.ResponseWriter, r *http.Request) {
+	userInfo.logger.Info("HandleRenameUser")
+	vars := mux.Vars(r)
+	nickname := vars[string(entity.NicknameKey)]
+
+	rename := &entity.UserRename{}
+	data, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		userInfo.logger.Info(
+			err.Error(), zap.String("url", r.RequestURI),
+			zap.String("method", r.Method))
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	err = json.Unmarshal(data, rename)
+	if err != nil {
+		userInfo.logger.Info(
+			err.Error(), zap.String("url", r.RequestURI),
+			zap.String("method", r.Method))
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	user, err := userInfo.userApp.RenameUser(nickname, rename.Nickname)
+	if err != nil {
+		var status int
+		switch {
+		case errors.Is(err, entity.UserDoesntExistsError):
+			userInfo.writeUserNotFound(w, nickname)
+			return
+		case errors.Is(err, entity.WrongNicknameError):
+			status = http.StatusBadRequest
+		case errors.Is(err, entity.NicknameExistsError), errors.Is(err, entity.NicknameReservedError):
+			status = http.StatusConflict
+		default:
+			userInfo.logger.Info(
+				err.Error(), zap.String("url", r.RequestURI),
+				zap.String("method", r.Method))
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+
+		httputil.WriteMessage(w, status, entity.Message{Text: err.Error()})
+		return
+	}
+
+	httputil.WriteJSON(w, http.StatusOK, user)
+}
+
+func (userInfo *UserInfo) HandleDeleteUser(w http.ResponseWriter, r *http.Request) {
+	userInfo.logger.Info("HandleDeleteUser")
+	vars := mux.Vars(r)
+	nickname := vars[string(entity.NicknameKey)]
+
+	user, err := userInfo.userApp.DeleteUser(nickname)
+	if err != nil {
+		if errors.Is(err, entity.UserDoesntExistsError) {
+			userInfo.writeUserNotFound(w, nickname)
+			return
+		}
+
+		userInfo.logger.Info(
+			err.Error(), zap.String("url", r.RequestURI),
+			zap.String("method", r.Method))
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	httputil.WriteJSON(w, http.StatusOK, user)
+}
+
+// HandleExportUser streams zip archive with personal data, errors after the archive
+// has started can only be logged and leave the archive truncated
+func (userInfo *UserInfo) HandleExportUser(w http.ResponseWriter, r *http.Request) {
+	userInfo.logger.Info("HandleExportUser")
+	vars := mux.Vars(r)
+
+	nickname := vars[string(entity.NicknameKey)]
+
+	export, err := userInfo.exportApp.PrepareExport(nickname)
+	if err != nil {
+		if errors.Is(err, entity.UserDoesntExistsError) {
+			userInfo.writeUserNotFound(w, nickname)
+			return
+		}
+		userInfo.logger.Info(
+			err.Error(), zap.String("url", r.RequestURI),
+			zap.String("method", r.Method))
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/zip")
+	w.Header().Set("Content-Disposition", mime.FormatMediaType("attachment",
+		map[string]string{"filename": export.Nickname() + "-export.zip"}))
+	w.WriteHeader(http.StatusOK)
+
+	// the status is sent already, errors of the stream can only be logged
+	err = userInfo.exportApp.WriteExport(export, w)
+	if err != nil {
+		userInfo.logger.Info(
+			err.Error(), zap.String("url", r.RequestURI),
+			zap.String("method", r.Method))
+	}
+}
+
+func (userInfo *UserInfo) HandleSendEmailVerification(w http.ResponseWriter, r *http.Request) {
+	userInfo.logger.Info("HandleSendEmailVerification")
+	vars := mux.Vars(r)
+	nickname := vars[string(entity.NicknameKey)]
+
+	err := userInfo.userApp.SendEmailVerification(nickname)
+	if err != nil {
+		switch {
+		case errors.Is(err, entity.UserDoesntExistsError):
+			userInfo.writeUserNotFound(w, nickname)
+		case errors.Is(err, entity.EmailVerifiedError):
+			httputil.WriteMessage(w, http.StatusConflict, entity.Message{Text: err.Error()})
+		default:
+			userInfo.logger.Info(
+				err.Error(), zap.String("url", r.RequestURI),
+				zap.String("method", r.Method))
+			w.WriteHeader(http.StatusInternalServerError)
+		}
+		return
+	}
+
+	httputil.WriteMessage(w, http.StatusAccepted, entity.Message{Text: "Verification mail is sent"})
+}
+
+func (userInfo *UserInfo) HandleConfirmEmail(w http.ResponseWriter, r *http.Request) {
+	userInfo.logger.Info("HandleConfirmEmail")
+
+	confirmation := &entity.EmailConfirmation{}
+	data, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		userInfo.logger.Info(
+			err.Error(), zap.String("url", r.RequestURI),
+			zap.String("method", r.Method))
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	err = json.Unmarshal(data, confirmation)
+	if err != nil {
+		userInfo.logger.Info(
+			err.Error(), zap.String("url", r.RequestURI),
+			zap.String("method", r.Method))
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	user, err := userInfo.userApp.ConfirmEmail(confirmation.Token)
+	if err != nil {
+		var status int
+		switch {
+		case errors.Is(err, entity.EmailTokenError):
+			status = http.StatusBadRequest
+		case errors.Is(err, entity.EmailTakenError):
+			status = http.StatusConflict
+		default:
+			userInfo.logger.Info(
+				err.Error(), zap.String("url", r.RequestURI),
+				zap.String("method", r.Method))
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+
+		httputil.WriteMessage(w, status, entity.Message{Text: err.Error()})
+		return
+	}
+
+	httputil.WriteJSON(w, http.StatusOK, user)
+}
+
+// redirectRenamed redirects requests with an old nickname to the same path with the current one.
+// The redirect is temporary, the old nickname may be taken by another user after the cooldown
+func (userInfo *UserInfo) redirectRenamed(w http.ResponseWriter, r *http.Request, oldNickname string) bool {
+	nickname, err := userInfo.userApp.GetRenamedNickname(oldNickname)
+	if err != nil {
+		return false
+	}
+
+	// path is /api/user/{nickname}/...
+	parts := strings.SplitN(r.URL.Path, "/", 5)
+	if len(parts) < 4 {
+		return false
+	}
+	parts[3] = url.PathEscape(nickname)
+	location := url.URL{Path: strings.Join(parts, "/"), RawQuery: r.URL.RawQuery}
+	http.Redirect(w, r, location.String(), http.StatusTemporaryRedirect)
+	return true
+}
+
+func (userInfo *UserInfo) writeUserNotFound(w http.ResponseWriter, nickname string) {
+	httputil.WriteMessage(w, http.StatusNotFound, entity.Message{
+		Text: fmt.Sprintf("Can't find user with id #%v\n", nickname),
+	})
+}
+
+func (userInfo *UserInfo) HandleFollowUser(w http.ResponseWriter, r *http.Request) {
+	userInfo.logger.Info("HandleFollowUser")
+	vars := mux.Vars(r)
+
+	err := userInfo.followApp.Follow(vars[string(entity.NicknameKey)], vars[string(entity.FolloweeKey)])
+	userInfo.writeFollowResult(w, r, err)
+}
+
+func (userInfo *UserInfo) HandleUnfollowUser(w http.ResponseWriter, r *http.Request) {
+	userInfo.logger.Info("HandleUnfollowUser")
+	vars := mux.Vars(r)
+
+	err := userInfo.followApp.Unfollow(vars[string(entity.NicknameKey)], vars[string(entity.FolloweeKey)])
+	userInfo.writeFollowResult(w, r, err)
+}
+
+func (userInfo *UserInfo) HandleBlockUser(w http.ResponseWriter, r *http.Request) {
+	userInfo.logger.Info("HandleBlockUser")
+	vars := mux.Vars(r)
+
+	err := userInfo.blockApp.Block(vars[string(entity.NicknameKey)], vars[string(entity.BlockedKey)])
+	userInfo.writeFollowResult(w, r, err)
+}
+
+func (userInfo *UserInfo) HandleUnblockUser(w http.ResponseWriter, r *http.Request) {
+	userInfo.logger.Info("HandleUnblockUser")
+	vars := mux.Vars(r)
+
+	err := userInfo.blockApp.Unblock(vars[string(entity.NicknameKey)], vars[string(entity.BlockedKey)])
+	userInfo.writeFollowResult(w, r, err)
+}
+
+// writeFollowResult answers follow and block changes
+func (userInfo *UserInfo) writeFollowResult(w http.ResponseWriter, r *http.Request, err error) {
+	if errors.Is(err, entity.UserDoesntExistsError) {
+		httputil.WriteMessage(w, http.StatusNotFound, entity.Message{Text: err.Error()})
+		return
+	}
+	if errors.Is(err, entity.FollowSelfError) || errors.Is(err, entity.BlockSelfError) {
+		httputil.WriteMessage(w, http.StatusBadRequest, entity.Message{Text: err.Error()})
+		return
+	}
+	if err != nil {
+		userInfo.logger.Info(
+			err.Error(), zap.String("url", r.RequestURI),
+			zap.String("method", r.Method))
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (userInfo *UserInfo) HandleGetFollowing(w http.ResponseWriter, r *http.Request) {
+	userInfo.logger.Info("HandleGetFollowing")
+	userInfo.handleGetFollowUsers(w, r, userInfo.followApp.GetFollowing)
+}
+
+func (userInfo *UserInfo) HandleGetFollowers(w http.ResponseWriter, r *http.Request) {
+	userInfo.logger.Info("HandleGetFollowers")
+	userInfo.handleGetFollowUsers(w, r, userInfo.followApp.GetFollowers)
+}
+
+func (userInfo *UserInfo) HandleGetBlocked(w http.ResponseWriter, r *http.Request) {
+	userInfo.logger.Info("HandleGetBlocked")
+	userInfo.handleGetFollowUsers(w, r, userInfo.blockApp.GetBlocked)
+}
+
+func (userInfo *UserInfo) handleGetFollowUsers(w http.ResponseWriter,
+	r *http.Request,
+	get func(nickname string, limit int32, since string, desc bool) ([]entity.User, error)) {
+	nickname := mux.Vars(r)[string(entity.NicknameKey)]
+
+	limit, since, desc, err := httputil.PageParams(r.URL.Query())
+	if err != nil {
+		userInfo.logger.Info(err.Error(), zap.String("url", r.RequestURI), zap.String("method", r.Method))
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	users, err := get(nickname, limit, since, desc)
+	if err != nil {
+		if errors.Is(err, entity.UserDoesntExistsError) {
+			if userInfo.redirectRenamed(w, r, nickname) {
+				return
+			}
+			userInfo.writeUserNotFound(w, nickname)
+			return
+		}
+
+		userInfo.logger.Info(
+			err.Error(), zap.String("url", r.RequestURI),
+			zap.String("method", r.Method))
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	httputil.WriteJSON(w, http.StatusOK, users)
+}
+
+// HandleGetUserFeed lists new threads and posts of followed users, newest first unless desc=false.
+// since is RFC 3339 time the first page starts at, next pages are requested with cursor
+func (userInfo *UserInfo) HandleGetUserFeed(w http.ResponseWriter, r *http.Request) {
+	userInfo.logger.Info("HandleGetUserFeed")
+	nickname := mux.Vars(r)[string(entity.NicknameKey)]
+	queryParams := r.URL.Query()
+
+	limit, sinceParam, _, err := httputil.PageParams(queryParams)
+	var since *time.Time
+	if err == nil && sinceParam != "" {
+		var sinceTime time.Time
+		sinceTime, err = time.Parse(time.RFC3339Nano, sinceParam)
+		since = &sinceTime
+	}
+	if err != nil {
+		userInfo.logger.Info(err.Error(), zap.String("url", r.RequestURI), zap.String("method", r.Method))
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	desc := queryParams.Get(string(entity.DescKey)) != "false"
+
+	page, err := userInfo.followApp.GetFeed(nickname, limit, since, queryParams.Get(string(entity.CursorKey)), desc)
+	if err != nil {
+		if errors.Is(err, entity.UserDoesntExistsError) {
+			if userInfo.redirectRenamed(w, r, nickname) {
+				return
+			}
+			userInfo.writeUserNotFound(w, nickname)
+			return
+		}
+		if errors.Is(err, entity.WrongCursorError) {
+			httputil.WriteMessage(w, http.StatusBadRequest, entity.Message{Text: err.Error()})
+			return
+		}
+
+		userInfo.logger.Info(
+			err.Error(), zap.String("url", r.RequestURI),
+			zap.String("method", r.Method))
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	httputil.WriteJSON(w, http.StatusOK, page)
+}